@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"os"
+
+	"visor-pdf/internal/auditoria"
+	"visor-pdf/internal/config"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/imagen"
+)
+
+// Recupera la marca forense invisible de una imagen filtrada (PNG o JPEG)
+// y la busca en la bitácora de visualizaciones.
+//
+// Ejecutar: go run ./cmd/extraer-marca -imagen captura.jpg [-sin-bd]
+func main() {
+	archivo := flag.String("imagen", "", "ruta de la imagen a analizar")
+	sinBD := flag.Bool("sin-bd", false, "solo decodificar, sin consultar la bitácora")
+	flag.Parse()
+
+	if *archivo == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*archivo)
+	if err != nil {
+		log.Fatalf("Error abriendo %s: %v", *archivo, err)
+	}
+	img, formato, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("Error decodificando imagen: %v", err)
+	}
+	fmt.Printf("🔍 Analizando %s (%s, %dx%d)...\n", *archivo, formato, img.Bounds().Dx(), img.Bounds().Dy())
+
+	candidatos := imagen.ExtraerForense(img)
+	if len(candidatos) == 0 {
+		fmt.Println("❌ No se encontró ninguna marca forense")
+		os.Exit(1)
+	}
+
+	if !*sinBD {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			log.Fatalf("Error cargando config: %v", err)
+		}
+		database.ConnectDB(cfg)
+		defer database.CloseDB()
+	}
+
+	fmt.Println("=================================")
+	for i, c := range candidatos {
+		fmt.Printf("%d. Usuario %d, vista %s (confianza %.2f)\n",
+			i+1, c.UsuarioID, auditoria.CodigoVista(c.VistaID), c.Confianza)
+		if !*sinBD {
+			mostrarRegistro(c.Identificador)
+		}
+	}
+	fmt.Println("=================================")
+	fmt.Println("Los candidatos con confianza baja (< 0.2) pueden ser coincidencias casuales del CRC;")
+	fmt.Println("el bueno es el que coincide con la bitácora.")
+}
+
+// mostrarRegistro busca la visualización en la bitácora y confirma que sea del mismo usuario
+func mostrarRegistro(id imagen.Identificador) {
+	var usuarioID int
+	var username, acto, ip string
+	var municipio, oficialia, localidad, anio, numActa int
+	var creado sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT b.usuario_id, u.username, b.acto, b.municipio_id, b.oficialia, b.localidad,
+			b.anio, b.num_acta, COALESCE(b.ip, ''), b.creado_en
+		FROM bitacora_visualizaciones b
+		JOIN usuarios u ON b.usuario_id = u.id
+		WHERE b.id = ?`, id.VistaID).Scan(&usuarioID, &username, &acto, &municipio, &oficialia,
+		&localidad, &anio, &numActa, &ip, &creado)
+	if err == sql.ErrNoRows {
+		fmt.Println("   ⚠️  La vista no existe en la bitácora")
+		return
+	}
+	if err != nil {
+		fmt.Println("   ⚠️  Error consultando bitácora:", err)
+		return
+	}
+	// El identificador solo guarda 16 bits del usuario
+	if usuarioID&0xFFFF != id.UsuarioID {
+		fmt.Printf("   ⚠️  La vista pertenece a otro usuario (%s, id %d)\n", username, usuarioID)
+		return
+	}
+	fmt.Printf("   ✅ Coincide con la bitácora: %s (id %d) el %s desde %s\n",
+		username, usuarioID, creado.Time.Format("2006-01-02 15:04:05"), ip)
+	fmt.Printf("      Acto %s, municipio %d, oficialía %d, localidad %d, año %d, acta %d\n",
+		acto, municipio, oficialia, localidad, anio, numActa)
+}
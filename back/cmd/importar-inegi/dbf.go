@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Lector mínimo de archivos dBase (DBF) como los que publica INEGI junto
+// con el catálogo: solo lee los campos como texto y omite los registros
+// borrados.
+
+type campoDBF struct {
+	nombre string
+	largo  int
+}
+
+// leerDBF devuelve los nombres de los campos y un registro por fila
+func leerDBF(r io.Reader, decodificar func([]byte) string) ([]string, [][]string, error) {
+	lector := bufio.NewReader(r)
+
+	cabecera := make([]byte, 32)
+	if _, err := io.ReadFull(lector, cabecera); err != nil {
+		return nil, nil, fmt.Errorf("cabecera DBF incompleta: %v", err)
+	}
+	total := int(binary.LittleEndian.Uint32(cabecera[4:8]))
+	largoCabecera := int(binary.LittleEndian.Uint16(cabecera[8:10]))
+	largoRegistro := int(binary.LittleEndian.Uint16(cabecera[10:12]))
+
+	// Descriptores de campo de 32 bytes hasta el terminador 0x0D
+	var campos []campoDBF
+	leidos := 32
+	for {
+		b, err := lector.Peek(1)
+		if err != nil {
+			return nil, nil, fmt.Errorf("descriptores DBF incompletos: %v", err)
+		}
+		if b[0] == 0x0D {
+			break
+		}
+		descriptor := make([]byte, 32)
+		if _, err := io.ReadFull(lector, descriptor); err != nil {
+			return nil, nil, fmt.Errorf("descriptores DBF incompletos: %v", err)
+		}
+		leidos += 32
+		nombre := strings.TrimRight(string(descriptor[:11]), "\x00 ")
+		campos = append(campos, campoDBF{nombre: nombre, largo: int(descriptor[16])})
+	}
+	if len(campos) == 0 {
+		return nil, nil, fmt.Errorf("el DBF no tiene campos")
+	}
+	// Saltar el terminador y lo que reste de la cabecera
+	if _, err := lector.Discard(largoCabecera - leidos); err != nil {
+		return nil, nil, fmt.Errorf("cabecera DBF inválida: %v", err)
+	}
+
+	nombres := make([]string, len(campos))
+	for i, c := range campos {
+		nombres[i] = c.nombre
+	}
+
+	registros := make([][]string, 0, total)
+	buffer := make([]byte, largoRegistro)
+	for i := 0; i < total; i++ {
+		if _, err := io.ReadFull(lector, buffer); err != nil {
+			return nil, nil, fmt.Errorf("registro %d incompleto: %v", i+1, err)
+		}
+		// El primer byte marca los registros borrados con '*'
+		if buffer[0] == '*' {
+			continue
+		}
+		registro := make([]string, len(campos))
+		pos := 1
+		for j, c := range campos {
+			if pos+c.largo > len(buffer) {
+				return nil, nil, fmt.Errorf("registro %d: campo %s fuera de rango", i+1, c.nombre)
+			}
+			registro[j] = strings.TrimSpace(decodificar(buffer[pos : pos+c.largo]))
+			pos += c.largo
+		}
+		registros = append(registros, registro)
+	}
+	return nombres, registros, nil
+}
@@ -0,0 +1,540 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"visor-pdf/internal/config"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
+)
+
+// Concilia los catálogos de municipios y localidades con el catálogo oficial
+// de INEGI (AGEEML) en CSV o DBF.
+//
+// Columnas reconocidas (sin importar mayúsculas): CVE_ENT, CVE_MUN, NOM_MUN,
+// CVE_LOC y NOM_LOC. Sin CVE_LOC solo se concilian los municipios. Solo se
+// toman las filas del estado indicado con -estado, y se comparan con los
+// municipios de ese estado por su clave INEGI. Los municipios nuevos reciben
+// el id interno de estados.IDMunicipio.
+//
+// Siempre imprime el reporte de diferencias (nuevos, renombrados y
+// faltantes). Con -dry-run no cambia nada; si no, aplica en una sola
+// transacción las categorías elegidas con -aplicar:
+//   - nuevos:      da de alta los municipios y localidades que faltan
+//   - renombrados: cambia el nombre y guarda el anterior en nombres_historicos
+//   - faltantes:   según -faltantes, los desactiva o borra las localidades
+//     sin referencias. Una localidad con actas, PDFs registrados,
+//     visualizaciones, descargas, copias, anotaciones o reportes nunca se
+//     borra, solo se desactiva; los municipios solo se desactivan. Para
+//     borrar se exige que el verificador de integridad ya haya registrado
+//     los PDFs del archivo.
+//
+// Ejecutar: go run ./cmd/importar-inegi -archivo AGEEML.csv -dry-run [-reporte diferencias.csv]
+//
+//	go run ./cmd/importar-inegi -archivo AGEEML.dbf -usuario admin -aplicar nuevos,renombrados
+func main() {
+	archivo := flag.String("archivo", "", "catálogo de INEGI (.csv o .dbf)")
+	separador := flag.String("separador", ",", "separador de columnas del CSV")
+	codificacion := flag.String("codificacion", "auto", "codificación del archivo: auto, utf8 o latin1")
+	estado := flag.Int("estado", 20, "clave INEGI del estado")
+	aplicar := flag.String("aplicar", "nuevos,renombrados", "categorías a aplicar: nuevos, renombrados, faltantes")
+	faltantes := flag.String("faltantes", "desactivar", "qué hacer con los faltantes: desactivar o borrar")
+	usuario := flag.String("usuario", "", "usuario administrador al que se atribuyen los cambios")
+	fecha := flag.String("fecha", "", "fecha desde la que rigen los nombres nuevos (AAAA-MM-DD, por defecto hoy)")
+	reporte := flag.String("reporte", "", "guardar el reporte de diferencias en este CSV")
+	dryRun := flag.Bool("dry-run", false, "mostrar el reporte sin guardar nada")
+	flag.Parse()
+
+	if *archivo == "" || len(*separador) != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	categorias := map[string]bool{}
+	for _, c := range strings.Split(*aplicar, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if c != "nuevos" && c != "renombrados" && c != "faltantes" {
+			log.Fatalf("Categoría desconocida en -aplicar: %q", c)
+		}
+		categorias[c] = true
+	}
+	if *estado < 1 || *estado > 32 {
+		log.Fatalf("-estado debe ser una clave INEGI (1-32)")
+	}
+	if *faltantes != "desactivar" && *faltantes != "borrar" {
+		log.Fatalf("-faltantes debe ser desactivar o borrar")
+	}
+	vigencia := time.Now().Format("2006-01-02")
+	if *fecha != "" {
+		if _, err := time.Parse("2006-01-02", *fecha); err != nil {
+			log.Fatalf("-fecha inválida: %v", err)
+		}
+		vigencia = *fecha
+	}
+
+	oficial, err := leerCatalogo(*archivo, rune((*separador)[0]), *codificacion, *estado)
+	if err != nil {
+		log.Fatalf("Error leyendo %s: %v", *archivo, err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error cargando config: %v", err)
+	}
+	database.ConnectDB(cfg)
+	defer database.CloseDB()
+
+	actual, err := leerBD(*estado, oficial.conLocalidades)
+	if err != nil {
+		log.Fatalf("Error leyendo catálogo actual: %v", err)
+	}
+	diferencias := conciliar(oficial, actual)
+	imprimirReporte(diferencias)
+	if *reporte != "" {
+		if err := guardarReporte(*reporte, diferencias); err != nil {
+			log.Fatalf("Error guardando reporte: %v", err)
+		}
+		fmt.Println("📄 Reporte guardado en", *reporte)
+	}
+
+	if *dryRun {
+		fmt.Println("ℹ️  dry-run: no se guardó ningún cambio")
+		return
+	}
+	if *usuario == "" {
+		log.Fatalf("Indica -usuario para registrar quién aplica los cambios (o usa -dry-run)")
+	}
+	var usuarioID int
+	err = database.DB.QueryRow("SELECT id FROM usuarios WHERE username = ? AND activo = 1", *usuario).Scan(&usuarioID)
+	if err != nil {
+		log.Fatalf("No se encontró el usuario activo %q: %v", *usuario, err)
+	}
+
+	if *faltantes == "borrar" && categorias["faltantes"] {
+		// Sin el registro de archivos_pdf no se sabe qué localidades tienen PDFs en disco
+		var registrados int
+		if err := database.DB.QueryRow("SELECT COUNT(*) FROM archivos_pdf").Scan(&registrados); err != nil {
+			log.Fatalf("Error consultando archivos_pdf: %v", err)
+		}
+		if registrados == 0 {
+			log.Fatalf("archivos_pdf está vacío: ejecuta el verificador de integridad antes de usar -faltantes borrar")
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Fatalf("Error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	aplicados := 0
+	for _, d := range diferencias {
+		if !categorias[d.categoria] {
+			continue
+		}
+		if err := aplicarDiferencia(tx, d, *estado, *faltantes, vigencia, usuarioID); err != nil {
+			log.Fatalf("Error aplicando %s %s: %v (no se guardó ningún cambio)", d.categoria, d.clave, err)
+		}
+		aplicados++
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Error guardando cambios: %v", err)
+	}
+	fmt.Printf("✅ %d cambios aplicados al catálogo\n", aplicados)
+}
+
+// clave identifica un municipio (localidad 0) o una localidad por sus claves
+// INEGI dentro del estado
+type clave struct {
+	municipio, localidad int
+}
+
+func (c clave) tipo() string {
+	if c.localidad == 0 {
+		return "municipio"
+	}
+	return "localidad"
+}
+
+func (c clave) String() string {
+	if c.localidad == 0 {
+		return fmt.Sprintf("municipio %03d", c.municipio)
+	}
+	return fmt.Sprintf("localidad %03d-%04d", c.municipio, c.localidad)
+}
+
+// catalogoOficial son los nombres del archivo de INEGI
+type catalogoOficial struct {
+	nombres        map[clave]string
+	conLocalidades bool
+}
+
+// registroActual es un municipio o localidad de la BD
+type registroActual struct {
+	nombre       string
+	activo       bool
+	referenciada bool // alguna tabla de referenciasLocalidad la menciona
+}
+
+// diferencia es una línea del reporte
+type diferencia struct {
+	categoria    string // nuevos, renombrados o faltantes
+	clave        clave
+	nombreBD     string
+	nombreINEGI  string
+	activo       bool
+	referenciada bool
+}
+
+// leerCatalogo lee el CSV o DBF y se queda con las filas del estado
+func leerCatalogo(ruta string, separador rune, codificacion string, estado int) (catalogoOficial, error) {
+	cat := catalogoOficial{nombres: map[clave]string{}}
+
+	f, err := os.Open(ruta)
+	if err != nil {
+		return cat, err
+	}
+	defer f.Close()
+
+	decodificar := func(b []byte) string {
+		if codificacion == "utf8" || (codificacion == "auto" && utf8.Valid(b)) {
+			return string(b)
+		}
+		// latin1: cada byte es el código Unicode del carácter
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return string(r)
+	}
+
+	var encabezado []string
+	var registros [][]string
+	if strings.EqualFold(filepath.Ext(ruta), ".dbf") {
+		encabezado, registros, err = leerDBF(f, decodificar)
+		if err != nil {
+			return cat, err
+		}
+	} else {
+		lector := csv.NewReader(f)
+		lector.Comma = separador
+		lector.FieldsPerRecord = -1
+		lector.LazyQuotes = true
+		for {
+			registro, err := lector.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return cat, err
+			}
+			for i, v := range registro {
+				registro[i] = strings.TrimSpace(decodificar([]byte(v)))
+			}
+			if encabezado == nil {
+				encabezado = registro
+				continue
+			}
+			registros = append(registros, registro)
+		}
+	}
+
+	columnas := map[string]int{}
+	for i, nombre := range encabezado {
+		nombre = strings.TrimPrefix(nombre, "\ufeff")
+		columnas[strings.ToUpper(strings.TrimSpace(nombre))] = i
+	}
+	for _, c := range []string{"CVE_ENT", "CVE_MUN", "NOM_MUN"} {
+		if _, ok := columnas[c]; !ok {
+			return cat, fmt.Errorf("falta la columna %s", c)
+		}
+	}
+	_, tieneLoc := columnas["CVE_LOC"]
+	_, tieneNomLoc := columnas["NOM_LOC"]
+	cat.conLocalidades = tieneLoc && tieneNomLoc
+
+	for n, registro := range registros {
+		valor := func(columna string) string {
+			i, ok := columnas[columna]
+			if !ok || i >= len(registro) {
+				return ""
+			}
+			return registro[i]
+		}
+		if ent, err := strconv.Atoi(valor("CVE_ENT")); err != nil || ent != estado {
+			continue
+		}
+		mun, err := strconv.Atoi(valor("CVE_MUN"))
+		if err != nil {
+			fmt.Printf("⚠️  Fila %d: CVE_MUN inválida %q, se omite\n", n+2, valor("CVE_MUN"))
+			continue
+		}
+		cat.nombres[clave{municipio: mun}] = valor("NOM_MUN")
+
+		if cat.conLocalidades {
+			loc, err := strconv.Atoi(valor("CVE_LOC"))
+			if err != nil || loc < 1 {
+				fmt.Printf("⚠️  Fila %d: CVE_LOC inválida %q, se omite\n", n+2, valor("CVE_LOC"))
+				continue
+			}
+			cat.nombres[clave{mun, loc}] = valor("NOM_LOC")
+		}
+	}
+	if len(cat.nombres) == 0 {
+		return cat, fmt.Errorf("no hay filas del estado %d", estado)
+	}
+	return cat, nil
+}
+
+// leerBD carga los municipios del estado y, si el archivo las trae, sus
+// localidades
+func leerBD(estado int, conLocalidades bool) (map[clave]registroActual, error) {
+	actual := map[clave]registroActual{}
+
+	rows, err := database.DB.Query("SELECT clave, COALESCE(nombre, ''), activo FROM municipios WHERE estado_id = ?",
+		estado)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c clave
+		var r registroActual
+		if err := rows.Scan(&c.municipio, &r.nombre, &r.activo); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		actual[c] = r
+	}
+	rows.Close()
+	if !conLocalidades {
+		return actual, nil
+	}
+
+	rows, err = database.DB.Query(`
+		SELECT m.clave, l.idlocalidades, COALESCE(l.nombre, ''), l.activo,
+			`+referenciadaSQL()+`
+		FROM localidades l
+		JOIN municipios m ON l.idmunicipio = m.idmunicipios
+		WHERE m.estado_id = ?`, estado)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c clave
+		var r registroActual
+		if err := rows.Scan(&c.municipio, &c.localidad, &r.nombre, &r.activo, &r.referenciada); err != nil {
+			return nil, err
+		}
+		actual[c] = r
+	}
+	return actual, rows.Err()
+}
+
+// Tablas que guardan la localidad de un acta sin llave foránea; si alguna la
+// menciona, la localidad no se puede borrar sin dejar filas huérfanas.
+// archivos_pdf cubre además los PDFs en disco que ya pasaron por el
+// verificador de integridad.
+var referenciasLocalidad = []struct {
+	tabla, municipio, localidad string
+}{
+	{"actas", "municipio_id", "localidad"},
+	{"archivos_pdf", "municipio_id", "localidad"},
+	{"bitacora_visualizaciones", "municipio_id", "localidad"},
+	{"bitacora_descargas", "municipio_id", "localidad"},
+	{"copias_certificadas", "municipio_id", "localidad"},
+	{"anotaciones", "municipio_id", "localidad"},
+	{"anotaciones_marginales", "municipio_id", "localidad"},
+	{"anotaciones_marginales", "ref_municipio_id", "ref_localidad"},
+	{"anotaciones_marginales_historial", "municipio_id", "localidad"},
+	{"anotaciones_marginales_historial", "ref_municipio_id", "ref_localidad"},
+	{"reportes_calidad", "municipio_id", "localidad"},
+}
+
+// referenciadaSQL arma la expresión que dice si la localidad l está en uso
+func referenciadaSQL() string {
+	partes := make([]string, len(referenciasLocalidad))
+	for i, r := range referenciasLocalidad {
+		partes[i] = fmt.Sprintf("EXISTS (SELECT 1 FROM %s r WHERE r.%s = l.idmunicipio AND r.%s = l.idlocalidades)",
+			r.tabla, r.municipio, r.localidad)
+	}
+	return strings.Join(partes, "\n\t\t\tOR ")
+}
+
+// conciliar compara ambos catálogos; los municipios van antes que sus
+// localidades para que las altas respeten el orden
+func conciliar(oficial catalogoOficial, actual map[clave]registroActual) []diferencia {
+	var diferencias []diferencia
+	for c, nombre := range oficial.nombres {
+		r, existe := actual[c]
+		switch {
+		case !existe:
+			diferencias = append(diferencias, diferencia{categoria: "nuevos", clave: c, nombreINEGI: nombre})
+		case strings.TrimSpace(r.nombre) != nombre:
+			diferencias = append(diferencias, diferencia{categoria: "renombrados", clave: c,
+				nombreBD: r.nombre, nombreINEGI: nombre, activo: r.activo, referenciada: r.referenciada})
+		}
+	}
+	for c, r := range actual {
+		if _, existe := oficial.nombres[c]; existe {
+			continue
+		}
+		// Los ya inactivos no se vuelven a reportar
+		if !r.activo {
+			continue
+		}
+		diferencias = append(diferencias, diferencia{categoria: "faltantes", clave: c,
+			nombreBD: r.nombre, activo: r.activo, referenciada: r.referenciada})
+	}
+
+	sort.Slice(diferencias, func(i, j int) bool {
+		a, b := diferencias[i].clave, diferencias[j].clave
+		if a.municipio != b.municipio {
+			return a.municipio < b.municipio
+		}
+		return a.localidad < b.localidad
+	})
+	return diferencias
+}
+
+func imprimirReporte(diferencias []diferencia) {
+	conteo := map[string]int{}
+	for _, d := range diferencias {
+		conteo[d.categoria]++
+		switch d.categoria {
+		case "nuevos":
+			fmt.Printf("➕ %s: %q\n", d.clave, d.nombreINEGI)
+		case "renombrados":
+			fmt.Printf("✏️  %s: %q -> %q\n", d.clave, d.nombreBD, d.nombreINEGI)
+		case "faltantes":
+			nota := ""
+			if d.referenciada {
+				nota = " (en uso, no se borrará)"
+			}
+			fmt.Printf("➖ %s: %q no está en INEGI%s\n", d.clave, d.nombreBD, nota)
+		}
+	}
+	fmt.Println("=================================")
+	fmt.Println("Nuevos:      ", conteo["nuevos"])
+	fmt.Println("Renombrados: ", conteo["renombrados"])
+	fmt.Println("Faltantes:   ", conteo["faltantes"])
+	fmt.Println("=================================")
+}
+
+func guardarReporte(ruta string, diferencias []diferencia) error {
+	f, err := os.Create(ruta)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"categoria", "tipo", "municipio", "localidad", "nombre_bd", "nombre_inegi", "referenciada"})
+	for _, d := range diferencias {
+		w.Write([]string{d.categoria, d.clave.tipo(), strconv.Itoa(d.clave.municipio),
+			strconv.Itoa(d.clave.localidad), d.nombreBD, d.nombreINEGI, strconv.FormatBool(d.referenciada)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// aplicarDiferencia hace el cambio y lo registra en catalogo_cambios
+func aplicarDiferencia(tx *sql.Tx, d diferencia, estado int, faltantes, vigencia string, usuarioID int) error {
+	c := d.clave
+	var accion, detalle string
+
+	// id interno del municipio; uno nuevo recibe el que le corresponde
+	var municipio int
+	err := tx.QueryRow("SELECT idmunicipios FROM municipios WHERE estado_id = ? AND clave = ?",
+		estado, c.municipio).Scan(&municipio)
+	if err == sql.ErrNoRows {
+		municipio = estados.IDMunicipio(estado, c.municipio)
+	} else if err != nil {
+		return err
+	}
+
+	switch d.categoria {
+	case "nuevos":
+		accion = "crear"
+		detalle = fmt.Sprintf("%s creado como %q desde INEGI", c, d.nombreINEGI)
+		if c.localidad == 0 {
+			_, err = tx.Exec("INSERT INTO municipios (idmunicipios, estado_id, clave, nombre) VALUES (?, ?, ?, ?)",
+				municipio, estado, c.municipio, d.nombreINEGI)
+		} else {
+			_, err = tx.Exec("INSERT INTO localidades (idlocalidades, idmunicipio, nombre) VALUES (?, ?, ?)",
+				c.localidad, municipio, d.nombreINEGI)
+		}
+
+	case "renombrados":
+		accion = "renombrar"
+		detalle = fmt.Sprintf("%s renombrado de %q a %q desde %s (INEGI)", c, d.nombreBD, d.nombreINEGI, vigencia)
+		if d.nombreBD != "" {
+			if _, err = tx.Exec(`
+				INSERT INTO nombres_historicos (tipo, municipio_id, localidad_id, nombre, vigente_hasta)
+				VALUES (?, ?, ?, ?, ?)`, c.tipo(), municipio, c.localidad, d.nombreBD, vigencia); err != nil {
+				return err
+			}
+		}
+		if c.localidad == 0 {
+			_, err = tx.Exec("UPDATE municipios SET nombre = ? WHERE idmunicipios = ?", d.nombreINEGI, municipio)
+		} else {
+			_, err = tx.Exec("UPDATE localidades SET nombre = ? WHERE idmunicipio = ? AND idlocalidades = ?",
+				d.nombreINEGI, municipio, c.localidad)
+		}
+
+	case "faltantes":
+		// Solo se borran localidades sin ninguna referencia. d.referenciada
+		// se leyó antes de la transacción; la condición se repite en el
+		// DELETE por si desde entonces se registró un acta, una anotación o
+		// un archivo. Si no se borró, se desactiva.
+		if faltantes == "borrar" && c.localidad != 0 && !d.referenciada {
+			result, err := tx.Exec(`
+				DELETE l FROM localidades l
+				WHERE l.idmunicipio = ? AND l.idlocalidades = ?
+					AND NOT (`+referenciadaSQL()+`)`,
+				municipio, c.localidad)
+			if err != nil {
+				return err
+			}
+			borradas, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if borradas > 0 {
+				accion = "borrar"
+				detalle = fmt.Sprintf("%s (%q) borrada: no está en INEGI", c, d.nombreBD)
+				break
+			}
+		}
+		accion = "desactivar"
+		detalle = fmt.Sprintf("%s (%q) desactivado: no está en INEGI", c, d.nombreBD)
+		if c.localidad == 0 {
+			_, err = tx.Exec("UPDATE municipios SET activo = 0 WHERE idmunicipios = ?", municipio)
+		} else {
+			_, err = tx.Exec("UPDATE localidades SET activo = 0 WHERE idmunicipio = ? AND idlocalidades = ?",
+				municipio, c.localidad)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO catalogo_cambios (usuario_id, tipo, accion, municipio_id, localidad_id, detalle)
+		VALUES (?, ?, ?, ?, ?, ?)`, usuarioID, c.tipo(), accion, municipio, c.localidad, detalle)
+	return err
+}
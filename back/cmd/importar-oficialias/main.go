@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"visor-pdf/internal/config"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
+)
+
+// Importa el catálogo de oficialías del Registro Civil.
+//
+// El CSV debe traer encabezados; se reconocen (sin importar mayúsculas):
+//   - municipio:   clave del municipio (obligatoria)
+//   - estado:      clave INEGI del estado; si viene, municipio es la clave
+//     INEGI dentro de ese estado y no el id interno
+//   - numero:      número de la oficialía (obligatoria)
+//   - nombre:      nombre o sede de la oficialía
+//   - oficial:     titular que firma las copias certificadas (si la columna
+//     no viene se conserva el registrado)
+//   - anio_inicio: primer año en que operó (vacío = sin límite)
+//   - anio_fin:    último año en que operó (vacío = sigue operando)
+//
+// Una oficialía ya registrada se actualiza; las que no vienen en el archivo
+// no se borran.
+//
+// Ejecutar: go run ./cmd/importar-oficialias -csv oficialias.csv [-separador ";"] [-dry-run]
+func main() {
+	archivo := flag.String("csv", "", "ruta del CSV con el catálogo")
+	separador := flag.String("separador", ",", "separador de columnas del CSV")
+	dryRun := flag.Bool("dry-run", false, "mostrar los cambios sin guardarlos")
+	flag.Parse()
+
+	if *archivo == "" || len(*separador) != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error cargando config: %v", err)
+	}
+	database.ConnectDB(cfg)
+	defer database.CloseDB()
+
+	f, err := os.Open(*archivo)
+	if err != nil {
+		log.Fatalf("Error abriendo %s: %v", *archivo, err)
+	}
+	defer f.Close()
+
+	lector := csv.NewReader(f)
+	lector.Comma = rune((*separador)[0])
+	lector.TrimLeadingSpace = true
+	lector.FieldsPerRecord = -1
+	encabezado, err := lector.Read()
+	if err != nil {
+		log.Fatalf("Error leyendo encabezado: %v", err)
+	}
+	columnas := map[string]int{}
+	for i, nombre := range encabezado {
+		// Quitar el BOM que agrega Excel al exportar en UTF-8
+		nombre = strings.TrimPrefix(nombre, "\ufeff")
+		columnas[strings.ToLower(strings.TrimSpace(nombre))] = i
+	}
+	for _, c := range []string{"municipio", "numero"} {
+		if _, ok := columnas[c]; !ok {
+			log.Fatalf("Falta la columna obligatoria %q", c)
+		}
+	}
+
+	_, conEstado := columnas["estado"]
+	_, conOficial := columnas["oficial"]
+	var municipios map[[2]int]int
+	if conEstado {
+		if municipios, err = estados.Municipios(); err != nil {
+			log.Fatalf("Error consultando municipios: %v", err)
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Fatalf("Error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	var nuevas, actualizadas, sinCambios, omitidas int
+	linea := 1
+	for {
+		registro, err := lector.Read()
+		if err == io.EOF {
+			break
+		}
+		linea++
+		if err != nil {
+			fmt.Printf("⚠️  Línea %d: %v, se omite\n", linea, err)
+			omitidas++
+			continue
+		}
+		valor := func(columna string) string {
+			i, ok := columnas[columna]
+			if !ok || i >= len(registro) {
+				return ""
+			}
+			return strings.TrimSpace(registro[i])
+		}
+
+		municipio, err1 := strconv.Atoi(valor("municipio"))
+		numero, err2 := strconv.Atoi(valor("numero"))
+		if err1 != nil || err2 != nil || numero < 1 {
+			fmt.Printf("⚠️  Línea %d: municipio o número inválido, se omite\n", linea)
+			omitidas++
+			continue
+		}
+		if conEstado {
+			estado, err := strconv.Atoi(valor("estado"))
+			id, ok := municipios[[2]int{estado, municipio}]
+			if err != nil || !ok {
+				fmt.Printf("⚠️  Línea %d: no existe el municipio %s del estado %s, se omite\n", linea, valor("municipio"), valor("estado"))
+				omitidas++
+				continue
+			}
+			municipio = id
+		}
+		inicio, err1 := anioOpcional(valor("anio_inicio"))
+		fin, err2 := anioOpcional(valor("anio_fin"))
+		if err1 != nil || err2 != nil {
+			fmt.Printf("⚠️  Línea %d: año de operación inválido, se omite\n", linea)
+			omitidas++
+			continue
+		}
+		if inicio != nil && fin != nil && *inicio > *fin {
+			fmt.Printf("⚠️  Línea %d: anio_inicio posterior a anio_fin, se omite\n", linea)
+			omitidas++
+			continue
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO oficialias (municipio_id, numero, nombre, oficial, anio_inicio, anio_fin)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE nombre = VALUES(nombre), oficial = IF(?, VALUES(oficial), oficial),
+				anio_inicio = VALUES(anio_inicio), anio_fin = VALUES(anio_fin)`,
+			municipio, numero, valor("nombre"), valor("oficial"), inicio, fin, conOficial)
+		if err != nil {
+			fmt.Printf("⚠️  Línea %d: no se pudo guardar la oficialía %d de %d: %v\n", linea, numero, municipio, err)
+			omitidas++
+			continue
+		}
+		// MySQL reporta 1 fila afectada al insertar, 2 al actualizar y 0 si
+		// la oficialía ya tenía esos valores
+		n, err := result.RowsAffected()
+		if err != nil {
+			log.Fatalf("Error leyendo filas afectadas: %v", err)
+		}
+		switch n {
+		case 1:
+			nuevas++
+		case 0:
+			sinCambios++
+		default:
+			actualizadas++
+		}
+	}
+
+	fmt.Println("=================================")
+	fmt.Println("Oficialías nuevas:      ", nuevas)
+	fmt.Println("Oficialías actualizadas:", actualizadas)
+	fmt.Println("Oficialías sin cambios: ", sinCambios)
+	fmt.Println("Líneas omitidas:        ", omitidas)
+	fmt.Println("=================================")
+
+	if *dryRun {
+		fmt.Println("ℹ️  dry-run: no se guardó ningún cambio")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Error guardando cambios: %v", err)
+	}
+	fmt.Println("✅ Catálogo de oficialías importado correctamente")
+}
+
+// anioOpcional convierte la columna de año; vacía significa sin límite
+func anioOpcional(v string) (*int, error) {
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
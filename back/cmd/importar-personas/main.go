@@ -0,0 +1,371 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/config"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
+	"visor-pdf/internal/fonetica"
+	"visor-pdf/internal/models"
+)
+
+// Importa el índice de personas desde las hojas de cálculo exportadas a CSV.
+//
+// Columnas obligatorias: acto, municipio, oficialia, localidad, anio, num_acta.
+// El acto debe existir en el catálogo de actos (tabla actos).
+// Opcionales: estado (clave INEGI; si viene, municipio es la clave INEGI
+// dentro de ese estado y no el id interno), fecha_evento (AAAA-MM-DD o DD/MM/AAAA) y, para cada rol
+// (registrado, padre, madre, conyuge1, conyuge2), las columnas
+// <rol>_nombre, <rol>_primer_apellido y <rol>_segundo_apellido.
+//
+// Reimportar un acta reemplaza sus personas, así que el comando se puede
+// ejecutar varias veces sobre el mismo archivo. Una línea que falla no deja
+// ninguno de sus cambios. -dry-run hace las mismas validaciones, incluidos
+// los catálogos de la base, sin guardar nada.
+//
+// Las claves fonéticas de cada nombre se calculan al importar. Si cambian
+// las reglas del paquete fonetica, -recalcular-claves las regenera para
+// todas las personas ya importadas.
+//
+// Ejecutar: go run ./cmd/importar-personas -csv indice.csv [-separador ";"] [-dry-run]
+//
+//	go run ./cmd/importar-personas -recalcular-claves
+func main() {
+	archivo := flag.String("csv", "", "ruta del CSV con el índice")
+	separador := flag.String("separador", ",", "separador de columnas del CSV")
+	dryRun := flag.Bool("dry-run", false, "validar el archivo sin guardar nada")
+	recalcular := flag.Bool("recalcular-claves", false, "regenerar las claves fonéticas de todas las personas")
+	flag.Parse()
+
+	if *recalcular {
+		recalcularClaves()
+		return
+	}
+	if *archivo == "" || len(*separador) != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*archivo)
+	if err != nil {
+		log.Fatalf("Error abriendo %s: %v", *archivo, err)
+	}
+	defer f.Close()
+
+	lector := csv.NewReader(f)
+	lector.Comma = rune((*separador)[0])
+	lector.FieldsPerRecord = -1
+	encabezado, err := lector.Read()
+	if err != nil {
+		log.Fatalf("Error leyendo encabezado: %v", err)
+	}
+	columnas := map[string]int{}
+	for i, nombre := range encabezado {
+		// Quitar el BOM que agrega Excel al exportar en UTF-8
+		nombre = strings.TrimPrefix(nombre, "\ufeff")
+		columnas[strings.ToLower(strings.TrimSpace(nombre))] = i
+	}
+	for _, c := range []string{"acto", "municipio", "oficialia", "localidad", "anio", "num_acta"} {
+		if _, ok := columnas[c]; !ok {
+			log.Fatalf("Falta la columna obligatoria %q", c)
+		}
+	}
+
+	// El dry-run también valida actos y municipios contra la base, para que
+	// sus conteos coincidan con los de una importación real
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error cargando config: %v", err)
+	}
+	database.ConnectDB(cfg)
+	defer database.CloseDB()
+
+	actos, err := cargarActos()
+	if err != nil {
+		log.Fatalf("Error consultando el catálogo de actos: %v", err)
+	}
+	var municipios map[[2]int]int
+	_, conEstado := columnas["estado"]
+	if conEstado {
+		if municipios, err = estados.Municipios(); err != nil {
+			log.Fatalf("Error consultando municipios: %v", err)
+		}
+	}
+	var tx *sql.Tx
+	if !*dryRun {
+		if tx, err = database.DB.Begin(); err != nil {
+			log.Fatalf("Error iniciando transacción: %v", err)
+		}
+	}
+
+	var importadas, personas, omitidas int
+	linea := 1
+	for {
+		registro, err := lector.Read()
+		if err == io.EOF {
+			break
+		}
+		linea++
+		if err != nil {
+			fmt.Printf("⚠️  Línea %d: %v, se omite\n", linea, err)
+			omitidas++
+			continue
+		}
+		valor := func(columna string) string {
+			i, ok := columnas[columna]
+			if !ok || i >= len(registro) {
+				return ""
+			}
+			return strings.TrimSpace(registro[i])
+		}
+
+		acta, err := leerActa(valor)
+		if err != nil {
+			fmt.Printf("⚠️  Línea %d: %v, se omite\n", linea, err)
+			omitidas++
+			continue
+		}
+		lista := leerPersonas(valor)
+
+		if !actos[acta.Acto] {
+			fmt.Printf("⚠️  Línea %d: acto %q no está en el catálogo, se omite\n", linea, acta.Acto)
+			omitidas++
+			continue
+		}
+		if conEstado {
+			estado, err := strconv.Atoi(valor("estado"))
+			id, ok := municipios[[2]int{estado, acta.Municipio}]
+			if err != nil || !ok {
+				fmt.Printf("⚠️  Línea %d: no existe el municipio %d del estado %s, se omite\n", linea, acta.Municipio, valor("estado"))
+				omitidas++
+				continue
+			}
+			acta.Municipio = id
+		}
+		if !*dryRun {
+			if err := guardarFila(tx, acta, lista); err != nil {
+				fmt.Printf("⚠️  Línea %d: %v, se omite\n", linea, err)
+				omitidas++
+				continue
+			}
+		}
+		importadas++
+		personas += len(lista)
+
+		// Confirmar por lotes para no mantener una transacción enorme
+		if !*dryRun && importadas%1000 == 0 {
+			if err := tx.Commit(); err != nil {
+				log.Fatalf("Error guardando lote en línea %d: %v", linea, err)
+			}
+			if tx, err = database.DB.Begin(); err != nil {
+				log.Fatalf("Error iniciando transacción: %v", err)
+			}
+			fmt.Printf("   %d actas importadas...\n", importadas)
+		}
+	}
+
+	if !*dryRun {
+		if err := tx.Commit(); err != nil {
+			log.Fatalf("Error guardando cambios: %v", err)
+		}
+	}
+
+	fmt.Println("=================================")
+	fmt.Println("Actas importadas: ", importadas)
+	fmt.Println("Personas:         ", personas)
+	fmt.Println("Líneas omitidas:  ", omitidas)
+	fmt.Println("=================================")
+	if *dryRun {
+		fmt.Println("ℹ️  dry-run: no se guardó ningún cambio")
+		return
+	}
+	fmt.Println("✅ Índice de personas importado correctamente")
+}
+
+// leerActa valida las columnas que identifican el acta
+func leerActa(valor func(string) string) (models.Acta, error) {
+	a := models.Acta{Acto: valor("acto")}
+	if a.Acto == "" {
+		return a, fmt.Errorf("acto vacío")
+	}
+	campos := []struct {
+		columna string
+		destino *int
+	}{
+		{"municipio", &a.Municipio},
+		{"oficialia", &a.Oficialia},
+		{"localidad", &a.Localidad},
+		{"anio", &a.Anio},
+		{"num_acta", &a.NumActa},
+	}
+	for _, c := range campos {
+		n, err := strconv.Atoi(valor(c.columna))
+		if err != nil || n < 0 {
+			return a, fmt.Errorf("%s inválido: %q", c.columna, valor(c.columna))
+		}
+		*c.destino = n
+	}
+
+	if fecha := valor("fecha_evento"); fecha != "" {
+		var t time.Time
+		var err error
+		for _, formato := range []string{"2006-01-02", "02/01/2006", "2/1/2006"} {
+			if t, err = time.Parse(formato, fecha); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return a, fmt.Errorf("fecha_evento inválida: %q", fecha)
+		}
+		f := t.Format("2006-01-02")
+		a.FechaEvento = &f
+	}
+	return a, nil
+}
+
+// leerPersonas arma la lista de personas con al menos un dato capturado
+func leerPersonas(valor func(string) string) []models.PersonaActa {
+	var lista []models.PersonaActa
+	for _, rol := range []string{"registrado", "padre", "madre", "conyuge1", "conyuge2"} {
+		p := models.PersonaActa{
+			Rol:             rol,
+			Nombre:          valor(rol + "_nombre"),
+			PrimerApellido:  valor(rol + "_primer_apellido"),
+			SegundoApellido: valor(rol + "_segundo_apellido"),
+		}
+		if p.Nombre == "" && p.PrimerApellido == "" && p.SegundoApellido == "" {
+			continue
+		}
+		lista = append(lista, p)
+	}
+	return lista
+}
+
+// cargarActos devuelve los códigos del catálogo de actos (activos o no,
+// porque el índice puede incluir actos que ya no se consultan)
+func cargarActos() (map[string]bool, error) {
+	rows, err := database.DB.Query("SELECT codigo FROM actos")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actos := map[string]bool{}
+	for rows.Next() {
+		var codigo string
+		if err := rows.Scan(&codigo); err != nil {
+			return nil, err
+		}
+		actos[codigo] = true
+	}
+	return actos, rows.Err()
+}
+
+// guardarFila guarda una línea del índice dentro de un savepoint: si falla
+// a medias se deshacen sus escrituras (el acta, el borrado de sus personas)
+// sin perder las líneas anteriores del lote, porque MySQL no aborta la
+// transacción cuando falla una sentencia
+func guardarFila(tx *sql.Tx, a models.Acta, personas []models.PersonaActa) error {
+	if _, err := tx.Exec("SAVEPOINT fila"); err != nil {
+		log.Fatalf("Error creando savepoint: %v", err)
+	}
+	err := guardarActa(tx, a, personas)
+	if err != nil {
+		if _, errRollback := tx.Exec("ROLLBACK TO SAVEPOINT fila"); errRollback != nil {
+			log.Fatalf("Error deshaciendo la línea: %v", errRollback)
+		}
+		return err
+	}
+	if _, err := tx.Exec("RELEASE SAVEPOINT fila"); err != nil {
+		log.Fatalf("Error liberando savepoint: %v", err)
+	}
+	return nil
+}
+
+// guardarActa inserta o actualiza el acta y reemplaza sus personas
+func guardarActa(tx *sql.Tx, a models.Acta, personas []models.PersonaActa) error {
+	result, err := tx.Exec(`
+		INSERT INTO actas (acto, municipio_id, oficialia, localidad, anio, num_acta, fecha_evento)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), fecha_evento = COALESCE(VALUES(fecha_evento), fecha_evento)`,
+		a.Acto, a.Municipio, a.Oficialia, a.Localidad, a.Anio, a.NumActa, a.FechaEvento)
+	if err != nil {
+		return fmt.Errorf("no se pudo guardar el acta: %v", err)
+	}
+	actaID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM acta_personas WHERE acta_id = ?", actaID); err != nil {
+		return err
+	}
+	for _, p := range personas {
+		if _, err := tx.Exec(`
+			INSERT INTO acta_personas (acta_id, rol, nombre, primer_apellido, segundo_apellido,
+				clave_nombre, clave_primer_apellido, clave_segundo_apellido)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			actaID, p.Rol, p.Nombre, p.PrimerApellido, p.SegundoApellido,
+			fonetica.Clave(p.Nombre), fonetica.Clave(p.PrimerApellido), fonetica.Clave(p.SegundoApellido)); err != nil {
+			return fmt.Errorf("no se pudo guardar %s: %v", p.Rol, err)
+		}
+	}
+	return nil
+}
+
+// recalcularClaves regenera las claves fonéticas de todas las personas
+func recalcularClaves() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error cargando config: %v", err)
+	}
+	database.ConnectDB(cfg)
+	defer database.CloseDB()
+
+	rows, err := database.DB.Query("SELECT id, nombre, primer_apellido, segundo_apellido FROM acta_personas")
+	if err != nil {
+		log.Fatalf("Error consultando personas: %v", err)
+	}
+	type persona struct {
+		id               int
+		nombre, ap1, ap2 string
+	}
+	var lista []persona
+	for rows.Next() {
+		var p persona
+		if err := rows.Scan(&p.id, &p.nombre, &p.ap1, &p.ap2); err != nil {
+			log.Fatalf("Error leyendo personas: %v", err)
+		}
+		lista = append(lista, p)
+	}
+	rows.Close()
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Fatalf("Error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+	for _, p := range lista {
+		if _, err := tx.Exec(`
+			UPDATE acta_personas
+			SET clave_nombre = ?, clave_primer_apellido = ?, clave_segundo_apellido = ?
+			WHERE id = ?`,
+			fonetica.Clave(p.nombre), fonetica.Clave(p.ap1), fonetica.Clave(p.ap2), p.id); err != nil {
+			log.Fatalf("Error actualizando persona %d: %v", p.id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Error guardando cambios: %v", err)
+	}
+	fmt.Printf("✅ Claves fonéticas recalculadas para %d personas\n", len(lista))
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"visor-pdf/internal/config"
+	"visor-pdf/internal/database"
+)
+
+// Importa el mapeo municipio -> distrito -> región desde el catálogo oficial.
+//
+// El CSV debe traer encabezados; se reconocen (sin importar mayúsculas):
+//   - cve_mun:  clave del municipio (obligatoria)
+//   - distrito: nombre o clave numérica del distrito (obligatoria)
+//   - region:   nombre de la región, solo se usa si el distrito no existe
+//
+// Ejecutar: go run ./cmd/importar-regiones -csv catalogo.csv [-dry-run]
+func main() {
+	archivo := flag.String("csv", "", "ruta del CSV con el catálogo")
+	dryRun := flag.Bool("dry-run", false, "mostrar los cambios sin guardarlos")
+	flag.Parse()
+
+	if *archivo == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error cargando config: %v", err)
+	}
+	database.ConnectDB(cfg)
+	defer database.CloseDB()
+
+	f, err := os.Open(*archivo)
+	if err != nil {
+		log.Fatalf("Error abriendo %s: %v", *archivo, err)
+	}
+	defer f.Close()
+
+	lector := csv.NewReader(f)
+	lector.TrimLeadingSpace = true
+	encabezado, err := lector.Read()
+	if err != nil {
+		log.Fatalf("Error leyendo encabezado: %v", err)
+	}
+	columnas := map[string]int{}
+	for i, nombre := range encabezado {
+		columnas[strings.ToLower(strings.TrimSpace(nombre))] = i
+	}
+	colMunicipio, ok1 := columnas["cve_mun"]
+	colDistrito, ok2 := columnas["distrito"]
+	colRegion, tieneRegion := columnas["region"]
+	if !ok1 || !ok2 {
+		log.Fatalf("El CSV debe tener las columnas cve_mun y distrito")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Fatalf("Error iniciando transacción: %v", err)
+	}
+	defer tx.Rollback()
+
+	var mapeados, creados, omitidos int
+	linea := 1
+	for {
+		registro, err := lector.Read()
+		if err == io.EOF {
+			break
+		}
+		linea++
+		if err != nil {
+			log.Fatalf("Error en línea %d: %v", linea, err)
+		}
+
+		municipioID, err := strconv.Atoi(strings.TrimSpace(registro[colMunicipio]))
+		if err != nil {
+			fmt.Printf("⚠️  Línea %d: clave de municipio inválida %q, se omite\n", linea, registro[colMunicipio])
+			omitidos++
+			continue
+		}
+		region := ""
+		if tieneRegion {
+			region = strings.TrimSpace(registro[colRegion])
+		}
+
+		distritoID, nuevo, err := buscarDistrito(tx, strings.TrimSpace(registro[colDistrito]), region)
+		if err != nil {
+			fmt.Printf("⚠️  Línea %d: %v, se omite\n", linea, err)
+			omitidos++
+			continue
+		}
+		if nuevo {
+			creados++
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO distrito_municipios (municipio_id, distrito_id) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE distrito_id = VALUES(distrito_id)`,
+			municipioID, distritoID); err != nil {
+			fmt.Printf("⚠️  Línea %d: no se pudo mapear el municipio %d: %v\n", linea, municipioID, err)
+			omitidos++
+			continue
+		}
+		mapeados++
+	}
+
+	fmt.Println("=================================")
+	fmt.Println("Municipios mapeados:", mapeados)
+	fmt.Println("Distritos creados:  ", creados)
+	fmt.Println("Líneas omitidas:    ", omitidos)
+	fmt.Println("=================================")
+
+	if *dryRun {
+		fmt.Println("ℹ️  dry-run: no se guardó ningún cambio")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatalf("Error guardando cambios: %v", err)
+	}
+	fmt.Println("✅ Catálogo de regiones importado correctamente")
+}
+
+// buscarDistrito localiza un distrito por clave o nombre. Si no existe y se
+// conoce la región, lo crea (creando también la región si hace falta).
+func buscarDistrito(tx *sql.Tx, distrito, region string) (int, bool, error) {
+	if distrito == "" {
+		return 0, false, fmt.Errorf("distrito vacío")
+	}
+
+	var id int
+	if clave, err := strconv.Atoi(distrito); err == nil {
+		err = tx.QueryRow("SELECT id FROM distritos WHERE id = ?", clave).Scan(&id)
+		if err == sql.ErrNoRows {
+			return 0, false, fmt.Errorf("no existe el distrito %d", clave)
+		}
+		return id, false, err
+	}
+
+	err := tx.QueryRow("SELECT id FROM distritos WHERE nombre = ?", distrito).Scan(&id)
+	if err == nil {
+		return id, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, err
+	}
+	if region == "" {
+		return 0, false, fmt.Errorf("no existe el distrito %q y no se indicó región", distrito)
+	}
+
+	var regionID int
+	err = tx.QueryRow("SELECT id FROM regiones WHERE nombre = ?", region).Scan(&regionID)
+	if err == sql.ErrNoRows {
+		if err := tx.QueryRow("SELECT COALESCE(MAX(id), 0) + 1 FROM regiones").Scan(&regionID); err != nil {
+			return 0, false, err
+		}
+		if _, err := tx.Exec("INSERT INTO regiones (id, nombre) VALUES (?, ?)", regionID, region); err != nil {
+			return 0, false, err
+		}
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	if err := tx.QueryRow("SELECT COALESCE(MAX(id), 0) + 1 FROM distritos").Scan(&id); err != nil {
+		return 0, false, err
+	}
+	if _, err := tx.Exec("INSERT INTO distritos (id, region_id, nombre) VALUES (?, ?, ?)", id, regionID, distrito); err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/almacen"
+	"visor-pdf/internal/config"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/integridad"
+	"visor-pdf/internal/rutas"
+)
+
+// Exporta un manifiesto estilo BagIt (RFC 8493) por cada directorio
+// "decada AAA0" de una raíz del archivo (la principal por defecto), con los
+// SHA-256 que registró el verificador de integridad. Solo aplica a raíces
+// cuya plantilla empieza por el directorio de la década.
+//
+// Por cada década se escribe en <salida>/<decada>/:
+//   - bagit.txt
+//   - bag-info.txt:            totales, Payload-Oxum y actas por acto
+//   - manifest-sha256.txt:     "<sha256>  data/<ruta dentro de la década>"
+//   - tagmanifest-sha256.txt:  hashes de los tres archivos anteriores
+//
+// Los PDFs no se copian: data/ corresponde al directorio de la década, de
+// modo que la bolsa se puede validar copiando el manifiesto junto a él. Por
+// eso el manifiesto debe listar todos los archivos del directorio (RFC 8493,
+// sección 3): si alguno no está en estado ok (tiene una incidencia sin
+// revisar) o no está registrado, no se escribe la bolsa de esa década y se
+// reporta por qué. Con -recalcular los hashes se vuelven a calcular desde el
+// archivo y un hash distinto del registrado también impide escribirla.
+//
+// Ejecutar: go run ./cmd/manifiesto-bagit [-raiz principal] [-decada 1950] [-salida manifiestos] [-recalcular]
+func main() {
+	nombreRaiz := flag.String("raiz", rutas.NombrePrincipal, "raíz del archivo a exportar")
+	decada := flag.Int("decada", 0, "exportar solo esta década (p. ej. 1950)")
+	salida := flag.String("salida", "manifiestos", "directorio donde se escriben los manifiestos")
+	recalcular := flag.Bool("recalcular", false, "recalcular los hashes desde el archivo")
+	flag.Parse()
+
+	if *decada%10 != 0 || *decada < 0 {
+		log.Fatalf("-decada debe ser un año terminado en 0")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error cargando config: %v", err)
+	}
+	if err := rutas.Configurar(cfg); err != nil {
+		log.Fatalf("Error en la configuración de raíces de PDFs: %v", err)
+	}
+	var raiz *rutas.Raiz
+	for _, r := range rutas.Raices() {
+		if r.Nombre == *nombreRaiz {
+			raiz = &r
+			break
+		}
+	}
+	if raiz == nil {
+		log.Fatalf("No hay una raíz llamada %q", *nombreRaiz)
+	}
+	database.ConnectDB(cfg)
+	defer database.CloseDB()
+
+	actos, err := cargarActos()
+	if err != nil {
+		log.Fatalf("Error cargando actos: %v", err)
+	}
+
+	prefijo := "decada %"
+	if *decada > 0 {
+		prefijo = fmt.Sprintf("decada %d/%%", *decada)
+	}
+	rows, err := database.DB.Query(`
+		SELECT ruta, COALESCE(acto, ''), tamano, sha256_ultimo, estado
+		FROM archivos_pdf
+		WHERE raiz = ? AND ruta LIKE ?
+		ORDER BY ruta`, raiz.Nombre, prefijo)
+	if err != nil {
+		log.Fatalf("Error consultando archivos: %v", err)
+	}
+	defer rows.Close()
+
+	bolsas := map[string]*bolsa{}
+	bolsaDe := func(decada string) *bolsa {
+		b, ok := bolsas[decada]
+		if !ok {
+			b = &bolsa{actos: map[string]int{}}
+			bolsas[decada] = b
+		}
+		return b
+	}
+	registrados := map[string]bool{}
+	for rows.Next() {
+		var ruta, acto, hash, estado string
+		var tamano int64
+		if err := rows.Scan(&ruta, &acto, &tamano, &hash, &estado); err != nil {
+			log.Fatalf("Error leyendo datos: %v", err)
+		}
+		partes := strings.SplitN(ruta, "/", 2)
+		if len(partes) != 2 {
+			continue
+		}
+		registrados[ruta] = true
+		b := bolsaDe(partes[0])
+		if estado != integridad.EstadoOK {
+			b.problemas = append(b.problemas, fmt.Sprintf("%s: estado %s", ruta, estado))
+			continue
+		}
+		if *recalcular {
+			actual, err := integridad.CalcularSHA256(raiz.Almacen, ruta)
+			if err != nil {
+				b.problemas = append(b.problemas, fmt.Sprintf("%s: %v", ruta, err))
+				continue
+			}
+			if actual != hash {
+				b.problemas = append(b.problemas, fmt.Sprintf("%s: el hash no coincide con el registrado", ruta))
+				continue
+			}
+		}
+
+		b.lineas = append(b.lineas, hash+"  data/"+partes[1])
+		b.bytes += tamano
+		b.actos[acto]++
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatalf("Error leyendo datos: %v", err)
+	}
+	if len(bolsas) == 0 {
+		log.Fatalf("No hay archivos verificados; ejecuta primero la verificación de integridad")
+	}
+
+	// Cualquier archivo del directorio de la década es parte de la carga de
+	// la bolsa, aunque el verificador no lo haya registrado
+	err = raiz.Almacen.Listar(strings.TrimSuffix(prefijo, "%"), func(info almacen.Info) error {
+		partes := strings.SplitN(info.Ruta, "/", 2)
+		if len(partes) != 2 || registrados[info.Ruta] {
+			return nil
+		}
+		b := bolsaDe(partes[0])
+		b.problemas = append(b.problemas, fmt.Sprintf("%s: no está registrado", info.Ruta))
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Error recorriendo la raíz %q: %v", raiz.Nombre, err)
+	}
+
+	nombres := make([]string, 0, len(bolsas))
+	for n := range bolsas {
+		nombres = append(nombres, n)
+	}
+	sort.Strings(nombres)
+	total, rechazadas := 0, 0
+	for _, n := range nombres {
+		b := bolsas[n]
+		if len(b.problemas) > 0 {
+			fmt.Printf("❌ %s: no se escribe la bolsa, %d archivos no se pueden incluir:\n", n, len(b.problemas))
+			for i, p := range b.problemas {
+				if i == maxProblemas {
+					fmt.Printf("   ... y %d más\n", len(b.problemas)-maxProblemas)
+					break
+				}
+				fmt.Printf("   %s\n", p)
+			}
+			rechazadas++
+			continue
+		}
+		if err := b.escribir(filepath.Join(*salida, n), n, actos); err != nil {
+			log.Fatalf("Error escribiendo manifiesto de %s: %v", n, err)
+		}
+		fmt.Printf("📦 %s: %d archivos\n", n, len(b.lineas))
+		total += len(b.lineas)
+	}
+
+	fmt.Println("\n=================================")
+	fmt.Printf("Décadas exportadas: %d\n", len(bolsas)-rechazadas)
+	fmt.Printf("Archivos en manifiestos: %d\n", total)
+	fmt.Printf("Décadas sin bolsa: %d\n", rechazadas)
+	fmt.Println("=================================")
+	if rechazadas > 0 {
+		fmt.Println("⚠️  Revisa las incidencias de integridad o ejecuta la verificación y vuelve a exportar")
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Manifiestos escritos en %s\n", *salida)
+}
+
+// Archivos con problema que se listan por década antes de resumir
+const maxProblemas = 20
+
+// bolsa acumula el manifiesto de una década y los archivos que impiden
+// escribirlo
+type bolsa struct {
+	lineas    []string
+	bytes     int64
+	actos     map[string]int
+	problemas []string
+}
+
+func (b *bolsa) escribir(dir, decada string, nombresActo map[string]string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var info strings.Builder
+	fmt.Fprintf(&info, "Source-Organization: Registro Civil\n")
+	fmt.Fprintf(&info, "External-Identifier: %s\n", decada)
+	fmt.Fprintf(&info, "Bagging-Date: %s\n", time.Now().Format("2006-01-02"))
+	fmt.Fprintf(&info, "Payload-Oxum: %d.%d\n", b.bytes, len(b.lineas))
+	codigos := make([]string, 0, len(b.actos))
+	for c := range b.actos {
+		codigos = append(codigos, c)
+	}
+	sort.Strings(codigos)
+	for _, c := range codigos {
+		nombre := nombresActo[c]
+		if nombre == "" {
+			nombre = "Acto " + c
+		}
+		fmt.Fprintf(&info, "Internal-Sender-Description: %s (%s): %d actas\n", nombre, c, b.actos[c])
+	}
+
+	archivos := []struct {
+		nombre, contenido string
+	}{
+		{"bagit.txt", "BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n"},
+		{"bag-info.txt", info.String()},
+		{"manifest-sha256.txt", strings.Join(b.lineas, "\n") + "\n"},
+	}
+	var tags strings.Builder
+	for _, a := range archivos {
+		if err := os.WriteFile(filepath.Join(dir, a.nombre), []byte(a.contenido), 0644); err != nil {
+			return err
+		}
+		suma := sha256.Sum256([]byte(a.contenido))
+		fmt.Fprintf(&tags, "%s  %s\n", hex.EncodeToString(suma[:]), a.nombre)
+	}
+	return os.WriteFile(filepath.Join(dir, "tagmanifest-sha256.txt"), []byte(tags.String()), 0644)
+}
+
+func cargarActos() (map[string]string, error) {
+	rows, err := database.DB.Query("SELECT codigo, nombre FROM actos")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actos := map[string]string{}
+	for rows.Next() {
+		var codigo, nombre string
+		if err := rows.Scan(&codigo, &nombre); err != nil {
+			return nil, err
+		}
+		actos[codigo] = nombre
+	}
+	return actos, rows.Err()
+}
@@ -5,10 +5,14 @@ import (
 	"log"
 	"net/http"
 
+	"visor-pdf/internal/auditoria"
 	"visor-pdf/internal/auth"
 	"visor-pdf/internal/config"
 	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
 	"visor-pdf/internal/handlers"
+	"visor-pdf/internal/integridad"
+	"visor-pdf/internal/rutas"
 )
 
 var cfg config.Config
@@ -20,28 +24,103 @@ func main() {
 		log.Fatalf("Error cargando config: %v", err)
 	}
 
+	// Raíces del archivo y sus plantillas de rutas
+	if err := rutas.Configurar(cfg); err != nil {
+		log.Fatalf("Error en la configuración de raíces de PDFs: %v", err)
+	}
+	if err := estados.Configurar(cfg); err != nil {
+		log.Fatalf("Error en la configuración de estados: %v", err)
+	}
+
 	// Configurar handlers con la configuración
 	handlers.SetConfig(cfg)
+	auditoria.SetConfig(cfg)
 
 	database.ConnectDB(cfg)
 	defer database.CloseDB()
 
+	integridad.Iniciar(cfg)
+
+	if cfg.ClaveVerificacion == "" {
+		log.Println("⚠️  CLAVE_VERIFICACION no está configurada: la emisión y verificación de copias certificadas quedan deshabilitadas")
+	}
+	if !handlers.URLPublicaValida(cfg.URLPublica) {
+		log.Printf("⚠️  URL_PUBLICA (%q) no es una dirección pública: la emisión y verificación de copias certificadas quedan deshabilitadas", cfg.URLPublica)
+	}
+
 	// Servir archivos estáticos
 	http.Handle("/front/", http.StripPrefix("/front/", http.FileServer(http.Dir("../../front"))))
 
 	// Rutas de la API
 	http.HandleFunc("/api/login", auth.Login) // Login no requiere middleware
 
+	// Verificación pública de copias certificadas (destino del QR, limitada por IP)
+	http.HandleFunc("/verificar/", handlers.VerificarCopia)
+
 	// Endpoints protegidos con autenticación
+	http.HandleFunc("/api/estados", auth.AuthMiddleware(handlers.GetEstados))
 	http.HandleFunc("/api/municipios", auth.AuthMiddleware(handlers.GetMunicipios))
 	http.HandleFunc("/api/localidades", auth.AuthMiddleware(handlers.GetLocalidades))
+	http.HandleFunc("/api/oficialias", auth.AuthMiddleware(handlers.GetOficialias))
+	http.HandleFunc("/api/actos", auth.AuthMiddleware(handlers.GetActos))
 	http.HandleFunc("/api/pdf", auth.AuthMiddleware(handlers.GetPDFAsImage))
+	http.HandleFunc("/api/pdf/download", auth.AuthMiddleware(handlers.DescargarPDF))
+	http.HandleFunc("/api/pdf/info", auth.AuthMiddleware(handlers.InfoPDF))
+	http.HandleFunc("/api/pdf/thumbnail", auth.AuthMiddleware(handlers.MiniaturaPDF))
+	http.HandleFunc("/api/pdf/dzi", auth.AuthMiddleware(handlers.IniciarDZI))
+	http.HandleFunc("/api/pdf/dzi/", auth.AuthMiddleware(handlers.RecursoDZI))
+	http.HandleFunc("/api/copias/emitir", auth.AuthMiddleware(handlers.EmitirCopia))
+	http.HandleFunc("/api/anotaciones", auth.AuthMiddleware(handlers.ListarAnotaciones))
+	http.HandleFunc("/api/anotaciones/crear", auth.AuthMiddleware(handlers.CrearAnotacion))
+	http.HandleFunc("/api/anotaciones/editar", auth.AuthMiddleware(handlers.EditarAnotacion))
+	http.HandleFunc("/api/anotaciones/eliminar", auth.AuthMiddleware(handlers.EliminarAnotacion))
+	http.HandleFunc("/api/anotaciones/w3c", auth.AuthMiddleware(handlers.ExportarAnotacionesW3C))
+	http.HandleFunc("/api/marginales", auth.AuthMiddleware(handlers.BuscarMarginales))
+	http.HandleFunc("/api/marginales/crear", auth.AuthMiddleware(handlers.CrearMarginal))
+	http.HandleFunc("/api/marginales/editar", auth.AuthMiddleware(handlers.EditarMarginal))
+	http.HandleFunc("/api/marginales/eliminar", auth.AuthMiddleware(handlers.EliminarMarginal))
+	http.HandleFunc("/api/marginales/historial", auth.AuthMiddleware(handlers.HistorialMarginal))
+	http.HandleFunc("/api/calidad/reportar", auth.AuthMiddleware(handlers.ReportarCalidad))
+	http.HandleFunc("/api/calidad/reportes", auth.AuthMiddleware(handlers.ListarReportesCalidad))
+	http.HandleFunc("/api/calidad/triage", auth.AuthMiddleware(handlers.TriageCalidad))
+	http.HandleFunc("/api/actas/buscar-persona", auth.AuthMiddleware(handlers.BuscarPersona))
 
 	// Endpoints de administración (requieren ser admin)
 	http.HandleFunc("/api/admin/usuarios", auth.AdminMiddleware(handlers.ListarUsuarios))
 	http.HandleFunc("/api/admin/usuarios/crear", auth.AdminMiddleware(handlers.CrearUsuario))
 	http.HandleFunc("/api/admin/usuarios/asignar-municipios", auth.AdminMiddleware(handlers.AsignarMunicipiosUsuario))
 	http.HandleFunc("/api/admin/roles", auth.AdminMiddleware(handlers.ObtenerRoles))
+	http.HandleFunc("/api/admin/regiones", auth.AdminMiddleware(handlers.GetRegiones))
+	http.HandleFunc("/api/admin/usuarios/regiones", auth.AdminMiddleware(handlers.ObtenerRegionesUsuario))
+	http.HandleFunc("/api/admin/usuarios/asignar-regiones", auth.AdminMiddleware(handlers.AsignarRegionesUsuario))
+	http.HandleFunc("/api/admin/cuotas", auth.AdminMiddleware(handlers.ListarCuotas))
+	http.HandleFunc("/api/admin/cuotas/guardar", auth.AdminMiddleware(handlers.GuardarCuota))
+	http.HandleFunc("/api/admin/cuotas/eliminar", auth.AdminMiddleware(handlers.EliminarCuota))
+	http.HandleFunc("/api/admin/alertas", auth.AdminMiddleware(handlers.ListarAlertas))
+	http.HandleFunc("/api/admin/alertas/revisar", auth.AdminMiddleware(handlers.RevisarAlerta))
+	http.HandleFunc("/api/admin/bitacora", auth.AdminMiddleware(handlers.ListarBitacora))
+	http.HandleFunc("/api/admin/descargas", auth.AdminMiddleware(handlers.ListarDescargas))
+	http.HandleFunc("/api/admin/permisos", auth.AdminMiddleware(handlers.ListarPermisos))
+	http.HandleFunc("/api/admin/permisos/otorgar", auth.AdminMiddleware(handlers.OtorgarPermiso))
+	http.HandleFunc("/api/admin/permisos/revocar", auth.AdminMiddleware(handlers.RevocarPermiso))
+	http.HandleFunc("/api/admin/copias", auth.AdminMiddleware(handlers.ListarCopias))
+	http.HandleFunc("/api/admin/copias/revocar", auth.AdminMiddleware(handlers.RevocarCopia))
+	http.HandleFunc("/api/admin/marcas-agua", auth.AdminMiddleware(handlers.ListarMarcasAgua))
+	http.HandleFunc("/api/admin/marcas-agua/guardar", auth.AdminMiddleware(handlers.GuardarMarcaAgua))
+	http.HandleFunc("/api/admin/municipios/crear", auth.AdminMiddleware(handlers.CrearMunicipio))
+	http.HandleFunc("/api/admin/municipios/renombrar", auth.AdminMiddleware(handlers.RenombrarMunicipio))
+	http.HandleFunc("/api/admin/municipios/fusionar", auth.AdminMiddleware(handlers.FusionarMunicipio))
+	http.HandleFunc("/api/admin/municipios/estado", auth.AdminMiddleware(handlers.CambiarEstadoMunicipio))
+	http.HandleFunc("/api/admin/localidades/crear", auth.AdminMiddleware(handlers.CrearLocalidad))
+	http.HandleFunc("/api/admin/localidades/renombrar", auth.AdminMiddleware(handlers.RenombrarLocalidad))
+	http.HandleFunc("/api/admin/localidades/fusionar", auth.AdminMiddleware(handlers.FusionarLocalidad))
+	http.HandleFunc("/api/admin/localidades/estado", auth.AdminMiddleware(handlers.CambiarEstadoLocalidad))
+	http.HandleFunc("/api/admin/catalogo/cambios", auth.AdminMiddleware(handlers.ListarCambiosCatalogo))
+	http.HandleFunc("/api/admin/integridad", auth.AdminMiddleware(handlers.EstadoIntegridad))
+	http.HandleFunc("/api/admin/integridad/verificar", auth.AdminMiddleware(handlers.VerificarIntegridad))
+	http.HandleFunc("/api/admin/integridad/incidencias", auth.AdminMiddleware(handlers.ListarIncidencias))
+	http.HandleFunc("/api/admin/integridad/revisar", auth.AdminMiddleware(handlers.RevisarIncidencia))
+	http.HandleFunc("/api/admin/reportes/cobertura", auth.AdminMiddleware(handlers.ReporteCobertura))
 
 	// Este endpoint lo usan tanto admins como usuarios regulares para ver sus municipios
 	http.HandleFunc("/api/admin/usuarios/municipios", handlers.ObtenerMunicipiosUsuario)
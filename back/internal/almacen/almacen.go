@@ -0,0 +1,130 @@
+// Package almacen abstrae dónde se guardan los PDFs del archivo: un
+// directorio local (o unidad de red montada) o un bucket compatible con S3.
+//
+// Las rutas son siempre relativas a la raíz del almacén y usan '/'. Un
+// archivo que no existe se reporta con un error que cumple
+// errors.Is(err, fs.ErrNotExist) en todas las implementaciones.
+package almacen
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/config"
+)
+
+// Info describe un archivo del almacén
+type Info struct {
+	Ruta       string // relativa a la raíz, con '/'
+	Tamano     int64
+	Modificado time.Time
+}
+
+// Almacen es el origen de los PDFs de una raíz del archivo
+type Almacen interface {
+	// Abrir devuelve el contenido completo del archivo
+	Abrir(ruta string) (io.ReadCloser, error)
+	// Info devuelve tamaño y fecha de modificación; un directorio cuenta
+	// como inexistente
+	Info(ruta string) (Info, error)
+	// LeerRango devuelve n bytes a partir de desde (menos si el archivo
+	// termina antes)
+	LeerRango(ruta string, desde, n int64) (io.ReadCloser, error)
+	// Listar llama a fn con cada archivo cuya ruta empieza por prefijo
+	// ("" = todos). Si fn devuelve error, el recorrido se detiene con él.
+	// Si una parte del almacén no se puede leer también devuelve error, en
+	// lugar de omitir en silencio los archivos que no vio.
+	Listar(prefijo string, fn func(Info) error) error
+}
+
+// Nuevo crea el almacén de una ruta configurada: "s3://bucket/prefijo" usa
+// el servicio S3 de la configuración y cualquier otra cosa es un directorio
+// local.
+func Nuevo(ruta string, c config.Config) (Almacen, error) {
+	if !strings.HasPrefix(ruta, "s3://") {
+		return NuevoLocal(ruta), nil
+	}
+
+	u, err := url.Parse(ruta)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("ruta S3 inválida %q (se espera s3://bucket/prefijo)", ruta)
+	}
+	if c.S3Endpoint == "" {
+		return nil, fmt.Errorf("la ruta %q necesita S3_ENDPOINT", ruta)
+	}
+	return NuevoS3(S3Config{
+		Endpoint:  c.S3Endpoint,
+		Region:    c.S3Region,
+		AccessKey: c.S3AccessKey,
+		SecretKey: c.S3SecretKey,
+		Bucket:    u.Host,
+		Prefijo:   strings.Trim(u.Path, "/"),
+	})
+}
+
+// lectorLimitado cierra el archivo de origen al terminar de leer un rango
+type lectorLimitado struct {
+	io.Reader
+	io.Closer
+}
+
+// Lector permite leer un archivo del almacén con Seek, pidiendo al almacén
+// solo el rango que se lee (p. ej. para http.ServeContent)
+type Lector struct {
+	almacen Almacen
+	ruta    string
+	tamano  int64
+	pos     int64
+	actual  io.ReadCloser
+}
+
+// NuevoLector crea un lector del archivo; tamano es el que devolvió Info
+func NuevoLector(a Almacen, ruta string, tamano int64) *Lector {
+	return &Lector{almacen: a, ruta: ruta, tamano: tamano}
+}
+
+func (l *Lector) Read(p []byte) (int, error) {
+	if l.pos >= l.tamano {
+		return 0, io.EOF
+	}
+	if l.actual == nil {
+		r, err := l.almacen.LeerRango(l.ruta, l.pos, l.tamano-l.pos)
+		if err != nil {
+			return 0, err
+		}
+		l.actual = r
+	}
+	n, err := l.actual.Read(p)
+	l.pos += int64(n)
+	return n, err
+}
+
+func (l *Lector) Seek(desplazamiento int64, desde int) (int64, error) {
+	switch desde {
+	case io.SeekCurrent:
+		desplazamiento += l.pos
+	case io.SeekEnd:
+		desplazamiento += l.tamano
+	}
+	if desplazamiento < 0 {
+		return 0, fmt.Errorf("posición negativa")
+	}
+	if desplazamiento != l.pos {
+		l.Close()
+		l.pos = desplazamiento
+	}
+	return l.pos, nil
+}
+
+// Close libera la lectura en curso; el lector se puede seguir usando
+func (l *Lector) Close() error {
+	if l.actual == nil {
+		return nil
+	}
+	err := l.actual.Close()
+	l.actual = nil
+	return err
+}
@@ -0,0 +1,82 @@
+package almacen
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// rangosContados registra los rangos que pide el Lector
+type rangosContados struct {
+	Almacen
+	pedidos [][2]int64
+}
+
+func (r *rangosContados) LeerRango(ruta string, desde, n int64) (io.ReadCloser, error) {
+	r.pedidos = append(r.pedidos, [2]int64{desde, n})
+	return r.Almacen.LeerRango(ruta, desde, n)
+}
+
+func TestLector(t *testing.T) {
+	m := NuevaMemoria()
+	m.Guardar("a.pdf", []byte("0123456789"))
+	contado := &rangosContados{Almacen: m}
+	l := NuevoLector(contado, "a.pdf", 10)
+	defer l.Close()
+
+	buf := make([]byte, 3)
+	if n, _ := io.ReadFull(l, buf); n != 3 || string(buf) != "012" {
+		t.Fatalf("primera lectura = %q", buf[:n])
+	}
+	if pos, err := l.Seek(-2, io.SeekEnd); err != nil || pos != 8 {
+		t.Fatalf("Seek(-2, fin) = %d, %v", pos, err)
+	}
+	if resto, _ := io.ReadAll(l); string(resto) != "89" {
+		t.Errorf("tras Seek = %q", resto)
+	}
+	if pos, _ := l.Seek(4, io.SeekStart); pos != 4 {
+		t.Fatalf("Seek(4) = %d", pos)
+	}
+	if pos, _ := l.Seek(1, io.SeekCurrent); pos != 5 {
+		t.Fatalf("Seek(+1) = %d", pos)
+	}
+	if resto, _ := io.ReadAll(l); string(resto) != "56789" {
+		t.Errorf("tras Seek relativo = %q", resto)
+	}
+	if _, err := l.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Seek a posición negativa debe fallar")
+	}
+
+	// Cada reposicionamiento pide al almacén solo lo que falta del archivo
+	esperado := [][2]int64{{0, 10}, {8, 2}, {5, 5}}
+	if len(contado.pedidos) != len(esperado) {
+		t.Fatalf("rangos pedidos = %v, se esperaba %v", contado.pedidos, esperado)
+	}
+	for i := range esperado {
+		if contado.pedidos[i] != esperado[i] {
+			t.Errorf("rango %d = %v, se esperaba %v", i, contado.pedidos[i], esperado[i])
+		}
+	}
+}
+
+func TestLectorServeContent(t *testing.T) {
+	m := NuevaMemoria()
+	m.Guardar("a.pdf", []byte("0123456789"))
+	contado := &rangosContados{Almacen: m}
+
+	req := httptest.NewRequest(http.MethodGet, "/a.pdf", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	rec := httptest.NewRecorder()
+	http.ServeContent(rec, req, "a.pdf", time.Time{}, NuevoLector(contado, "a.pdf", 10))
+
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "2345" {
+		t.Fatalf("respuesta %d %q", rec.Code, rec.Body.String())
+	}
+	for _, p := range contado.pedidos {
+		if p[0] == 0 && p[1] == 10 {
+			t.Errorf("se leyó el archivo completo para servir un rango: %v", contado.pedidos)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package almacen
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local guarda los archivos en un directorio del servidor
+type Local struct {
+	base string
+}
+
+// NuevoLocal crea un almacén sobre el directorio base
+func NuevoLocal(base string) *Local {
+	return &Local{base: base}
+}
+
+func (l *Local) ruta(ruta string) string {
+	return filepath.Join(l.base, filepath.FromSlash(ruta))
+}
+
+func (l *Local) Abrir(ruta string) (io.ReadCloser, error) {
+	return os.Open(l.ruta(ruta))
+}
+
+func (l *Local) Info(ruta string) (Info, error) {
+	info, err := os.Stat(l.ruta(ruta))
+	if err != nil {
+		// Sin el directorio base (una unidad o recurso compartido sin
+		// montar) no se sabe si el archivo existe: no es fs.ErrNotExist
+		if errors.Is(err, fs.ErrNotExist) {
+			if _, errBase := os.Stat(l.base); errBase != nil {
+				return Info{}, fmt.Errorf("directorio base no disponible: %v", errBase)
+			}
+		}
+		return Info{}, err
+	}
+	if info.IsDir() {
+		return Info{}, &fs.PathError{Op: "info", Path: ruta, Err: fs.ErrNotExist}
+	}
+	return Info{Ruta: ruta, Tamano: info.Size(), Modificado: info.ModTime()}, nil
+}
+
+func (l *Local) LeerRango(ruta string, desde, n int64) (io.ReadCloser, error) {
+	f, err := os.Open(l.ruta(ruta))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(desde, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return lectorLimitado{io.LimitReader(f, n), f}, nil
+}
+
+// Listar recorre el directorio. Un subdirectorio ilegible (p. ej. en una
+// unidad de red intermitente) detiene el recorrido con error: saltarlo haría
+// que quien llama tomara por borrados todos los archivos que contiene.
+func (l *Local) Listar(prefijo string, fn func(Info) error) error {
+	if info, err := os.Stat(l.base); err != nil || !info.IsDir() {
+		return fmt.Errorf("no se puede leer el directorio %s: %v", l.base, err)
+	}
+
+	// El recorrido empieza en el directorio más profundo que cubre el prefijo
+	inicio := l.base
+	if i := strings.LastIndex(prefijo, "/"); i >= 0 {
+		inicio = l.ruta(prefijo[:i])
+		if _, err := os.Stat(inicio); os.IsNotExist(err) {
+			return nil
+		}
+	}
+	return filepath.WalkDir(inicio, func(ruta string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(l.base, ruta)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !strings.HasPrefix(rel, prefijo) {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// Se borró después de leer el directorio
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fn(Info{Ruta: rel, Tamano: info.Size(), Modificado: info.ModTime()})
+	})
+}
@@ -0,0 +1,113 @@
+package almacen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func nuevoLocalPrueba(t *testing.T, archivos ...string) *Local {
+	t.Helper()
+	base := t.TempDir()
+	for _, ruta := range archivos {
+		completa := filepath.Join(base, filepath.FromSlash(ruta))
+		if err := os.MkdirAll(filepath.Dir(completa), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(completa, []byte(ruta), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NuevoLocal(base)
+}
+
+func listarRutas(t *testing.T, a Almacen, prefijo string) []string {
+	t.Helper()
+	var rutas []string
+	if err := a.Listar(prefijo, func(i Info) error {
+		rutas = append(rutas, i.Ruta)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(rutas)
+	return rutas
+}
+
+func TestLocalListar(t *testing.T) {
+	l := nuevoLocalPrueba(t, "20/1990/a.pdf", "20/1990/b.pdf", "20/1991/c.pdf", "21/d.pdf", "raiz.pdf")
+
+	if got, esperado := listarRutas(t, l, ""), []string{
+		"20/1990/a.pdf", "20/1990/b.pdf", "20/1991/c.pdf", "21/d.pdf", "raiz.pdf",
+	}; !reflect.DeepEqual(got, esperado) {
+		t.Errorf("Listar(\"\") = %v", got)
+	}
+	if got, esperado := listarRutas(t, l, "20/1990/"), []string{"20/1990/a.pdf", "20/1990/b.pdf"}; !reflect.DeepEqual(got, esperado) {
+		t.Errorf("Listar(20/1990/) = %v", got)
+	}
+	// El prefijo puede cortar un nombre
+	if got, esperado := listarRutas(t, l, "20/199"), []string{
+		"20/1990/a.pdf", "20/1990/b.pdf", "20/1991/c.pdf",
+	}; !reflect.DeepEqual(got, esperado) {
+		t.Errorf("Listar(20/199) = %v", got)
+	}
+	if got := listarRutas(t, l, "22/"); len(got) != 0 {
+		t.Errorf("Listar(22/) = %v", got)
+	}
+}
+
+func TestLocalListarSinBase(t *testing.T) {
+	l := NuevoLocal(filepath.Join(t.TempDir(), "no-montada"))
+	if err := l.Listar("", func(Info) error { return nil }); err == nil {
+		t.Fatal("se esperaba error con la raíz ausente")
+	}
+}
+
+func TestLocalListarSubdirectorioIlegible(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root puede leer cualquier directorio")
+	}
+	l := nuevoLocalPrueba(t, "20/1990/a.pdf", "20/1991/c.pdf")
+	ilegible := filepath.Join(l.base, "20", "1991")
+	if err := os.Chmod(ilegible, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(ilegible, 0o755)
+
+	if err := l.Listar("", func(Info) error { return nil }); err == nil {
+		t.Fatal("un subdirectorio ilegible debe detener el recorrido")
+	}
+}
+
+func TestLocalInfoYRango(t *testing.T) {
+	l := nuevoLocalPrueba(t, "20/a.pdf")
+
+	info, err := l.Info("20/a.pdf")
+	if err != nil || info.Tamano != int64(len("20/a.pdf")) || info.Ruta != "20/a.pdf" {
+		t.Fatalf("Info = %+v, %v", info, err)
+	}
+	if _, err := l.Info("20"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Info de un directorio: %v", err)
+	}
+	if _, err := l.Info("20/b.pdf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Info de un archivo inexistente: %v", err)
+	}
+	sinMontar := NuevoLocal(filepath.Join(t.TempDir(), "sin-montar"))
+	if _, err := sinMontar.Info("20/a.pdf"); err == nil || errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Info sin el directorio base debe fallar sin fs.ErrNotExist: %v", err)
+	}
+
+	r, err := l.LeerRango("20/a.pdf", 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if datos, _ := io.ReadAll(r); string(datos) != "a." {
+		t.Errorf("LeerRango = %q", datos)
+	}
+}
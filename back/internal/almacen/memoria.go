@@ -0,0 +1,103 @@
+package almacen
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memoria guarda los archivos en memoria; sirve para pruebas y para
+// ensayar el verificador sin tocar el archivo real
+type Memoria struct {
+	mu       sync.RWMutex
+	archivos map[string]archivoMemoria
+}
+
+type archivoMemoria struct {
+	datos      []byte
+	modificado time.Time
+}
+
+// NuevaMemoria crea un almacén en memoria vacío
+func NuevaMemoria() *Memoria {
+	return &Memoria{archivos: map[string]archivoMemoria{}}
+}
+
+// Guardar crea o reemplaza un archivo
+func (m *Memoria) Guardar(ruta string, datos []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.archivos[ruta] = archivoMemoria{datos: append([]byte(nil), datos...), modificado: time.Now()}
+}
+
+// Borrar elimina un archivo si existe
+func (m *Memoria) Borrar(ruta string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.archivos, ruta)
+}
+
+func (m *Memoria) buscar(op, ruta string) (archivoMemoria, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	a, ok := m.archivos[ruta]
+	if !ok {
+		return a, &fs.PathError{Op: op, Path: ruta, Err: fs.ErrNotExist}
+	}
+	return a, nil
+}
+
+func (m *Memoria) Abrir(ruta string) (io.ReadCloser, error) {
+	a, err := m.buscar("abrir", ruta)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(a.datos)), nil
+}
+
+func (m *Memoria) Info(ruta string) (Info, error) {
+	a, err := m.buscar("info", ruta)
+	if err != nil {
+		return Info{}, err
+	}
+	return Info{Ruta: ruta, Tamano: int64(len(a.datos)), Modificado: a.modificado}, nil
+}
+
+func (m *Memoria) LeerRango(ruta string, desde, n int64) (io.ReadCloser, error) {
+	a, err := m.buscar("leer", ruta)
+	if err != nil {
+		return nil, err
+	}
+	total := int64(len(a.datos))
+	if desde > total {
+		desde = total
+	}
+	fin := desde + n
+	if fin > total {
+		fin = total
+	}
+	return io.NopCloser(bytes.NewReader(a.datos[desde:fin])), nil
+}
+
+func (m *Memoria) Listar(prefijo string, fn func(Info) error) error {
+	m.mu.RLock()
+	var lista []Info
+	for ruta, a := range m.archivos {
+		if strings.HasPrefix(ruta, prefijo) {
+			lista = append(lista, Info{Ruta: ruta, Tamano: int64(len(a.datos)), Modificado: a.modificado})
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(lista, func(i, j int) bool { return lista[i].Ruta < lista[j].Ruta })
+	for _, info := range lista {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
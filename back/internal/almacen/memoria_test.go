@@ -0,0 +1,76 @@
+package almacen
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestMemoriaListar(t *testing.T) {
+	m := NuevaMemoria()
+	for _, ruta := range []string{"20/1990/b.pdf", "20/1990/a.pdf", "20/1991/c.pdf", "21/1990/d.pdf"} {
+		m.Guardar(ruta, []byte(ruta))
+	}
+
+	var rutas []string
+	err := m.Listar("20/", func(i Info) error {
+		rutas = append(rutas, i.Ruta)
+		if i.Tamano != int64(len(i.Ruta)) {
+			t.Errorf("%s: tamaño %d", i.Ruta, i.Tamano)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if esperado := []string{"20/1990/a.pdf", "20/1990/b.pdf", "20/1991/c.pdf"}; !reflect.DeepEqual(rutas, esperado) {
+		t.Errorf("Listar = %v, se esperaba %v", rutas, esperado)
+	}
+
+	// El error de fn detiene el recorrido
+	alto := errors.New("alto")
+	vistos := 0
+	err = m.Listar("", func(Info) error {
+		vistos++
+		return alto
+	})
+	if err != alto || vistos != 1 {
+		t.Errorf("Listar con error = %v tras %d archivos", err, vistos)
+	}
+}
+
+func TestMemoriaLeerRango(t *testing.T) {
+	m := NuevaMemoria()
+	m.Guardar("a.pdf", []byte("0123456789"))
+
+	casos := []struct {
+		desde, n int64
+		esperado string
+	}{
+		{0, 4, "0123"},
+		{6, 10, "6789"},
+		{10, 5, ""},
+		{20, 5, ""},
+	}
+	for _, c := range casos {
+		r, err := m.LeerRango("a.pdf", c.desde, c.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		datos, _ := io.ReadAll(r)
+		r.Close()
+		if string(datos) != c.esperado {
+			t.Errorf("LeerRango(%d, %d) = %q, se esperaba %q", c.desde, c.n, datos, c.esperado)
+		}
+	}
+
+	if _, err := m.LeerRango("b.pdf", 0, 1); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("archivo inexistente: %v", err)
+	}
+	m.Borrar("a.pdf")
+	if _, err := m.Info("a.pdf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("archivo borrado: %v", err)
+	}
+}
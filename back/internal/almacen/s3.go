@@ -0,0 +1,278 @@
+package almacen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config son los datos de conexión a un bucket compatible con S3 (AWS,
+// MinIO, Ceph, ...). Sin AccessKey las peticiones van sin firmar.
+type S3Config struct {
+	Endpoint  string // p. ej. https://s3.us-east-1.amazonaws.com o http://minio:9000
+	Region    string // por defecto us-east-1
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Prefijo   string // directorio dentro del bucket que actúa como raíz
+}
+
+// S3 lee los archivos de un bucket con la API REST (direcciones estilo
+// ruta, firma AWS Signature V4)
+type S3 struct {
+	cfg     S3Config
+	base    *url.URL
+	cliente *http.Client
+}
+
+// NuevoS3 crea el almacén; no contacta al servicio hasta la primera lectura
+func NuevoS3(c S3Config) (*S3, error) {
+	base, err := url.Parse(strings.TrimRight(c.Endpoint, "/"))
+	if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("endpoint S3 inválido: %q", c.Endpoint)
+	}
+	if c.Bucket == "" {
+		return nil, fmt.Errorf("falta el bucket S3")
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	c.Prefijo = strings.Trim(c.Prefijo, "/")
+	return &S3{cfg: c, base: base, cliente: &http.Client{Timeout: 5 * time.Minute}}, nil
+}
+
+// llave convierte una ruta relativa en la llave del objeto
+func (s *S3) llave(ruta string) string {
+	if s.cfg.Prefijo == "" {
+		return ruta
+	}
+	return s.cfg.Prefijo + "/" + ruta
+}
+
+func (s *S3) Abrir(ruta string) (io.ReadCloser, error) {
+	resp, err := s.pedir(http.MethodGet, s.llave(ruta), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := revisarRespuesta(resp, "abrir", ruta, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Info(ruta string) (Info, error) {
+	resp, err := s.pedir(http.MethodHead, s.llave(ruta), nil, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	resp.Body.Close()
+	if err := revisarRespuesta(resp, "info", ruta, http.StatusOK); err != nil {
+		return Info{}, err
+	}
+	info := Info{Ruta: ruta, Tamano: resp.ContentLength}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.Modificado = t
+	}
+	return info, nil
+}
+
+func (s *S3) LeerRango(ruta string, desde, n int64) (io.ReadCloser, error) {
+	if n <= 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	encabezados := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", desde, desde+n-1)}}
+	resp, err := s.pedir(http.MethodGet, s.llave(ruta), nil, encabezados)
+	if err != nil {
+		return nil, err
+	}
+	// 416: el rango empieza después del final del archivo
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		resp.Body.Close()
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	if err := revisarRespuesta(resp, "leer", ruta, http.StatusPartialContent, http.StatusOK); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		// El servicio ignoró el rango y mandó el archivo completo
+		if _, err := io.CopyN(io.Discard, resp.Body, desde); err != nil && err != io.EOF {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	return lectorLimitado{io.LimitReader(resp.Body, n), resp.Body}, nil
+}
+
+// respuestaLista es la parte que se usa de ListObjectsV2
+type respuestaLista struct {
+	Objetos []struct {
+		Llave      string    `xml:"Key"`
+		Tamano     int64     `xml:"Size"`
+		Modificado time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	Truncada  bool   `xml:"IsTruncated"`
+	Siguiente string `xml:"NextContinuationToken"`
+}
+
+func (s *S3) Listar(prefijo string, fn func(Info) error) error {
+	prefijoLlave := s.llave(prefijo)
+	continuar := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefijoLlave}}
+		if continuar != "" {
+			q.Set("continuation-token", continuar)
+		}
+		resp, err := s.pedir(http.MethodGet, "", q, nil)
+		if err != nil {
+			return err
+		}
+		if err := revisarRespuesta(resp, "listar", prefijo, http.StatusOK); err != nil {
+			return fmt.Errorf("no se puede listar el bucket %s: %v", s.cfg.Bucket, err)
+		}
+		var lista respuestaLista
+		err = xml.NewDecoder(resp.Body).Decode(&lista)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("respuesta inválida al listar el bucket %s: %v", s.cfg.Bucket, err)
+		}
+
+		for _, o := range lista.Objetos {
+			// Los "directorios" creados desde la consola son objetos vacíos con '/'
+			if strings.HasSuffix(o.Llave, "/") {
+				continue
+			}
+			ruta := o.Llave
+			if s.cfg.Prefijo != "" {
+				ruta = strings.TrimPrefix(ruta, s.cfg.Prefijo+"/")
+			}
+			if err := fn(Info{Ruta: ruta, Tamano: o.Tamano, Modificado: o.Modificado}); err != nil {
+				return err
+			}
+		}
+		if !lista.Truncada || lista.Siguiente == "" {
+			return nil
+		}
+		continuar = lista.Siguiente
+	}
+}
+
+// revisarRespuesta convierte un código inesperado en error (404 cumple
+// fs.ErrNotExist) y cierra el cuerpo en ese caso
+func revisarRespuesta(resp *http.Response, op, ruta string, esperados ...int) error {
+	for _, c := range esperados {
+		if resp.StatusCode == c {
+			return nil
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return &fs.PathError{Op: op, Path: ruta, Err: fs.ErrNotExist}
+	}
+	detalle, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &fs.PathError{Op: op, Path: ruta, Err: fmt.Errorf("S3 respondió %s: %s", resp.Status, strings.TrimSpace(string(detalle)))}
+}
+
+// pedir hace una petición al bucket; llave vacía se refiere al bucket
+func (s *S3) pedir(metodo, llave string, q url.Values, encabezados http.Header) (*http.Response, error) {
+	u := *s.base
+	u.Path = s.base.Path + "/" + s.cfg.Bucket
+	if llave != "" {
+		u.Path += "/" + llave
+	}
+	u.RawPath = escaparS3(u.Path, true)
+	u.RawQuery = consultaCanonica(q)
+
+	req, err := http.NewRequest(metodo, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range encabezados {
+		req.Header[k] = v
+	}
+	if s.cfg.AccessKey != "" {
+		s.firmar(req, u.RawPath, u.RawQuery, time.Now().UTC())
+	}
+	return s.cliente.Do(req)
+}
+
+// firmar agrega la firma AWS Signature V4. El cuerpo siempre está vacío, así
+// que no se firma su contenido (UNSIGNED-PAYLOAD) salvo que la petición ya
+// traiga X-Amz-Content-Sha256.
+func (s *S3) firmar(req *http.Request, rutaCanonica, consulta string, ahora time.Time) {
+	contenido := req.Header.Get("X-Amz-Content-Sha256")
+	if contenido == "" {
+		contenido = "UNSIGNED-PAYLOAD"
+		req.Header.Set("X-Amz-Content-Sha256", contenido)
+	}
+	fecha := ahora.Format("20060102T150405Z")
+	dia := ahora.Format("20060102")
+	req.Header.Set("X-Amz-Date", fecha)
+
+	firmados := "host;x-amz-content-sha256;x-amz-date"
+	canonica := strings.Join([]string{
+		req.Method,
+		rutaCanonica,
+		consulta,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + contenido + "\n" +
+			"x-amz-date:" + fecha + "\n",
+		firmados,
+		contenido,
+	}, "\n")
+	suma := sha256.Sum256([]byte(canonica))
+	alcance := dia + "/" + s.cfg.Region + "/s3/aws4_request"
+	porFirmar := "AWS4-HMAC-SHA256\n" + fecha + "\n" + alcance + "\n" + hex.EncodeToString(suma[:])
+
+	llave := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), dia)
+	llave = hmacSHA256(llave, s.cfg.Region)
+	llave = hmacSHA256(llave, "s3")
+	llave = hmacSHA256(llave, "aws4_request")
+	firma := hex.EncodeToString(hmacSHA256(llave, porFirmar))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, alcance, firmados, firma))
+}
+
+func hmacSHA256(llave []byte, texto string) []byte {
+	h := hmac.New(sha256.New, llave)
+	h.Write([]byte(texto))
+	return h.Sum(nil)
+}
+
+// consultaCanonica ordena y codifica los parámetros como lo exige la firma
+func consultaCanonica(q url.Values) string {
+	pares := make([]string, 0, len(q))
+	for k, valores := range q {
+		for _, v := range valores {
+			pares = append(pares, escaparS3(k, false)+"="+escaparS3(v, false))
+		}
+	}
+	sort.Strings(pares)
+	return strings.Join(pares, "&")
+}
+
+// escaparS3 codifica todo salvo los caracteres no reservados (RFC 3986) y,
+// en rutas, la '/'
+func escaparS3(s string, ruta bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', ruta && c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package auditoria
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"visor-pdf/internal/database"
+)
+
+// Tipos de alerta que genera el detector
+const (
+	AlertaSecuencial       = "escaneo_secuencial"
+	AlertaFueraHorario     = "fuera_horario"
+	AlertaMuchosMunicipios = "muchos_municipios"
+)
+
+// AnalizarVisualizacion busca patrones inusuales a partir de la última
+// visualización del usuario. Se ejecuta en segundo plano: los errores
+// solo se registran en el log.
+func AnalizarVisualizacion(v Visualizacion) {
+	if err := revisarHorario(v); err != nil {
+		log.Printf("⚠️  Detector (horario): %v", err)
+	}
+	if err := revisarSecuencia(v); err != nil {
+		log.Printf("⚠️  Detector (secuencia): %v", err)
+	}
+	if err := revisarMunicipios(v); err != nil {
+		log.Printf("⚠️  Detector (municipios): %v", err)
+	}
+}
+
+// revisarHorario alerta sobre accesos fuera del horario laboral configurado
+func revisarHorario(v Visualizacion) error {
+	hora := time.Now().Hour()
+	if hora >= cfg.HorarioInicio && hora < cfg.HorarioFin {
+		return nil
+	}
+	detalle := fmt.Sprintf("Acceso a las %02d:%02d, fuera del horario %02d:00-%02d:00",
+		hora, time.Now().Minute(), cfg.HorarioInicio, cfg.HorarioFin)
+	return registrarAlerta(v.UsuarioID, AlertaFueraHorario, detalle, 12)
+}
+
+// revisarSecuencia detecta recorridos consecutivos de numActa dentro del
+// mismo libro (acto, municipio, oficialía y año) en la última hora
+func revisarSecuencia(v Visualizacion) error {
+	if cfg.UmbralSecuencia < 2 {
+		return nil
+	}
+
+	// Se piden más filas que el umbral porque los visores recargan la misma acta
+	rows, err := database.DB.Query(`
+		SELECT num_acta
+		FROM bitacora_visualizaciones
+		WHERE usuario_id = ? AND acto = ? AND municipio_id = ? AND oficialia = ? AND anio = ?
+			AND creado_en >= NOW() - INTERVAL 1 HOUR
+		ORDER BY id DESC
+		LIMIT ?`,
+		v.UsuarioID, v.Acto, v.Municipio, v.Oficialia, v.Anio, cfg.UmbralSecuencia*3)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var actas []int
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return err
+		}
+		if len(actas) > 0 && actas[len(actas)-1] == n {
+			continue
+		}
+		actas = append(actas, n)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !esSecuencia(actas, cfg.UmbralSecuencia) {
+		return nil
+	}
+	detalle := fmt.Sprintf("%d actas consecutivas (%d a %d) de acto %s (%s), municipio %d, oficialía %d, año %d",
+		cfg.UmbralSecuencia, actas[cfg.UmbralSecuencia-1], actas[0], v.Acto, v.ActoNombre, v.Municipio, v.Oficialia, v.Anio)
+	return registrarAlerta(v.UsuarioID, AlertaSecuencial, detalle, 1)
+}
+
+// esSecuencia indica si los primeros n valores avanzan de uno en uno en la misma dirección
+func esSecuencia(actas []int, n int) bool {
+	if len(actas) < n {
+		return false
+	}
+	paso := actas[0] - actas[1]
+	if paso != 1 && paso != -1 {
+		return false
+	}
+	for i := 1; i < n; i++ {
+		if actas[i-1]-actas[i] != paso {
+			return false
+		}
+	}
+	return true
+}
+
+// revisarMunicipios alerta cuando un usuario consulta demasiados municipios en 24 horas
+func revisarMunicipios(v Visualizacion) error {
+	if cfg.UmbralMunicipios < 1 {
+		return nil
+	}
+	var total int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(DISTINCT municipio_id)
+		FROM bitacora_visualizaciones
+		WHERE usuario_id = ? AND creado_en >= NOW() - INTERVAL 24 HOUR`,
+		v.UsuarioID).Scan(&total)
+	if err != nil {
+		return err
+	}
+	if total < cfg.UmbralMunicipios {
+		return nil
+	}
+	detalle := fmt.Sprintf("Consultó actas de %d municipios distintos en 24 horas", total)
+	return registrarAlerta(v.UsuarioID, AlertaMuchosMunicipios, detalle, 24)
+}
+
+// registrarAlerta guarda la alerta salvo que ya exista una del mismo tipo
+// para el usuario dentro de las últimas horas indicadas
+func registrarAlerta(usuarioID int, tipo, detalle string, horas int) error {
+	var existentes int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*) FROM alertas
+		WHERE usuario_id = ? AND tipo = ? AND creado_en >= NOW() - INTERVAL ? HOUR`,
+		usuarioID, tipo, horas).Scan(&existentes)
+	if err != nil {
+		return err
+	}
+	if existentes > 0 {
+		return nil
+	}
+	_, err = database.DB.Exec(
+		"INSERT INTO alertas (usuario_id, tipo, detalle) VALUES (?, ?, ?)",
+		usuarioID, tipo, detalle)
+	return err
+}
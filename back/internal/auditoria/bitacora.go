@@ -0,0 +1,93 @@
+package auditoria
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/config"
+	"visor-pdf/internal/database"
+)
+
+var cfg config.Config
+
+// SetConfig guarda la configuración usada por el detector de alertas
+func SetConfig(c config.Config) {
+	cfg = c
+}
+
+// Visualizacion es una consulta de acta hecha por un usuario
+type Visualizacion struct {
+	UsuarioID  int
+	Acto       string
+	ActoNombre string // solo para los mensajes; la bitácora guarda el código
+	Municipio  int
+	Oficialia  int
+	Localidad  int
+	Anio       int
+	NumActa    int
+	IP         string
+}
+
+// RegistrarVisualizacion revisa la cuota del usuario (rolID es su rol) y,
+// si no la alcanzó, guarda la consulta en la bitácora y devuelve su id.
+// Revisión e inserción van en una transacción que bloquea la fila del
+// usuario, así que peticiones simultáneas no pueden pasar todas la revisión
+// antes de que se registre alguna. Devuelve *CuotaExcedida si se alcanzó.
+func RegistrarVisualizacion(v Visualizacion, rolID int) (int64, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// El bloqueo va antes de cualquier lectura: así la instantánea de la
+	// transacción se toma ya con las visualizaciones que otra petición del
+	// mismo usuario acaba de confirmar
+	var id int
+	if err := tx.QueryRow("SELECT id FROM usuarios WHERE id = ? FOR UPDATE", v.UsuarioID).Scan(&id); err != nil {
+		return 0, err
+	}
+	if err := verificarCuota(tx, v.UsuarioID, rolID); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`
+		INSERT INTO bitacora_visualizaciones
+			(usuario_id, acto, municipio_id, oficialia, localidad, anio, num_acta, ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		v.UsuarioID, v.Acto, v.Municipio, v.Oficialia, v.Localidad, v.Anio, v.NumActa, v.IP)
+	if err != nil {
+		return 0, err
+	}
+	vistaID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return vistaID, tx.Commit()
+}
+
+// ObtenerVisualizacion lee una visualización registrada y su fecha
+func ObtenerVisualizacion(id int64) (Visualizacion, time.Time, error) {
+	var v Visualizacion
+	var creada time.Time
+	var ip sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT usuario_id, acto, municipio_id, oficialia, localidad, anio, num_acta, ip, creado_en
+		FROM bitacora_visualizaciones WHERE id = ?`, id).Scan(&v.UsuarioID, &v.Acto, &v.Municipio,
+		&v.Oficialia, &v.Localidad, &v.Anio, &v.NumActa, &ip, &creada)
+	v.IP = ip.String
+	return v, creada, err
+}
+
+// CodigoVista convierte el id de la bitácora en el código corto que se
+// imprime en la marca de agua (por ejemplo 48213 -> "V-1179")
+func CodigoVista(id int64) string {
+	return "V-" + strings.ToUpper(strconv.FormatInt(id, 36))
+}
+
+// IDDesdeCodigo es la operación inversa de CodigoVista
+func IDDesdeCodigo(codigo string) (int64, error) {
+	codigo = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(codigo)), "V-")
+	return strconv.ParseInt(strings.ToLower(codigo), 36, 64)
+}
@@ -0,0 +1,66 @@
+package auditoria
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// CuotaExcedida indica que el usuario alcanzó su límite de visualizaciones
+type CuotaExcedida struct {
+	Periodo    string // "hora" o "día"
+	Maximo     int
+	Reintentar int // segundos hasta que se libere la cuota
+}
+
+func (e *CuotaExcedida) Error() string {
+	return fmt.Sprintf("cuota de visualización excedida: máximo %d por %s", e.Maximo, e.Periodo)
+}
+
+// verificarCuota revisa, dentro de la transacción de
+// RegistrarVisualizacion, si el usuario puede abrir otra acta.
+// La cuota del usuario tiene prioridad sobre la de su rol; si no hay
+// ninguna configurada no hay límite. Devuelve *CuotaExcedida si se alcanzó.
+func verificarCuota(tx *sql.Tx, usuarioID, rolID int) error {
+	var porHora, porDia sql.NullInt64
+	err := tx.QueryRow(`
+		SELECT max_por_hora, max_por_dia
+		FROM cuotas
+		WHERE usuario_id = ? OR rol_id = ?
+		ORDER BY usuario_id IS NULL
+		LIMIT 1`,
+		usuarioID, rolID).Scan(&porHora, &porDia)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !porHora.Valid && !porDia.Valid {
+		return nil
+	}
+
+	// La ventana de una hora puede empezar antes de medianoche, por eso se
+	// consulta desde el menor de los dos límites
+	var enHora, enDia, liberaHora, liberaDia int
+	err = tx.QueryRow(`
+		SELECT
+			COALESCE(SUM(creado_en >= NOW() - INTERVAL 1 HOUR), 0),
+			COALESCE(SUM(creado_en >= CURDATE()), 0),
+			COALESCE(TIMESTAMPDIFF(SECOND, NOW() - INTERVAL 1 HOUR,
+				MIN(CASE WHEN creado_en >= NOW() - INTERVAL 1 HOUR THEN creado_en END)), 0),
+			TIMESTAMPDIFF(SECOND, NOW(), CURDATE() + INTERVAL 1 DAY)
+		FROM bitacora_visualizaciones
+		WHERE usuario_id = ? AND creado_en >= LEAST(CURDATE(), NOW() - INTERVAL 1 HOUR)`,
+		usuarioID).Scan(&enHora, &enDia, &liberaHora, &liberaDia)
+	if err != nil {
+		return err
+	}
+
+	if porDia.Valid && enDia >= int(porDia.Int64) {
+		return &CuotaExcedida{Periodo: "día", Maximo: int(porDia.Int64), Reintentar: liberaDia}
+	}
+	if porHora.Valid && enHora >= int(porHora.Int64) {
+		return &CuotaExcedida{Periodo: "hora", Maximo: int(porHora.Int64), Reintentar: liberaHora + 1}
+	}
+	return nil
+}
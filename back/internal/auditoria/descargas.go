@@ -0,0 +1,81 @@
+package auditoria
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/database"
+)
+
+// Descarga es una petición del PDF original; se registra aparte de las
+// visualizaciones y no cuenta contra las cuotas
+type Descarga struct {
+	Visualizacion
+	Raiz    string
+	Ruta    string // relativa a la raíz
+	Sellado bool   // con pie de página de verificación
+	Rango   string // encabezado Range de la primera petición, si lo hubo
+	Filtros string // filtros de mejora aplicados, en forma canónica
+}
+
+// ventanaRangos es cuánto después de la última petición se siguen sumando
+// rangos a la misma descarga
+const ventanaRangos = 30 * time.Minute
+
+// RegistrarDescarga guarda la descarga en su bitácora y devuelve su id.
+// Un visor de PDF pide el original en muchos rangos: una petición de rango
+// sin sello ni filtros se suma a la descarga del mismo usuario y archivo
+// cuya última petición fue hace menos de ventanaRangos, en lugar de abrir
+// otra.
+func RegistrarDescarga(d Descarga) (int64, error) {
+	if d.Rango != "" && !d.Sellado && d.Filtros == "" {
+		var id int64
+		err := database.DB.QueryRow(`
+			SELECT id FROM bitacora_descargas
+			WHERE usuario_id = ? AND raiz = ? AND ruta = ? AND sellado = 0 AND filtros IS NULL
+				AND COALESCE(ultima_peticion, creado_en) >= NOW() - INTERVAL ? SECOND
+			ORDER BY id DESC
+			LIMIT 1`, d.UsuarioID, d.Raiz, d.Ruta, int(ventanaRangos.Seconds())).Scan(&id)
+		if err == nil {
+			_, err = database.DB.Exec(`
+				UPDATE bitacora_descargas SET peticiones = peticiones + 1, ultima_peticion = NOW()
+				WHERE id = ?`, id)
+			return id, err
+		}
+		if err != sql.ErrNoRows {
+			return 0, err
+		}
+	}
+
+	var rango, filtros interface{}
+	if d.Rango != "" {
+		rango = d.Rango
+	}
+	if d.Filtros != "" {
+		filtros = d.Filtros
+	}
+	result, err := database.DB.Exec(`
+		INSERT INTO bitacora_descargas
+			(usuario_id, acto, municipio_id, oficialia, localidad, anio, num_acta, raiz, ruta, sellado, filtros, rango, ip)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.UsuarioID, d.Acto, d.Municipio, d.Oficialia, d.Localidad, d.Anio, d.NumActa,
+		d.Raiz, d.Ruta, d.Sellado, filtros, rango, d.IP)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// CodigoDescarga es el código que se imprime en el pie de las descargas
+// selladas (por ejemplo 48213 -> "D-1179")
+func CodigoDescarga(id int64) string {
+	return "D-" + strings.ToUpper(strconv.FormatInt(id, 36))
+}
+
+// IDDesdeCodigoDescarga es la operación inversa de CodigoDescarga
+func IDDesdeCodigoDescarga(codigo string) (int64, error) {
+	codigo = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(codigo)), "D-")
+	return strconv.ParseInt(strings.ToLower(codigo), 36, 64)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
 	"visor-pdf/internal/models"
 
 	"golang.org/x/crypto/bcrypt"
@@ -40,12 +41,18 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Obtener municipios permitidos (sin filtro por fecha)
+	// Registrar el inicio de sesión (no bloquea el login si falla)
+	if _, err := database.DB.Exec("UPDATE usuarios SET ultimo_login = NOW() WHERE id = ?", user.ID); err != nil {
+		fmt.Println("⚠️  No se pudo registrar el último login:", err)
+	}
+
+	// Obtener municipios permitidos (directos, por distrito, región o estado)
 	rows, err := database.DB.Query(`
-		SELECT DISTINCT um.municipio_id, m.nombre
-		FROM usuario_municipios um
+		SELECT DISTINCT um.municipio_id, m.estado_id, e.abreviatura, m.clave, m.nombre, m.activo
+		FROM v_usuario_municipios um
 		JOIN municipios m ON um.municipio_id = m.idmunicipios
-		WHERE um.usuario_id = ?`,
+		JOIN estados e ON m.estado_id = e.id
+		WHERE um.usuario_id = ? AND `+estados.CondicionSQL("m.estado_id"),
 		user.ID)
 
 	if err != nil {
@@ -57,7 +64,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	var municipiosPermitidos []models.Municipio
 	for rows.Next() {
 		var m models.Municipio
-		rows.Scan(&m.ID, &m.Nombre)
+		rows.Scan(&m.ID, &m.EstadoID, &m.Estado, &m.Clave, &m.Nombre, &m.Activo)
 		municipiosPermitidos = append(municipiosPermitidos, m)
 	}
 
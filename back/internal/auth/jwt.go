@@ -27,6 +27,11 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// EsAdmin indica si el usuario del token es administrador (rol_id = 1 o rol_name = "admin")
+func (c *Claims) EsAdmin() bool {
+	return c.RolID == 1 || c.RolName == "admin"
+}
+
 // GenerateJWT genera un token JWT para un usuario
 func GenerateJWT(userID int, username string, rolID int, rolName string) (string, error) {
 	// Crear claims con información del usuario
@@ -59,8 +59,8 @@ func AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// Verificar que sea administrador (rol_id = 1 o rol_name = "admin")
-		if claims.RolID != 1 && claims.RolName != "admin" {
+		// Verificar que sea administrador
+		if !claims.EsAdmin() {
 			http.Error(w, "Acceso denegado - Solo administradores", http.StatusForbidden)
 			return
 		}
@@ -70,3 +70,9 @@ func AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// GetClaims obtiene los claims que el middleware dejó en el contexto
+func GetClaims(r *http.Request) *Claims {
+	claims, _ := r.Context().Value("claims").(*Claims)
+	return claims
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"visor-pdf/internal/database"
+)
+
+// Permisos adicionales al rol, otorgados por rol o por usuario en la tabla permisos
+const (
+	// PermisoDescargaOriginal permite bajar el PDF original (/api/pdf/download)
+	PermisoDescargaOriginal = "descarga_original"
+	// PermisoEmitirCopias permite emitir copias certificadas (/api/copias/emitir)
+	PermisoEmitirCopias = "emitir_copias"
+	// PermisoAnotacionesMarginales permite capturar y corregir el registro de
+	// anotaciones marginales (/api/marginales/crear, /editar y /eliminar)
+	PermisoAnotacionesMarginales = "anotaciones_marginales"
+	// PermisoSupervisarCalidad permite ver todos los reportes de calidad de
+	// digitalización, asignarlos y cambiar su estado (/api/calidad/triage)
+	PermisoSupervisarCalidad = "supervisar_calidad"
+)
+
+// Permisos es la lista de permisos que se pueden otorgar
+var Permisos = []string{PermisoDescargaOriginal, PermisoEmitirCopias, PermisoAnotacionesMarginales,
+	PermisoSupervisarCalidad}
+
+// PermisoValido indica si el nombre corresponde a un permiso conocido
+func PermisoValido(permiso string) bool {
+	for _, p := range Permisos {
+		if p == permiso {
+			return true
+		}
+	}
+	return false
+}
+
+// TienePermiso revisa en la BD si el usuario o su rol tienen el permiso.
+// Se consulta en cada petición para que quitar un permiso surta efecto
+// sin esperar a que venza el token.
+func TienePermiso(c *Claims, permiso string) (bool, error) {
+	var existe bool
+	err := database.DB.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM permisos
+			WHERE permiso = ? AND (usuario_id = ? OR rol_id = ?)
+		)`, permiso, c.UserID, c.RolID).Scan(&existe)
+	return existe, err
+}
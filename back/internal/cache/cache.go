@@ -0,0 +1,105 @@
+// Package cache guarda en memoria resultados costosos de generar
+// (metadatos, miniaturas y tiles de los PDFs) con un límite de bytes.
+// Al llenarse descarta los menos usados recientemente.
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache es un LRU de []byte acotado por el tamaño total de los valores.
+// Es seguro para uso concurrente.
+type Cache struct {
+	mu      sync.Mutex
+	maximo  int64
+	tamano  int64
+	orden   *list.List // el más reciente al frente
+	entrada map[string]*list.Element
+	enCurso map[string]*generacion
+}
+
+// generacion es un valor que se está generando; las demás peticiones de la
+// misma clave esperan a que se cierre listo
+type generacion struct {
+	listo chan struct{}
+	datos []byte
+	err   error
+}
+
+type elemento struct {
+	clave string
+	datos []byte
+}
+
+// Nueva crea una caché de hasta maximo bytes; con maximo <= 0 no guarda nada
+func Nueva(maximo int64) *Cache {
+	return &Cache{maximo: maximo, orden: list.New(), entrada: map[string]*list.Element{},
+		enCurso: map[string]*generacion{}}
+}
+
+// Obtener devuelve el valor guardado con la clave. El slice no debe
+// modificarse: es el mismo que reciben los demás lectores.
+func (c *Cache) Obtener(clave string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entrada[clave]
+	if !ok {
+		return nil, false
+	}
+	c.orden.MoveToFront(e)
+	return e.Value.(*elemento).datos, true
+}
+
+// Guardar agrega o reemplaza el valor de la clave. Un valor mayor que toda
+// la caché no se guarda.
+func (c *Cache) Guardar(clave string, datos []byte) {
+	tamano := int64(len(datos))
+	if tamano > c.maximo {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entrada[clave]; ok {
+		c.quitar(e)
+	}
+	c.entrada[clave] = c.orden.PushFront(&elemento{clave: clave, datos: datos})
+	c.tamano += tamano
+	for c.tamano > c.maximo {
+		c.quitar(c.orden.Back())
+	}
+}
+
+// Generar devuelve el valor de la clave y, si no está, lo genera con fn y
+// lo guarda. Las peticiones simultáneas de la misma clave esperan a una
+// sola generación; los errores no se guardan.
+func (c *Cache) Generar(clave string, fn func() ([]byte, error)) ([]byte, error) {
+	if datos, ok := c.Obtener(clave); ok {
+		return datos, nil
+	}
+	c.mu.Lock()
+	if g, ok := c.enCurso[clave]; ok {
+		c.mu.Unlock()
+		<-g.listo
+		return g.datos, g.err
+	}
+	g := &generacion{listo: make(chan struct{})}
+	c.enCurso[clave] = g
+	c.mu.Unlock()
+
+	g.datos, g.err = fn()
+	if g.err == nil {
+		c.Guardar(clave, g.datos)
+	}
+	c.mu.Lock()
+	delete(c.enCurso, clave)
+	c.mu.Unlock()
+	close(g.listo)
+	return g.datos, g.err
+}
+
+func (c *Cache) quitar(e *list.Element) {
+	el := c.orden.Remove(e).(*elemento)
+	delete(c.entrada, el.clave)
+	c.tamano -= int64(len(el.datos))
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheLRU(t *testing.T) {
+	c := Nueva(10)
+	c.Guardar("a", []byte("1234"))
+	c.Guardar("b", []byte("1234"))
+	// Leer a la vuelve la más reciente: al llenarse sale b
+	if _, ok := c.Obtener("a"); !ok {
+		t.Fatal("a debió estar en caché")
+	}
+	c.Guardar("c", []byte("1234"))
+
+	casos := []struct {
+		clave string
+		esta  bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, caso := range casos {
+		if _, ok := c.Obtener(caso.clave); ok != caso.esta {
+			t.Errorf("Obtener(%q) = %v, se esperaba %v", caso.clave, ok, caso.esta)
+		}
+	}
+	if c.tamano != 8 {
+		t.Errorf("tamaño = %d, se esperaba 8", c.tamano)
+	}
+}
+
+func TestCacheReemplazarYLimites(t *testing.T) {
+	c := Nueva(10)
+	c.Guardar("a", []byte("12345678"))
+	c.Guardar("a", []byte("12"))
+	if datos, _ := c.Obtener("a"); string(datos) != "12" || c.tamano != 2 {
+		t.Fatalf("tras reemplazar: %q, tamaño %d", datos, c.tamano)
+	}
+
+	// Un valor mayor que toda la caché no se guarda ni desplaza a los demás
+	c.Guardar("grande", make([]byte, 11))
+	if _, ok := c.Obtener("grande"); ok {
+		t.Error("un valor mayor que la caché no debió guardarse")
+	}
+	if _, ok := c.Obtener("a"); !ok {
+		t.Error("un valor que no cabe no debió desplazar a los demás")
+	}
+
+	sinMemoria := Nueva(0)
+	sinMemoria.Guardar("a", []byte("1"))
+	if _, ok := sinMemoria.Obtener("a"); ok {
+		t.Error("una caché de 0 bytes no debe guardar nada")
+	}
+}
+
+func TestCacheGenerarUnaVez(t *testing.T) {
+	c := Nueva(100)
+	var llamadas int32
+	empezo, listo := make(chan struct{}), make(chan struct{})
+	fn := func() ([]byte, error) {
+		if atomic.AddInt32(&llamadas, 1) == 1 {
+			close(empezo)
+		}
+		<-listo
+		return []byte("tile"), nil
+	}
+
+	var wg sync.WaitGroup
+	resultados := make([]string, 8)
+	pedir := func(i int) {
+		defer wg.Done()
+		datos, err := c.Generar("clave", fn)
+		if err != nil {
+			t.Error(err)
+		}
+		resultados[i] = string(datos)
+	}
+	// La primera petición empieza a generar; las demás llegan mientras tanto
+	wg.Add(len(resultados))
+	go pedir(0)
+	<-empezo
+	for i := 1; i < len(resultados); i++ {
+		go pedir(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(listo)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&llamadas); n != 1 {
+		t.Errorf("fn se llamó %d veces, se esperaba 1", n)
+	}
+	for i, r := range resultados {
+		if r != "tile" {
+			t.Errorf("resultado %d = %q", i, r)
+		}
+	}
+	if datos, ok := c.Obtener("clave"); !ok || string(datos) != "tile" {
+		t.Errorf("Obtener tras Generar = %q, %v", datos, ok)
+	}
+}
+
+func TestCacheGenerarNoGuardaErrores(t *testing.T) {
+	c := Nueva(100)
+	fallo := errors.New("microservicio caído")
+	if _, err := c.Generar("clave", func() ([]byte, error) { return nil, fallo }); err != fallo {
+		t.Fatalf("error = %v, se esperaba %v", err, fallo)
+	}
+	datos, err := c.Generar("clave", func() ([]byte, error) { return []byte("ok"), nil })
+	if err != nil || string(datos) != "ok" {
+		t.Fatalf("tras un error se debió generar de nuevo: %q, %v", datos, err)
+	}
+}
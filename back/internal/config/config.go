@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +17,57 @@ type Config struct {
 	DBHost      string `json:"dbHost"`
 	DBPort      string `json:"dbPort"`
 	DBName      string `json:"dbName"`
+
+	// Plantilla de ruta de la raíz principal (vacío = convención original)
+	PDFPlantilla string `json:"pdfPlantilla"`
+	// Raíces adicionales del archivo, p. ej. lotes de otros proveedores
+	RaicesPDF []RaizPDF `json:"raicesPDF"`
+
+	// Servicio compatible con S3 para las raíces con ruta "s3://bucket/prefijo"
+	S3Endpoint  string `json:"s3Endpoint"`
+	S3Region    string `json:"s3Region"`
+	S3AccessKey string `json:"s3AccessKey"`
+	S3SecretKey string `json:"s3SecretKey"`
+
+	// Claves INEGI de los estados que atiende este despliegue (vacío = 20)
+	Estados []int `json:"estados"`
+
+	// Detector de accesos inusuales (horario laboral en horas 0-23)
+	HorarioInicio    int `json:"horarioInicio"`
+	HorarioFin       int `json:"horarioFin"`
+	UmbralSecuencia  int `json:"umbralSecuencia"`
+	UmbralMunicipios int `json:"umbralMunicipios"`
+
+	// Memoria para la caché de páginas, miniaturas y tiles de los PDFs (MB)
+	CacheMB int `json:"cacheMB"`
+	// Zoom del nivel más alto de las pirámides Deep Zoom (píxeles por punto)
+	DZIZoom int `json:"dziZoom"`
+
+	// Horas entre verificaciones de integridad de los PDFs (0 = desactivado)
+	IntervaloVerificacion int `json:"intervaloVerificacion"`
+
+	// Copias certificadas: dirección pública para el QR de verificación y
+	// plantillas de encabezado y pie (vacío = texto predeterminado)
+	URLPublica      string `json:"urlPublica"`
+	CopiaEncabezado string `json:"copiaEncabezado"`
+	CopiaPie        string `json:"copiaPie"`
+
+	// Clave HMAC de los códigos de verificación de las copias y máximo de
+	// consultas públicas por minuto e IP a /verificar
+	ClaveVerificacion  string `json:"claveVerificacion"`
+	LimiteVerificacion int    `json:"limiteVerificacion"`
+}
+
+// RaizPDF es un directorio del archivo con su propia plantilla de rutas.
+// Las raíces se prueban de menor a mayor prioridad; la principal tiene 0.
+// Estado limita la raíz a un estado cuando su plantilla no incluye
+// {estado} (0 = cualquiera).
+type RaizPDF struct {
+	Nombre    string `json:"nombre"`
+	Ruta      string `json:"ruta"`
+	Plantilla string `json:"plantilla"`
+	Prioridad int    `json:"prioridad"`
+	Estado    int    `json:"estado"`
 }
 
 func LoadConfig() (Config, error) {
@@ -31,6 +84,33 @@ func LoadConfig() (Config, error) {
 		DBHost:      getEnv("DB_HOST", "localhost"),
 		DBPort:      getEnv("DB_PORT", "3306"),
 		DBName:      getEnv("DB_NAME", "digitalizacion"),
+
+		PDFPlantilla: getEnv("PDF_PLANTILLA", ""),
+		RaicesPDF:    getEnvRaices("PDF_RAICES"),
+
+		S3Endpoint:  getEnv("S3_ENDPOINT", ""),
+		S3Region:    getEnv("S3_REGION", "us-east-1"),
+		S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+
+		Estados: getEnvInts("ESTADOS"),
+
+		HorarioInicio:    getEnvInt("HORARIO_INICIO", 8),
+		HorarioFin:       getEnvInt("HORARIO_FIN", 20),
+		UmbralSecuencia:  getEnvInt("UMBRAL_SECUENCIA", 10),
+		UmbralMunicipios: getEnvInt("UMBRAL_MUNICIPIOS", 15),
+
+		CacheMB: getEnvInt("CACHE_MB", 128),
+		DZIZoom: getEnvInt("DZI_ZOOM", 4),
+
+		IntervaloVerificacion: getEnvInt("INTERVALO_VERIFICACION", 24),
+
+		URLPublica:      getEnv("URL_PUBLICA", "http://localhost:8080"),
+		CopiaEncabezado: getEnv("COPIA_ENCABEZADO", ""),
+		CopiaPie:        getEnv("COPIA_PIE", ""),
+
+		ClaveVerificacion:  getEnv("CLAVE_VERIFICACION", ""),
+		LimiteVerificacion: getEnvInt("LIMITE_VERIFICACION", 30),
 	}
 
 	// Si no hay variables de entorno, intentar cargar desde config.json
@@ -79,3 +159,50 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvInt obtiene una variable de entorno numérica o retorna un valor por defecto
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInts lee una lista de enteros separados por comas
+func getEnvInts(key string) []int {
+	var valores []int
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			valores = append(valores, n)
+		}
+	}
+	return valores
+}
+
+// getEnvRaices lee raíces adicionales con el formato
+// "nombre|ruta|plantilla|prioridad|estado;..." (todo opcional salvo nombre y ruta)
+func getEnvRaices(key string) []RaizPDF {
+	var raices []RaizPDF
+	for _, entrada := range strings.Split(os.Getenv(key), ";") {
+		if strings.TrimSpace(entrada) == "" {
+			continue
+		}
+		partes := strings.Split(entrada, "|")
+		r := RaizPDF{Nombre: strings.TrimSpace(partes[0])}
+		if len(partes) > 1 {
+			r.Ruta = strings.TrimSpace(partes[1])
+		}
+		if len(partes) > 2 {
+			r.Plantilla = strings.TrimSpace(partes[2])
+		}
+		if len(partes) > 3 {
+			r.Prioridad, _ = strconv.Atoi(strings.TrimSpace(partes[3]))
+		}
+		if len(partes) > 4 {
+			r.Estado, _ = strconv.Atoi(strings.TrimSpace(partes[4]))
+		}
+		raices = append(raices, r)
+	}
+	return raices
+}
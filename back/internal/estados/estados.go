@@ -0,0 +1,109 @@
+// Package estados guarda qué estados atiende este despliegue del visor.
+//
+// Los municipios conservan un id interno único (idmunicipios) y además la
+// clave INEGI del estado y del municipio, que son las que forman el nombre
+// del PDF. Los municipios de Oaxaca (estado 20), anteriores a que el visor
+// atendiera varios estados, tienen su clave como id.
+package estados
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"visor-pdf/internal/config"
+	"visor-pdf/internal/database"
+)
+
+// Predeterminado es el estado de los datos anteriores a la migración 016
+const Predeterminado = 20
+
+var (
+	mu       sync.RWMutex
+	servidos = []int{Predeterminado}
+)
+
+// Configurar toma los estados de la configuración (Predeterminado si no
+// hay ninguno)
+func Configurar(c config.Config) error {
+	lista := c.Estados
+	if len(lista) == 0 {
+		lista = []int{Predeterminado}
+	}
+	vistos := map[int]bool{}
+	for _, e := range lista {
+		if e < 1 || e > 32 {
+			return fmt.Errorf("clave de estado inválida: %d (debe ser 1-32)", e)
+		}
+		if vistos[e] {
+			return fmt.Errorf("estado %d repetido", e)
+		}
+		vistos[e] = true
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	servidos = append([]int(nil), lista...)
+	return nil
+}
+
+// Servidos devuelve las claves de los estados que atiende el despliegue
+func Servidos() []int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]int(nil), servidos...)
+}
+
+// Sirve indica si el despliegue atiende el estado
+func Sirve(estado int) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, e := range servidos {
+		if e == estado {
+			return true
+		}
+	}
+	return false
+}
+
+// CondicionSQL devuelve "<columna> IN (...)" con los estados servidos. Las
+// claves son enteros validados al configurar, así que van en el texto.
+func CondicionSQL(columna string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	claves := make([]string, len(servidos))
+	for i, e := range servidos {
+		claves[i] = strconv.Itoa(e)
+	}
+	return columna + " IN (" + strings.Join(claves, ", ") + ")"
+}
+
+// IDMunicipio es el id interno que recibe un municipio nuevo: su clave en el
+// estado Predeterminado y estado*1000+clave en los demás
+func IDMunicipio(estado, clave int) int {
+	if estado == Predeterminado {
+		return clave
+	}
+	return estado*1000 + clave
+}
+
+// Municipios devuelve el id interno de cada municipio por estado y clave
+// INEGI
+func Municipios() (map[[2]int]int, error) {
+	rows, err := database.DB.Query("SELECT estado_id, clave, idmunicipios FROM municipios")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	municipios := map[[2]int]int{}
+	for rows.Next() {
+		var estado, clave, id int
+		if err := rows.Scan(&estado, &clave, &id); err != nil {
+			return nil, err
+		}
+		municipios[[2]int{estado, clave}] = id
+	}
+	return municipios, rows.Err()
+}
@@ -0,0 +1,297 @@
+// Package fonetica compara nombres propios escritos de forma inconsistente
+// en actas históricas (Xóchitl/Sochitl, Hernández/Fernández, acentos
+// omitidos, "de la", etc.).
+//
+// Normalizar limpia el texto, Clave produce una clave fonética en español
+// para encontrar candidatos con un índice de la BD, y Similitud y Relevancia
+// ordenan esos candidatos combinando trigramas y distancia de edición.
+package fonetica
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Partículas que no distinguen un nombre de otro
+var particulas = map[string]bool{
+	"de": true, "del": true, "la": true, "las": true, "los": true,
+	"y": true, "e": true, "da": true, "dos": true, "van": true, "von": true,
+}
+
+// Letras con diacrítico y su equivalente sin él
+var sinAcento = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ä': 'a', 'ã': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'ö': 'o', 'õ': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ñ': 'n', 'ç': 'c', 'ý': 'y', 'ÿ': 'y',
+}
+
+// Normalizar pasa a minúsculas, quita acentos y signos, y elimina las
+// partículas ("María de la Luz" -> "maria luz")
+func Normalizar(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if sa, ok := sinAcento[r]; ok {
+			r = sa
+		}
+		switch {
+		case r >= 'a' && r <= 'z':
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '.' || r == ',':
+			b.WriteRune(' ')
+		}
+	}
+
+	palabras := strings.Fields(b.String())
+	resultado := palabras[:0]
+	for _, p := range palabras {
+		if !particulas[p] {
+			resultado = append(resultado, p)
+		}
+	}
+	// Si todo eran partículas (p. ej. el apellido "De la"), conservarlas
+	if len(resultado) == 0 {
+		return strings.Join(palabras, " ")
+	}
+	return strings.Join(resultado, " ")
+}
+
+// Clave devuelve la clave fonética de un nombre: palabras normalizadas y
+// transformadas para que variantes de escritura con el mismo sonido (o que
+// históricamente se confundían) den la misma clave.
+func Clave(s string) string {
+	palabras := strings.Fields(Normalizar(s))
+	for i, p := range palabras {
+		palabras[i] = clavePalabra(p)
+	}
+	return strings.Join(palabras, " ")
+}
+
+// clavePalabra aplica las reglas fonéticas a una palabra ya normalizada
+func clavePalabra(p string) string {
+	l := []rune(p)
+	var b strings.Builder
+	vocal := func(i int) bool {
+		return i >= 0 && i < len(l) && strings.ContainsRune("aeiou", l[i])
+	}
+	anteEI := func(i int) bool {
+		return i < len(l) && (l[i] == 'e' || l[i] == 'i')
+	}
+
+	for i := 0; i < len(l); i++ {
+		c := l[i]
+		switch c {
+		case 'h':
+			// h muda; "ch" y "ph" ya se resolvieron con la letra anterior
+			continue
+		case 'f':
+			// F- inicial ante vocal alterna con h- en documentos antiguos (Fernández/Hernández)
+			if i == 0 && vocal(1) {
+				continue
+			}
+			b.WriteRune('f')
+		case 'p':
+			if i+1 < len(l) && l[i+1] == 'h' {
+				// Ph- inicial sigue la misma regla que F- (Phelipe/Felipe)
+				if i > 0 || !vocal(2) {
+					b.WriteRune('f')
+				}
+				i++
+				continue
+			}
+			b.WriteRune('p')
+		case 'c':
+			switch {
+			case i+1 < len(l) && l[i+1] == 'h':
+				b.WriteRune('x') // ch
+			case anteEI(i + 1):
+				b.WriteRune('s')
+			default:
+				b.WriteRune('k')
+			}
+		case 'q':
+			b.WriteRune('k')
+			if i+1 < len(l) && l[i+1] == 'u' && anteEI(i+2) {
+				i++ // qu+e/i: la u no suena
+			}
+		case 'k':
+			b.WriteRune('k')
+		case 'z', 's':
+			b.WriteRune('s')
+		case 'x':
+			// X- inicial ante o/i es casi siempre náhuatl y suena como s
+			// (Xóchitl/Sochitl); en los demás casos es la j antigua
+			// (Xuárez/Juárez, México/Méjico)
+			if i == 0 && i+1 < len(l) && (l[i+1] == 'o' || l[i+1] == 'i') {
+				b.WriteRune('s')
+			} else {
+				b.WriteRune('j')
+			}
+		case 'g':
+			switch {
+			case anteEI(i + 1):
+				b.WriteRune('j')
+			case i+1 < len(l) && l[i+1] == 'u' && anteEI(i+2):
+				b.WriteRune('g')
+				i++ // gu+e/i: la u no suena
+			default:
+				b.WriteRune('g')
+			}
+		case 'j':
+			b.WriteRune('j')
+		case 'v', 'w':
+			b.WriteRune('b')
+		case 'l':
+			if i+1 < len(l) && l[i+1] == 'l' {
+				b.WriteRune('y')
+				i++
+				continue
+			}
+			b.WriteRune('l')
+		case 'y':
+			// y final o entre consonantes es vocal
+			if i == len(l)-1 || !vocal(i+1) {
+				b.WriteRune('i')
+			} else {
+				b.WriteRune('y')
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return colapsarDobles(b.String())
+}
+
+// colapsarDobles quita letras repetidas consecutivas (rr -> r, ss -> s)
+func colapsarDobles(s string) string {
+	var b strings.Builder
+	var anterior rune
+	for _, r := range s {
+		if r != anterior {
+			b.WriteRune(r)
+		}
+		anterior = r
+	}
+	return b.String()
+}
+
+// Similitud devuelve un valor entre 0 y 1: 1 si los textos normalizados son
+// iguales. Combina trigramas (tolerante a palabras en otro orden) con la
+// distancia de edición (tolerante a errores de una o dos letras).
+func Similitud(a, b string) float64 {
+	na, nb := Normalizar(a), Normalizar(b)
+	if na == nb {
+		return 1
+	}
+	if na == "" || nb == "" {
+		return 0
+	}
+	trigramas := Trigramas(na, nb)
+	largo := len([]rune(na))
+	if lb := len([]rune(nb)); lb > largo {
+		largo = lb
+	}
+	edicion := 1 - float64(Distancia(na, nb))/float64(largo)
+
+	s := 0.5*trigramas + 0.5*edicion
+	// Si suenan igual, al menos cuentan como parecidos
+	if Clave(a) == Clave(b) && s < 0.8 {
+		s = 0.8
+	}
+	return s
+}
+
+// relevanciaPrefijo es la puntuación de una palabra buscada que es el
+// inicio de una palabra registrada ("Mar" en "Mariana"): cuenta como
+// coincidencia, pero por debajo de la palabra completa.
+const relevanciaPrefijo = 0.9
+
+// Relevancia mide qué tan bien el texto buscado coincide con un nombre
+// registrado. A diferencia de Similitud no castiga las palabras de más del
+// registro, así que "Maria" encuentra "María Guadalupe" con relevancia 1,
+// igual que la búsqueda por prefijo en la BD. Cada palabra buscada se
+// compara con la palabra registrada que mejor le corresponde.
+func Relevancia(buscado, registrado string) float64 {
+	s := Similitud(buscado, registrado)
+	pb := strings.Fields(Normalizar(buscado))
+	pr := strings.Fields(Normalizar(registrado))
+	if len(pb) == 0 || len(pr) == 0 {
+		return s
+	}
+	suma := 0.0
+	for _, b := range pb {
+		mejor := 0.0
+		for _, r := range pr {
+			if v := relevanciaPalabra(b, r); v > mejor {
+				mejor = v
+			}
+		}
+		suma += mejor
+	}
+	if porPalabra := suma / float64(len(pb)); porPalabra > s {
+		s = porPalabra
+	}
+	return s
+}
+
+// relevanciaPalabra compara dos palabras ya normalizadas
+func relevanciaPalabra(buscada, registrada string) float64 {
+	kb, kr := clavePalabra(buscada), clavePalabra(registrada)
+	switch {
+	case buscada == registrada || kb == kr:
+		return 1
+	case strings.HasPrefix(registrada, buscada) || strings.HasPrefix(kr, kb):
+		return relevanciaPrefijo
+	}
+	return Similitud(buscada, registrada)
+}
+
+// Trigramas calcula el coeficiente de Jaccard entre los trigramas de dos textos
+func Trigramas(a, b string) float64 {
+	ta, tb := trigramas(a), trigramas(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	comunes := 0
+	for t := range ta {
+		if tb[t] {
+			comunes++
+		}
+	}
+	return float64(comunes) / float64(len(ta)+len(tb)-comunes)
+}
+
+func trigramas(s string) map[string]bool {
+	resultado := map[string]bool{}
+	for _, palabra := range strings.Fields(s) {
+		r := []rune("  " + palabra + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			resultado[string(r[i:i+3])] = true
+		}
+	}
+	return resultado
+}
+
+// Distancia calcula la distancia de Levenshtein entre dos textos
+func Distancia(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	anterior := make([]int, len(rb)+1)
+	actual := make([]int, len(rb)+1)
+	for j := range anterior {
+		anterior[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		actual[0] = i
+		for j := 1; j <= len(rb); j++ {
+			costo := 1
+			if ra[i-1] == rb[j-1] {
+				costo = 0
+			}
+			actual[j] = min(anterior[j]+1, actual[j-1]+1, anterior[j-1]+costo)
+		}
+		anterior, actual = actual, anterior
+	}
+	return anterior[len(rb)]
+}
@@ -0,0 +1,119 @@
+package fonetica
+
+import "testing"
+
+func TestNormalizar(t *testing.T) {
+	casos := []struct{ entrada, esperado string }{
+		{"María de la Luz", "maria luz"},
+		{"  PÉREZ-Núñez ", "perez nunez"},
+		{"Ma. Guadalupe", "ma guadalupe"},
+		{"De la", "de la"},
+		{"O'Higgins", "ohiggins"},
+		{"", ""},
+	}
+	for _, c := range casos {
+		if got := Normalizar(c.entrada); got != c.esperado {
+			t.Errorf("Normalizar(%q) = %q, se esperaba %q", c.entrada, got, c.esperado)
+		}
+	}
+}
+
+func TestClaveVariantes(t *testing.T) {
+	// Pares que deben dar la misma clave
+	iguales := [][2]string{
+		{"Xóchitl", "Sochitl"},
+		{"Hernández", "Fernández"},
+		{"Xuárez", "Juárez"},
+		{"Phelipe", "Felipe"},
+		{"Gerónimo", "Jerónimo"},
+		{"Vázquez", "Basquez"},
+		{"Cecilia", "Sesilia"},
+		{"Guillermo", "Guiyermo"},
+		{"Eloy", "Eloi"},
+		{"Hortensia", "Ortensia"},
+		{"Ramírez", "Rramires"},
+		{"María de la Luz", "Maria Luz"},
+	}
+	for _, p := range iguales {
+		if a, b := Clave(p[0]), Clave(p[1]); a != b {
+			t.Errorf("Clave(%q) = %q, Clave(%q) = %q; se esperaban iguales", p[0], a, p[1], b)
+		}
+	}
+
+	distintos := [][2]string{
+		{"Juan", "Juana"},
+		{"López", "Lozano"},
+		{"Casa", "Gasa"},
+	}
+	for _, p := range distintos {
+		if a, b := Clave(p[0]), Clave(p[1]); a == b {
+			t.Errorf("Clave(%q) y Clave(%q) son iguales (%q)", p[0], p[1], a)
+		}
+	}
+}
+
+func TestSimilitud(t *testing.T) {
+	casos := []struct {
+		a, b     string
+		min, max float64
+	}{
+		{"María", "maria", 1, 1},
+		{"Hernández", "Fernández", 0.8, 1},
+		{"Gonzalez", "Gonzales", 0.8, 1},
+		{"Martínez", "Martines", 0.8, 1},
+		{"Pérez", "Ramírez", 0, 0.5},
+		{"Juan", "", 0, 0},
+	}
+	for _, c := range casos {
+		s := Similitud(c.a, c.b)
+		if s < c.min || s > c.max {
+			t.Errorf("Similitud(%q, %q) = %.2f, se esperaba entre %.2f y %.2f", c.a, c.b, s, c.min, c.max)
+		}
+		if s2 := Similitud(c.b, c.a); s2 != s {
+			t.Errorf("Similitud no es simétrica para %q y %q: %.2f y %.2f", c.a, c.b, s, s2)
+		}
+	}
+}
+
+func TestRelevancia(t *testing.T) {
+	casos := []struct {
+		buscado, registrado string
+		min, max            float64
+	}{
+		// Palabra completa dentro de un nombre compuesto
+		{"Maria", "María Guadalupe", 1, 1},
+		{"Juan", "Juan Carlos", 1, 1},
+		{"Lopez", "López Ramírez", 1, 1},
+		{"Hernandes", "Hernández López", 1, 1},
+		// Inicio de palabra: coincidencia, por debajo de la palabra completa
+		{"Guadal", "María Guadalupe", relevanciaPrefijo, relevanciaPrefijo},
+		{"Mar", "Mariana", relevanciaPrefijo, relevanciaPrefijo},
+		// Todas las palabras buscadas cuentan
+		{"Juan Pedro", "Juan Carlos", 0.5, 0.8},
+		{"Pérez", "Ramírez", 0, 0.5},
+	}
+	for _, c := range casos {
+		if s := Relevancia(c.buscado, c.registrado); s < c.min || s > c.max {
+			t.Errorf("Relevancia(%q, %q) = %.2f, se esperaba entre %.2f y %.2f",
+				c.buscado, c.registrado, s, c.min, c.max)
+		}
+	}
+}
+
+func TestDistancia(t *testing.T) {
+	casos := []struct {
+		a, b     string
+		esperado int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"gonzalez", "gonzales", 1},
+		{"kitten", "sitting", 3},
+		{"ñandu", "nandu", 1},
+	}
+	for _, c := range casos {
+		if d := Distancia(c.a, c.b); d != c.esperado {
+			t.Errorf("Distancia(%q, %q) = %d, se esperaba %d", c.a, c.b, d, c.esperado)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
+	"visor-pdf/internal/fonetica"
+	"visor-pdf/internal/models"
+)
+
+// Campos de nombre que acepta la búsqueda y sus columnas en acta_personas
+var camposPersona = []struct {
+	parametro, columna, columnaClave string
+}{
+	{"nombre", "p.nombre", "p.clave_nombre"},
+	{"primer_apellido", "p.primer_apellido", "p.clave_primer_apellido"},
+	{"segundo_apellido", "p.segundo_apellido", "p.clave_segundo_apellido"},
+}
+
+// BuscarPersona busca actas por el nombre de cualquier persona que aparezca
+// en ellas (registrado, padres o cónyuges).
+//
+// Parámetros: nombre, primer_apellido, segundo_apellido (al menos uno), y
+// opcionalmente rol, acto, municipio, anio_desde, anio_hasta, limite
+// (máx. 200) y min_relevancia (0-1, por defecto 0.5). Los candidatos se
+// buscan por prefijo del texto o de la clave fonética y se ordenan por
+// relevancia (fonetica.Relevancia), en la que una palabra completa o su
+// inicio cuentan como coincidencia. Los usuarios que no son administradores
+// solo ven actas de los municipios que tienen asignados.
+func BuscarPersona(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	claims := auth.GetClaims(r)
+
+	var condiciones []string
+	var args []interface{}
+
+	// Texto buscado por campo, para calcular la relevancia
+	buscados := make([]string, len(camposPersona))
+	hayNombre := false
+	// Puntos por coincidencia de la clave fonética, para ordenar los candidatos
+	var orden []string
+	var argsOrden []interface{}
+	for i, c := range camposPersona {
+		v := strings.TrimSpace(query.Get(c.parametro))
+		if v == "" {
+			continue
+		}
+		buscados[i] = v
+		hayNombre = true
+		clave := fonetica.Clave(v)
+		condiciones = append(condiciones, "("+c.columna+" LIKE ? OR "+c.columnaClave+" LIKE ?)")
+		args = append(args, v+"%", clave+"%")
+		// 2 si la clave es idéntica, 1 si coincide como palabra completa
+		orden = append(orden, "(CASE WHEN "+c.columnaClave+" = ? THEN 2 WHEN "+c.columnaClave+" LIKE ? THEN 1 ELSE 0 END)")
+		argsOrden = append(argsOrden, clave, clave+" %")
+	}
+	if !hayNombre {
+		http.Error(w, "Indica al menos nombre, primer_apellido o segundo_apellido", http.StatusBadRequest)
+		return
+	}
+
+	if v := query.Get("rol"); v != "" {
+		condiciones = append(condiciones, "p.rol = ?")
+		args = append(args, v)
+	}
+	if v := query.Get("acto"); v != "" {
+		condiciones = append(condiciones, "a.acto = ?")
+		args = append(args, v)
+	}
+	filtrosNumericos := []struct {
+		parametro, condicion string
+	}{
+		{"municipio", "a.municipio_id = ?"},
+		{"anio_desde", "a.anio >= ?"},
+		{"anio_hasta", "a.anio <= ?"},
+	}
+	for _, f := range filtrosNumericos {
+		if v := query.Get(f.parametro); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, f.parametro+" inválido", http.StatusBadRequest)
+				return
+			}
+			condiciones = append(condiciones, f.condicion)
+			args = append(args, n)
+		}
+	}
+
+	// Solo actas de los estados que atiende el visor
+	condiciones = append(condiciones, estados.CondicionSQL("m.estado_id"))
+
+	// Restringir a los municipios del usuario
+	if !claims.EsAdmin() {
+		condiciones = append(condiciones,
+			"a.municipio_id IN (SELECT municipio_id FROM v_usuario_municipios WHERE usuario_id = ?)")
+		args = append(args, claims.UserID)
+	}
+
+	limite := 50
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 200 {
+		limite = 200
+	}
+	minRelevancia := 0.5
+	if v := query.Get("min_relevancia"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 || n > 1 {
+			http.Error(w, "min_relevancia inválido", http.StatusBadRequest)
+			return
+		}
+		minRelevancia = n
+	}
+
+	// Se traen más candidatos que el límite porque el orden final es por
+	// relevancia; los que mejor coinciden por clave fonética van primero
+	// para que el recorte no dependa del orden físico de la tabla.
+	// Los nombres de municipio y localidad son los vigentes en la fecha del acta
+	fecha := "COALESCE(a.fecha_evento, MAKEDATE(a.anio, 1))"
+	rows, err := database.DB.Query(`
+		SELECT a.id, a.acto, COALESCE(ac.nombre, ''), a.municipio_id,
+			`+nombreVigenteSQL("municipio", "a.municipio_id", "0", fecha, "m.nombre")+`,
+			a.oficialia, a.localidad,
+			`+nombreVigenteSQL("localidad", "a.municipio_id", "a.localidad", fecha, "l.nombre")+`,
+			a.anio, a.num_acta, a.fecha_evento, p.nombre, p.primer_apellido, p.segundo_apellido
+		FROM acta_personas p
+		JOIN actas a ON p.acta_id = a.id
+		JOIN municipios m ON a.municipio_id = m.idmunicipios
+		LEFT JOIN localidades l ON l.idmunicipio = a.municipio_id AND l.idlocalidades = a.localidad
+		LEFT JOIN actos ac ON a.acto = ac.codigo
+		WHERE `+strings.Join(condiciones, " AND ")+`
+		ORDER BY `+strings.Join(orden, " + ")+` DESC,
+			p.primer_apellido, p.segundo_apellido, p.nombre, a.id
+		LIMIT ?`, append(append(args, argsOrden...), limite*10)...)
+	if err != nil {
+		http.Error(w, "Error buscando actas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	actas := []models.Acta{}
+	posicion := map[int]int{}
+	for rows.Next() {
+		var valores [3]string
+		a, err := escanearActa(rows, &valores[0], &valores[1], &valores[2])
+		if err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Relevancia: promedio de la similitud de los campos buscados
+		suma, campos := 0.0, 0
+		for i, buscado := range buscados {
+			if buscado != "" {
+				suma += fonetica.Relevancia(buscado, valores[i])
+				campos++
+			}
+		}
+		a.Relevancia = suma / float64(campos)
+		if a.Relevancia < minRelevancia {
+			continue
+		}
+
+		// Un acta puede coincidir por varias personas; se queda la mejor
+		if i, existe := posicion[a.ID]; existe {
+			if a.Relevancia > actas[i].Relevancia {
+				actas[i].Relevancia = a.Relevancia
+			}
+			continue
+		}
+		posicion[a.ID] = len(actas)
+		actas = append(actas, a)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sort.SliceStable(actas, func(i, j int) bool {
+		if actas[i].Relevancia != actas[j].Relevancia {
+			return actas[i].Relevancia > actas[j].Relevancia
+		}
+		if actas[i].Anio != actas[j].Anio {
+			return actas[i].Anio < actas[j].Anio
+		}
+		return actas[i].NumActa < actas[j].NumActa
+	})
+	if len(actas) > limite {
+		actas = actas[:limite]
+	}
+
+	if err := cargarPersonas(actas); err != nil {
+		http.Error(w, "Error consultando personas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(actas)
+}
+
+// escanearActa lee una fila con las columnas de actas y los nombres del
+// acto, del municipio y de la localidad; extra recibe las columnas adicionales que siguen a esas
+func escanearActa(rows *sql.Rows, extra ...interface{}) (models.Acta, error) {
+	var a models.Acta
+	var fecha sql.NullTime
+	destinos := append([]interface{}{&a.ID, &a.Acto, &a.ActoNombre, &a.Municipio, &a.MunicipioNombre, &a.Oficialia,
+		&a.Localidad, &a.LocalidadNombre, &a.Anio, &a.NumActa, &fecha}, extra...)
+	err := rows.Scan(destinos...)
+	if fecha.Valid {
+		f := fecha.Time.Format("2006-01-02")
+		a.FechaEvento = &f
+	}
+	a.Personas = []models.PersonaActa{}
+	return a, err
+}
+
+// cargarPersonas completa las personas de cada acta con una sola consulta
+func cargarPersonas(actas []models.Acta) error {
+	if len(actas) == 0 {
+		return nil
+	}
+	indice := make(map[int]int, len(actas))
+	marcadores := make([]string, len(actas))
+	args := make([]interface{}, len(actas))
+	for i, a := range actas {
+		indice[a.ID] = i
+		marcadores[i] = "?"
+		args[i] = a.ID
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT acta_id, rol, nombre, primer_apellido, segundo_apellido
+		FROM acta_personas
+		WHERE acta_id IN (`+strings.Join(marcadores, ",")+`)
+		ORDER BY acta_id, FIELD(rol, 'registrado', 'padre', 'madre', 'conyuge1', 'conyuge2'), id`,
+		args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var actaID int
+		var p models.PersonaActa
+		if err := rows.Scan(&actaID, &p.Rol, &p.Nombre, &p.PrimerApellido, &p.SegundoApellido); err != nil {
+			return err
+		}
+		i := indice[actaID]
+		actas[i].Personas = append(actas[i].Personas, p)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/models"
+)
+
+// GetActos devuelve el catálogo de actos registrales. Los usuarios solo ven
+// los activos; los administradores pueden pedir todos con ?todos=1.
+func GetActos(w http.ResponseWriter, r *http.Request) {
+	query := "SELECT codigo, nombre, activo FROM actos WHERE activo = 1 ORDER BY codigo"
+	if r.URL.Query().Get("todos") == "1" && auth.GetClaims(r).EsAdmin() {
+		query = "SELECT codigo, nombre, activo FROM actos ORDER BY codigo"
+	}
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		http.Error(w, "Error consultando actos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	actos := []models.Acto{}
+	for rows.Next() {
+		var a models.Acto
+		if err := rows.Scan(&a.Codigo, &a.Nombre, &a.Activo); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		actos = append(actos, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(actos)
+}
+
+// buscarActo devuelve el acto activo con ese código, o sql.ErrNoRows si no
+// existe o está desactivado
+func buscarActo(codigo string) (models.Acto, error) {
+	var a models.Acto
+	err := database.DB.QueryRow(
+		"SELECT codigo, nombre, activo FROM actos WHERE codigo = ? AND activo = 1", codigo,
+	).Scan(&a.Codigo, &a.Nombre, &a.Activo)
+	return a, err
+}
+
+// validarActo responde 400 si el acto no está en el catálogo; devuelve false
+// cuando ya se respondió
+func validarActo(w http.ResponseWriter, codigo string) (models.Acto, bool) {
+	a, err := buscarActo(codigo)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Acto inválido", http.StatusBadRequest)
+		return a, false
+	}
+	if err != nil {
+		http.Error(w, "Error consultando actos", http.StatusInternalServerError)
+		return a, false
+	}
+	return a, true
+}
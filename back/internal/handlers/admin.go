@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
 	"visor-pdf/internal/models"
 
 	"golang.org/x/crypto/bcrypt"
@@ -42,27 +46,158 @@ func CrearUsuario(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// Columnas por las que se puede ordenar el listado de usuarios
+var ordenUsuarios = map[string]string{
+	"id":           "u.id",
+	"username":     "u.username",
+	"rol":          "r.nombre",
+	"creado":       "u.creado_en",
+	"ultimo_login": "u.ultimo_login",
+}
+
+// ListarUsuarios devuelve los usuarios paginados.
+//
+// Parámetros opcionales:
+//   - q: texto a buscar en el username o en el nombre del rol
+//   - rol_id, activo (true/false): filtros
+//   - municipio: solo usuarios con acceso a ese municipio (directo, distrito, región o estado)
+//   - orden: id, username, rol, creado o ultimo_login; dir: asc o desc
+//   - limite (máx. 500, por defecto 50) y offset
+//   - incluir: lista separada por comas con ultimo_login y/o asignaciones
 func ListarUsuarios(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var condiciones []string
+	var args []interface{}
+
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		condiciones = append(condiciones, "(u.username LIKE ? OR r.nombre LIKE ?)")
+		args = append(args, "%"+q+"%", "%"+q+"%")
+	}
+	if rolID := query.Get("rol_id"); rolID != "" {
+		id, err := strconv.Atoi(rolID)
+		if err != nil {
+			http.Error(w, "rol_id inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "u.rol_id = ?")
+		args = append(args, id)
+	}
+	if activo := query.Get("activo"); activo != "" {
+		valor, err := strconv.ParseBool(activo)
+		if err != nil {
+			http.Error(w, "activo inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "u.activo = ?")
+		args = append(args, valor)
+	}
+	if municipio := query.Get("municipio"); municipio != "" {
+		id, err := strconv.Atoi(municipio)
+		if err != nil {
+			http.Error(w, "municipio inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones,
+			"EXISTS (SELECT 1 FROM v_usuario_municipios vm WHERE vm.usuario_id = u.id AND vm.municipio_id = ?)")
+		args = append(args, id)
+	}
+
+	where := ""
+	if len(condiciones) > 0 {
+		where = "WHERE " + strings.Join(condiciones, " AND ")
+	}
+
+	orden, ok := ordenUsuarios[query.Get("orden")]
+	if !ok {
+		orden = ordenUsuarios["id"]
+	}
+	dir := "ASC"
+	if strings.EqualFold(query.Get("dir"), "desc") {
+		dir = "DESC"
+	}
+
+	limite := 50
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 500 {
+		limite = 500
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "offset inválido", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	incluirLogin, incluirAsignaciones := false, false
+	for _, campo := range strings.Split(query.Get("incluir"), ",") {
+		switch strings.TrimSpace(campo) {
+		case "ultimo_login":
+			incluirLogin = true
+		case "asignaciones":
+			incluirAsignaciones = true
+		}
+	}
+
+	lista := models.ListaUsuarios{Usuarios: []models.Usuario{}, Limite: limite, Offset: offset}
+
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*)
+		FROM usuarios u
+		JOIN roles r ON u.rol_id = r.id
+		`+where, args...).Scan(&lista.Total)
+	if err != nil {
+		http.Error(w, "Error contando usuarios", http.StatusInternalServerError)
+		return
+	}
+
 	rows, err := database.DB.Query(`
-		SELECT u.id, u.username, u.activo, u.rol_id, r.nombre as rol_nombre
+		SELECT u.id, u.username, u.activo, u.rol_id, r.nombre as rol_nombre, u.ultimo_login,
+			(SELECT COUNT(DISTINCT vm.municipio_id) FROM v_usuario_municipios vm WHERE vm.usuario_id = u.id)
 		FROM usuarios u
 		JOIN roles r ON u.rol_id = r.id
-	`)
+		`+where+`
+		ORDER BY `+orden+` `+dir+`, u.id
+		LIMIT ? OFFSET ?`, append(args, limite, offset)...)
 	if err != nil {
 		http.Error(w, "Error consultando usuarios", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var usuarios []models.Usuario
 	for rows.Next() {
 		var u models.Usuario
-		rows.Scan(&u.ID, &u.Username, &u.Activo, &u.RolID, &u.RolNombre)
-		usuarios = append(usuarios, u)
+		var ultimoLogin sql.NullTime
+		var asignaciones int
+		if err := rows.Scan(&u.ID, &u.Username, &u.Activo, &u.RolID, &u.RolNombre, &ultimoLogin, &asignaciones); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if incluirLogin && ultimoLogin.Valid {
+			u.UltimoLogin = &ultimoLogin.Time
+		}
+		if incluirAsignaciones {
+			u.TotalAsignaciones = &asignaciones
+		}
+		lista.Usuarios = append(lista.Usuarios, u)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(usuarios)
+	json.NewEncoder(w).Encode(lista)
 }
 
 func AsignarMunicipiosUsuario(w http.ResponseWriter, r *http.Request) {
@@ -104,10 +239,11 @@ func ObtenerMunicipiosUsuario(w http.ResponseWriter, r *http.Request) {
 	// Eliminamos el parámetro de fecha
 
 	rows, err := database.DB.Query(`
-        SELECT DISTINCT um.municipio_id, m.nombre
-        FROM usuario_municipios um
+        SELECT DISTINCT um.municipio_id, m.estado_id, e.abreviatura, m.clave, m.nombre, m.activo
+        FROM v_usuario_municipios um
         JOIN municipios m ON um.municipio_id = m.idmunicipios
-        WHERE um.usuario_id = ?`,
+        JOIN estados e ON m.estado_id = e.id
+        WHERE um.usuario_id = ? AND `+estados.CondicionSQL("m.estado_id"),
 		usuarioID)
 
 	if err != nil {
@@ -119,7 +255,7 @@ func ObtenerMunicipiosUsuario(w http.ResponseWriter, r *http.Request) {
 	var municipios []models.Municipio
 	for rows.Next() {
 		var m models.Municipio
-		rows.Scan(&m.ID, &m.Nombre)
+		rows.Scan(&m.ID, &m.EstadoID, &m.Estado, &m.Clave, &m.Nombre, &m.Activo)
 		municipios = append(municipios, m)
 	}
 
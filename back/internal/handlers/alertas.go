@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/models"
+)
+
+// ListarAlertas devuelve las alertas del detector, las más recientes primero.
+// Filtros opcionales: revisada (true/false), usuario_id, tipo, limite (máx. 500).
+func ListarAlertas(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var condiciones []string
+	var args []interface{}
+	if v := query.Get("revisada"); v != "" {
+		revisada, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "revisada inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "a.revisada = ?")
+		args = append(args, revisada)
+	}
+	if v := query.Get("usuario_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "usuario_id inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "a.usuario_id = ?")
+		args = append(args, id)
+	}
+	if v := query.Get("tipo"); v != "" {
+		condiciones = append(condiciones, "a.tipo = ?")
+		args = append(args, v)
+	}
+	where := ""
+	if len(condiciones) > 0 {
+		where = "WHERE " + strings.Join(condiciones, " AND ")
+	}
+
+	limite := 100
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 500 {
+		limite = 500
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT a.id, a.usuario_id, u.username, a.tipo, a.detalle, a.creado_en,
+			a.revisada, rv.username, a.revisada_en
+		FROM alertas a
+		JOIN usuarios u ON a.usuario_id = u.id
+		LEFT JOIN usuarios rv ON a.revisada_por = rv.id
+		`+where+`
+		ORDER BY a.creado_en DESC, a.id DESC
+		LIMIT ?`, append(args, limite)...)
+	if err != nil {
+		http.Error(w, "Error consultando alertas", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	alertas := []models.Alerta{}
+	for rows.Next() {
+		var a models.Alerta
+		var revisadaPor sql.NullString
+		var revisadaEn sql.NullTime
+		if err := rows.Scan(&a.ID, &a.UsuarioID, &a.Username, &a.Tipo, &a.Detalle, &a.CreadoEn,
+			&a.Revisada, &revisadaPor, &revisadaEn); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if revisadaPor.Valid {
+			a.RevisadaPor = &revisadaPor.String
+		}
+		if revisadaEn.Valid {
+			a.RevisadaEn = &revisadaEn.Time
+		}
+		alertas = append(alertas, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alertas)
+}
+
+// RevisarAlerta marca una alerta como revisada por el administrador actual
+func RevisarAlerta(w http.ResponseWriter, r *http.Request) {
+	var datos struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || datos.ID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+
+	claims := auth.GetClaims(r)
+	result, err := database.DB.Exec(`
+		UPDATE alertas SET revisada = 1, revisada_por = ?, revisada_en = NOW()
+		WHERE id = ? AND revisada = 0`,
+		claims.UserID, datos.ID)
+	if err != nil {
+		http.Error(w, "Error actualizando alerta", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Alerta no encontrada o ya revisada", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Alerta marcada como revisada"})
+}
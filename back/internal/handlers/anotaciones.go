@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"visor-pdf/internal/auditoria"
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/models"
+)
+
+// maxTextoAnotacion es el largo máximo del comentario, en caracteres
+const maxTextoAnotacion = 4000
+
+// Visibilidades de una anotación: la privada solo la ve su autor; la de
+// equipo, todos los usuarios con el municipio asignado
+var visibilidadesAnotacion = map[string]bool{"privada": true, "equipo": true}
+
+// datosAnotacion es el cuerpo de /api/anotaciones/crear y /editar
+type datosAnotacion struct {
+	ID     int64 `json:"id"`
+	Pagina int   `json:"pagina"`
+	models.RegionPagina
+	Texto       string `json:"texto"`
+	Visibilidad string `json:"visibilidad"`
+}
+
+// validar normaliza el texto y la visibilidad; la región se valida aparte
+// contra el tamaño de la página con regionEnPagina
+func (d *datosAnotacion) validar() error {
+	d.Texto = strings.TrimSpace(d.Texto)
+	if d.Texto == "" {
+		return fmt.Errorf("el texto de la anotación está vacío")
+	}
+	if len([]rune(d.Texto)) > maxTextoAnotacion {
+		return fmt.Errorf("el texto excede %d caracteres", maxTextoAnotacion)
+	}
+	if d.Visibilidad == "" {
+		d.Visibilidad = "privada"
+	}
+	if !visibilidadesAnotacion[d.Visibilidad] {
+		return fmt.Errorf("visibilidad inválida (privada o equipo)")
+	}
+	return nil
+}
+
+// regionEnPagina comprueba que la región quede dentro de la página, con
+// medio punto de tolerancia por redondeo del cliente
+func regionEnPagina(r models.RegionPagina, pagina models.PaginaPDF) error {
+	if r.Ancho <= 0 || r.Alto <= 0 || r.X < 0 || r.Y < 0 ||
+		r.X+r.Ancho > pagina.Ancho+0.5 || r.Y+r.Alto > pagina.Alto+0.5 {
+		return fmt.Errorf("la región queda fuera de la página (%.0f x %.0f puntos)", pagina.Ancho, pagina.Alto)
+	}
+	return nil
+}
+
+// ListarAnotaciones devuelve las anotaciones del acta que el usuario puede
+// ver: las suyas y las de equipo. Mismos parámetros que /api/pdf más
+// pagina, opcional.
+func ListarAnotaciones(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetClaims(r)
+	pagina, ok := paginaAnotaciones(w, r)
+	if !ok {
+		return
+	}
+	visualizacion, ok := actaAnotada(w, r, claims)
+	if !ok {
+		return
+	}
+
+	anotaciones, err := consultarAnotaciones(visualizacion, claims.UserID, pagina)
+	if err != nil {
+		http.Error(w, "Error consultando anotaciones", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(anotaciones)
+}
+
+// CrearAnotacion guarda una anotación sobre una región de una página del
+// acta. Mismos parámetros que /api/pdf y en el cuerpo pagina, x, y, ancho,
+// alto (puntos PDF), texto y visibilidad (privada por omisión).
+func CrearAnotacion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	var datos datosAnotacion
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	if err := datos.validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := auth.GetClaims(r)
+	visualizacion, acta, archivo, ok := ubicarActa(w, r, claims.UserID)
+	if !ok {
+		return
+	}
+	if !exigirMunicipio(w, claims, visualizacion.Municipio) {
+		return
+	}
+	dim, ok := dimensionesPagina(w, acta, archivo, datos.Pagina)
+	if !ok {
+		return
+	}
+	if err := regionEnPagina(datos.RegionPagina, dim); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO anotaciones
+			(acto, municipio_id, oficialia, localidad, anio, num_acta, pagina, x, y, ancho, alto, texto, visibilidad, usuario_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		visualizacion.Acto, visualizacion.Municipio, visualizacion.Oficialia, visualizacion.Localidad,
+		visualizacion.Anio, visualizacion.NumActa, datos.Pagina, datos.X, datos.Y, datos.Ancho, datos.Alto,
+		datos.Texto, datos.Visibilidad, claims.UserID)
+	if err != nil {
+		http.Error(w, "Error guardando anotación", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"message": "Anotación guardada exitosamente",
+	})
+}
+
+// EditarAnotacion reemplaza la región, el texto y la visibilidad de una
+// anotación. Solo la puede editar su autor.
+func EditarAnotacion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	var datos datosAnotacion
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || datos.ID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	if err := datos.validar(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := auth.GetClaims(r)
+	visualizacion, autor, ok := anotacionGuardada(w, datos.ID)
+	if !ok {
+		return
+	}
+	if autor != claims.UserID {
+		http.Error(w, "Solo el autor puede editar la anotación", http.StatusForbidden)
+		return
+	}
+	if !exigirMunicipio(w, claims, visualizacion.Municipio) {
+		return
+	}
+	acta, archivo, ok := ubicarActaRegistrada(w, visualizacion)
+	if !ok {
+		return
+	}
+	dim, ok := dimensionesPagina(w, acta, archivo, datos.Pagina)
+	if !ok {
+		return
+	}
+	if err := regionEnPagina(datos.RegionPagina, dim); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err := database.DB.Exec(`
+		UPDATE anotaciones
+		SET pagina = ?, x = ?, y = ?, ancho = ?, alto = ?, texto = ?, visibilidad = ?
+		WHERE id = ?`,
+		datos.Pagina, datos.X, datos.Y, datos.Ancho, datos.Alto, datos.Texto, datos.Visibilidad, datos.ID)
+	if err != nil {
+		http.Error(w, "Error guardando anotación", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Anotación actualizada exitosamente"})
+}
+
+// EliminarAnotacion borra una anotación. La puede borrar su autor o un
+// administrador.
+func EliminarAnotacion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	var datos struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || datos.ID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+
+	claims := auth.GetClaims(r)
+	_, autor, ok := anotacionGuardada(w, datos.ID)
+	if !ok {
+		return
+	}
+	if autor != claims.UserID && !claims.EsAdmin() {
+		http.Error(w, "Solo el autor puede eliminar la anotación", http.StatusForbidden)
+		return
+	}
+
+	if _, err := database.DB.Exec("DELETE FROM anotaciones WHERE id = ?", datos.ID); err != nil {
+		http.Error(w, "Error eliminando anotación", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Anotación eliminada exitosamente"})
+}
+
+// ExportarAnotacionesW3C devuelve las anotaciones visibles del acta como
+// AnnotationPage del modelo W3C Web Annotation (JSON-LD), para cargarlas en
+// visores IIIF. Mismos parámetros que /api/anotaciones más:
+//
+//	canvas  URI del canvas de cada página; {pagina} se reemplaza por el
+//	        número. Sin él se usa URL_PUBLICA/iiif/{acta}/canvas/{pagina}.
+//	escala  unidades del canvas por punto PDF (1 por omisión), por ejemplo
+//	        DZI_ZOOM si el canvas mide lo que el nivel más alto de Deep Zoom
+func ExportarAnotacionesW3C(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetClaims(r)
+	pagina, ok := paginaAnotaciones(w, r)
+	if !ok {
+		return
+	}
+	escala := 1.0
+	if v := r.URL.Query().Get("escala"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n <= 0 || n > 100 {
+			http.Error(w, "escala inválida", http.StatusBadRequest)
+			return
+		}
+		escala = n
+	}
+	visualizacion, ok := actaAnotada(w, r, claims)
+	if !ok {
+		return
+	}
+
+	base := strings.TrimRight(Cfg.URLPublica, "/")
+	canvas := r.URL.Query().Get("canvas")
+	if canvas == "" {
+		canvas = fmt.Sprintf("%s/iiif/%s-%d-%d-%d-%d-%d/canvas/{pagina}", base, visualizacion.Acto,
+			visualizacion.Municipio, visualizacion.Oficialia, visualizacion.Localidad, visualizacion.Anio, visualizacion.NumActa)
+	}
+
+	anotaciones, err := consultarAnotaciones(visualizacion, claims.UserID, pagina)
+	if err != nil {
+		http.Error(w, "Error consultando anotaciones", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(anotaciones))
+	for _, a := range anotaciones {
+		items = append(items, map[string]interface{}{
+			"id":         fmt.Sprintf("%s/anotaciones/%d", base, a.ID),
+			"type":       "Annotation",
+			"motivation": "commenting",
+			"created":    a.CreadaEn.UTC().Format("2006-01-02T15:04:05Z"),
+			"modified":   a.ActualizadaEn.UTC().Format("2006-01-02T15:04:05Z"),
+			"creator":    map[string]string{"type": "Person", "nickname": a.Username},
+			"body": map[string]string{
+				"type":     "TextualBody",
+				"value":    a.Texto,
+				"format":   "text/plain",
+				"language": "es",
+			},
+			"target": map[string]interface{}{
+				"source": strings.ReplaceAll(canvas, "{pagina}", strconv.Itoa(a.Pagina)),
+				"selector": map[string]string{
+					"type":       "FragmentSelector",
+					"conformsTo": "http://www.w3.org/TR/media-frags/",
+					"value":      fmt.Sprintf("xywh=%.0f,%.0f,%.0f,%.0f", a.X*escala, a.Y*escala, a.Ancho*escala, a.Alto*escala),
+				},
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", `application/ld+json; profile="http://www.w3.org/ns/anno.jsonld"`)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"@context": "http://www.w3.org/ns/anno.jsonld",
+		"id":       base + r.URL.RequestURI(),
+		"type":     "AnnotationPage",
+		"items":    items,
+	})
+}
+
+// paginaAnotaciones lee el filtro opcional pagina (0 = todas)
+func paginaAnotaciones(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("pagina")
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		http.Error(w, "pagina inválida", http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// actaAnotada valida los parámetros del acta y que el usuario pueda
+// anotarla. Si falla ya respondió al cliente.
+func actaAnotada(w http.ResponseWriter, r *http.Request, claims *auth.Claims) (auditoria.Visualizacion, bool) {
+	visualizacion, _, _, ok := ubicarActa(w, r, claims.UserID)
+	if !ok {
+		return visualizacion, false
+	}
+	return visualizacion, exigirMunicipio(w, claims, visualizacion.Municipio)
+}
+
+// anotacionGuardada devuelve el acta y el autor de una anotación
+func anotacionGuardada(w http.ResponseWriter, id int64) (auditoria.Visualizacion, int, bool) {
+	var v auditoria.Visualizacion
+	var autor int
+	err := database.DB.QueryRow(`
+		SELECT acto, municipio_id, oficialia, localidad, anio, num_acta, usuario_id
+		FROM anotaciones WHERE id = ?`, id).Scan(&v.Acto, &v.Municipio, &v.Oficialia, &v.Localidad,
+		&v.Anio, &v.NumActa, &autor)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Anotación no encontrada", http.StatusNotFound)
+		return v, 0, false
+	}
+	if err != nil {
+		http.Error(w, "Error consultando anotación", http.StatusInternalServerError)
+		return v, 0, false
+	}
+	return v, autor, true
+}
+
+// consultarAnotaciones lee las anotaciones del acta visibles para el
+// usuario, de una página o de todas (pagina 0)
+func consultarAnotaciones(v auditoria.Visualizacion, usuarioID, pagina int) ([]models.Anotacion, error) {
+	condiciones := `a.acto = ? AND a.municipio_id = ? AND a.oficialia = ? AND a.localidad = ? AND a.anio = ?
+		AND a.num_acta = ? AND (a.usuario_id = ? OR a.visibilidad = 'equipo')`
+	args := []interface{}{v.Acto, v.Municipio, v.Oficialia, v.Localidad, v.Anio, v.NumActa, usuarioID}
+	if pagina > 0 {
+		condiciones += " AND a.pagina = ?"
+		args = append(args, pagina)
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT a.id, a.pagina, a.x, a.y, a.ancho, a.alto, a.texto, a.visibilidad, a.usuario_id, u.username,
+			a.creada_en, a.actualizada_en
+		FROM anotaciones a
+		JOIN usuarios u ON a.usuario_id = u.id
+		WHERE `+condiciones+`
+		ORDER BY a.pagina, a.y, a.x, a.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	anotaciones := []models.Anotacion{}
+	for rows.Next() {
+		var a models.Anotacion
+		if err := rows.Scan(&a.ID, &a.Pagina, &a.X, &a.Y, &a.Ancho, &a.Alto, &a.Texto, &a.Visibilidad,
+			&a.UsuarioID, &a.Username, &a.CreadaEn, &a.ActualizadaEn); err != nil {
+			return nil, err
+		}
+		anotaciones = append(anotaciones, a)
+	}
+	return anotaciones, rows.Err()
+}
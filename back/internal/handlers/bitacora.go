@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"visor-pdf/internal/auditoria"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/models"
+)
+
+// ListarBitacora devuelve las visualizaciones registradas, las más recientes primero.
+// Filtros opcionales: vista (código impreso en la marca de agua), usuario_id, limite (máx. 500).
+func ListarBitacora(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var condiciones []string
+	var args []interface{}
+	if v := query.Get("vista"); v != "" {
+		id, err := auditoria.IDDesdeCodigo(v)
+		if err != nil {
+			http.Error(w, "Código de vista inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "b.id = ?")
+		args = append(args, id)
+	}
+	if v := query.Get("usuario_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "usuario_id inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "b.usuario_id = ?")
+		args = append(args, id)
+	}
+	where := ""
+	if len(condiciones) > 0 {
+		where = "WHERE " + strings.Join(condiciones, " AND ")
+	}
+
+	limite := 100
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 500 {
+		limite = 500
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT b.id, b.usuario_id, u.username, b.acto, COALESCE(ac.nombre, ''), b.municipio_id, b.oficialia, b.localidad,
+			b.anio, b.num_acta, COALESCE(b.ip, ''), b.creado_en
+		FROM bitacora_visualizaciones b
+		JOIN usuarios u ON b.usuario_id = u.id
+		LEFT JOIN actos ac ON b.acto = ac.codigo
+		`+where+`
+		ORDER BY b.id DESC
+		LIMIT ?`, append(args, limite)...)
+	if err != nil {
+		http.Error(w, "Error consultando bitácora", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	registros := []models.RegistroVisualizacion{}
+	for rows.Next() {
+		var v models.RegistroVisualizacion
+		if err := rows.Scan(&v.ID, &v.UsuarioID, &v.Username, &v.Acto, &v.ActoNombre, &v.Municipio, &v.Oficialia,
+			&v.Localidad, &v.Anio, &v.NumActa, &v.IP, &v.CreadoEn); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		v.Vista = auditoria.CodigoVista(v.ID)
+		registros = append(registros, v)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(registros)
+}
+
+// ListarDescargas devuelve las descargas del PDF original, las más recientes primero.
+// Filtros opcionales: codigo (impreso en el pie de las descargas selladas), usuario_id, limite (máx. 500).
+func ListarDescargas(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var condiciones []string
+	var args []interface{}
+	if v := query.Get("codigo"); v != "" {
+		id, err := auditoria.IDDesdeCodigoDescarga(v)
+		if err != nil {
+			http.Error(w, "Código de descarga inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "d.id = ?")
+		args = append(args, id)
+	}
+	if v := query.Get("usuario_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "usuario_id inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "d.usuario_id = ?")
+		args = append(args, id)
+	}
+	where := ""
+	if len(condiciones) > 0 {
+		where = "WHERE " + strings.Join(condiciones, " AND ")
+	}
+
+	limite := 100
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 500 {
+		limite = 500
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT d.id, d.usuario_id, u.username, d.acto, COALESCE(ac.nombre, ''), d.municipio_id, d.oficialia, d.localidad,
+			d.anio, d.num_acta, d.raiz, d.ruta, d.sellado, COALESCE(d.filtros, ''), COALESCE(d.rango, ''), d.peticiones, COALESCE(d.ip, ''), d.creado_en,
+			COALESCE(d.ultima_peticion, d.creado_en)
+		FROM bitacora_descargas d
+		JOIN usuarios u ON d.usuario_id = u.id
+		LEFT JOIN actos ac ON d.acto = ac.codigo
+		`+where+`
+		ORDER BY d.id DESC
+		LIMIT ?`, append(args, limite)...)
+	if err != nil {
+		http.Error(w, "Error consultando descargas", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	registros := []models.RegistroDescarga{}
+	for rows.Next() {
+		var d models.RegistroDescarga
+		if err := rows.Scan(&d.ID, &d.UsuarioID, &d.Username, &d.Acto, &d.ActoNombre, &d.Municipio, &d.Oficialia,
+			&d.Localidad, &d.Anio, &d.NumActa, &d.Raiz, &d.Ruta, &d.Sellado, &d.Filtros, &d.Rango, &d.Peticiones, &d.IP, &d.CreadoEn,
+			&d.UltimaEn); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		d.Codigo = auditoria.CodigoDescarga(d.ID)
+		registros = append(registros, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(registros)
+}
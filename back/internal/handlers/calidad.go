@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/integridad"
+	"visor-pdf/internal/models"
+)
+
+// Categorías de los reportes de calidad y estados de la cola de revisión
+var (
+	categoriasCalidad = map[string]bool{
+		"ilegible":        true,
+		"recortada":       true,
+		"acta_equivocada": true,
+		"pagina_faltante": true,
+		"otra":            true,
+	}
+	estadosCalidad = map[string]bool{
+		"abierto":             true,
+		"en_redigitalizacion": true,
+		"corregido":           true,
+		"no_se_corregira":     true,
+	}
+)
+
+// Largo máximo de la descripción de un reporte y del comentario del supervisor
+const (
+	maxDescripcionCalidad = 2000
+	maxComentarioCalidad  = 500
+)
+
+// sugerirCierreSQL es verdadero si el reporte sigue abierto y la
+// verificación de integridad registró otro contenido para el archivo
+const sugerirCierreSQL = `(r.estado IN ('abierto', 'en_redigitalizacion')
+	AND a.sha256_ultimo IS NOT NULL AND a.sha256_ultimo <> r.sha256)`
+
+// ReportarCalidad registra un problema de digitalización en una página del
+// acta (ilegible, recortada, acta equivocada...). Mismos parámetros que
+// /api/pdf y en el cuerpo pagina, categoria, descripcion y, opcionalmente,
+// region {x, y, ancho, alto} en puntos PDF. Guarda el SHA-256 del PDF que
+// se vio para reconocer después si el archivo ya se reemplazó. Salvo a los
+// administradores, exige que el municipio esté asignado.
+func ReportarCalidad(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	var datos struct {
+		Pagina      int                  `json:"pagina"`
+		Categoria   string               `json:"categoria"`
+		Descripcion string               `json:"descripcion"`
+		Region      *models.RegionPagina `json:"region"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	if !categoriasCalidad[datos.Categoria] {
+		http.Error(w, "categoria inválida (ilegible, recortada, acta_equivocada, pagina_faltante u otra)",
+			http.StatusBadRequest)
+		return
+	}
+	datos.Descripcion = strings.TrimSpace(datos.Descripcion)
+	if datos.Descripcion == "" || len([]rune(datos.Descripcion)) > maxDescripcionCalidad {
+		http.Error(w, "Describe el problema (máximo 2000 caracteres)", http.StatusBadRequest)
+		return
+	}
+
+	claims := auth.GetClaims(r)
+	visualizacion, acta, archivo, ok := ubicarActa(w, r, claims.UserID)
+	if !ok || !exigirMunicipio(w, claims, visualizacion.Municipio) {
+		return
+	}
+	dim, ok := dimensionesPagina(w, acta, archivo, datos.Pagina)
+	if !ok {
+		return
+	}
+	region := []interface{}{nil, nil, nil, nil}
+	if datos.Region != nil {
+		if err := regionEnPagina(*datos.Region, dim); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		region = []interface{}{datos.Region.X, datos.Region.Y, datos.Region.Ancho, datos.Region.Alto}
+	}
+
+	info, ok := infoArchivo(w, acta, archivo)
+	if !ok {
+		return
+	}
+	pdf, err := contenidoPDF(archivo, info)
+	if err != nil {
+		http.Error(w, "Error leyendo el PDF del acta", http.StatusBadGateway)
+		return
+	}
+	suma := sha256.Sum256(pdf)
+
+	args := []interface{}{visualizacion.Acto, visualizacion.Municipio, visualizacion.Oficialia,
+		visualizacion.Localidad, visualizacion.Anio, visualizacion.NumActa, datos.Pagina, datos.Categoria,
+		datos.Descripcion}
+	args = append(args, region...)
+	args = append(args, archivo.Raiz, archivo.Relativa, hex.EncodeToString(suma[:]), claims.UserID)
+	result, err := database.DB.Exec(`
+		INSERT INTO reportes_calidad
+			(acto, municipio_id, oficialia, localidad, anio, num_acta, pagina, categoria, descripcion,
+			 x, y, ancho, alto, raiz, ruta, sha256, reportado_por)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)
+	if err != nil {
+		http.Error(w, "Error guardando reporte", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"message": "Reporte registrado exitosamente",
+	})
+}
+
+// ListarReportesCalidad es la cola de revisión de los reportes de calidad.
+// Con el permiso supervisar_calidad devuelve todos; sin él, los del propio
+// usuario. Filtros opcionales: estado, categoria, asignado_a, acto,
+// municipio, anio, num_acta, sugerir_cierre=1 y limite (100 por omisión,
+// máximo 500).
+func ListarReportesCalidad(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	claims := auth.GetClaims(r)
+
+	var condiciones []string
+	var args []interface{}
+	supervisor, err := auth.TienePermiso(claims, auth.PermisoSupervisarCalidad)
+	if err != nil {
+		http.Error(w, "Error consultando permisos", http.StatusInternalServerError)
+		return
+	}
+	if !supervisor {
+		condiciones = append(condiciones, "r.reportado_por = ?")
+		args = append(args, claims.UserID)
+	}
+
+	if v := query.Get("estado"); v != "" {
+		if !estadosCalidad[v] {
+			http.Error(w, "estado inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "r.estado = ?")
+		args = append(args, v)
+	}
+	if v := query.Get("categoria"); v != "" {
+		if !categoriasCalidad[v] {
+			http.Error(w, "categoria inválida", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "r.categoria = ?")
+		args = append(args, v)
+	}
+	if v := query.Get("acto"); v != "" {
+		condiciones = append(condiciones, "r.acto = ?")
+		args = append(args, v)
+	}
+	filtrosNumericos := []struct {
+		parametro, condicion string
+	}{
+		{"asignado_a", "r.asignado_a = ?"},
+		{"municipio", "r.municipio_id = ?"},
+		{"anio", "r.anio = ?"},
+		{"num_acta", "r.num_acta = ?"},
+	}
+	for _, f := range filtrosNumericos {
+		if v := query.Get(f.parametro); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, f.parametro+" inválido", http.StatusBadRequest)
+				return
+			}
+			condiciones = append(condiciones, f.condicion)
+			args = append(args, n)
+		}
+	}
+	if query.Get("sugerir_cierre") == "1" {
+		condiciones = append(condiciones, sugerirCierreSQL)
+	}
+	where := ""
+	if len(condiciones) > 0 {
+		where = "WHERE " + strings.Join(condiciones, " AND ")
+	}
+
+	limite := 100
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 500 {
+		limite = 500
+	}
+
+	// La incidencia es el cambio de contenido más reciente detectado
+	// después del reporte
+	rows, err := database.DB.Query(`
+		SELECT r.id, r.acto, r.municipio_id, r.oficialia, r.localidad, r.anio, r.num_acta, r.pagina,
+			r.categoria, r.descripcion, r.x, r.y, r.ancho, r.alto, r.estado, r.asignado_a, ua.username,
+			r.comentario, ur.username, r.reportado_en, uu.username, r.actualizado_en, r.raiz, r.ruta, r.sha256,
+			a.sha256_ultimo,
+			(SELECT MAX(i.id) FROM incidencias_integridad i
+			 WHERE i.archivo_id = a.id AND i.tipo = 'cambiado' AND i.detectada_en >= r.reportado_en),
+			`+sugerirCierreSQL+`
+		FROM reportes_calidad r
+		JOIN usuarios ur ON r.reportado_por = ur.id
+		LEFT JOIN usuarios ua ON r.asignado_a = ua.id
+		LEFT JOIN usuarios uu ON r.actualizado_por = uu.id
+		LEFT JOIN archivos_pdf a ON a.raiz = r.raiz AND a.ruta = r.ruta
+		`+where+`
+		ORDER BY r.id DESC
+		LIMIT ?`, append(args, limite)...)
+	if err != nil {
+		http.Error(w, "Error consultando reportes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	reportes := []models.ReporteCalidad{}
+	for rows.Next() {
+		var rep models.ReporteCalidad
+		var x, y, ancho, alto sql.NullFloat64
+		var asignado, incidencia sql.NullInt64
+		var asignadoNombre, comentario, actualizadoPor, actual sql.NullString
+		var actualizadoEn sql.NullTime
+		var sugerir sql.NullBool
+		if err := rows.Scan(&rep.ID, &rep.Acta.Acto, &rep.Acta.Municipio, &rep.Acta.Oficialia,
+			&rep.Acta.Localidad, &rep.Acta.Anio, &rep.Acta.NumActa, &rep.Pagina, &rep.Categoria,
+			&rep.Descripcion, &x, &y, &ancho, &alto, &rep.Estado, &asignado, &asignadoNombre, &comentario,
+			&rep.ReportadoPor, &rep.ReportadoEn, &actualizadoPor, &actualizadoEn, &rep.Raiz, &rep.Ruta,
+			&rep.SHA256, &actual, &incidencia, &sugerir); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if x.Valid {
+			rep.Region = &models.RegionPagina{X: x.Float64, Y: y.Float64, Ancho: ancho.Float64, Alto: alto.Float64}
+		}
+		rep.AsignadoA = enteroNulo(asignado)
+		rep.AsignadoNombre = textoNulo(asignadoNombre)
+		rep.Comentario = textoNulo(comentario)
+		rep.ActualizadoPor = textoNulo(actualizadoPor)
+		if actualizadoEn.Valid {
+			rep.ActualizadoEn = &actualizadoEn.Time
+		}
+		rep.SHA256Actual = textoNulo(actual)
+		rep.IncidenciaID = enteroNulo(incidencia)
+		rep.SugerirCierre = sugerir.Bool
+		reportes = append(reportes, rep)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reportes)
+}
+
+// TriageCalidad cambia el estado, el responsable y el comentario de un
+// reporte. Requiere el permiso supervisar_calidad. Recibe id, estado,
+// asignado_a (null = sin asignar) y comentario; con estado corregido y
+// aceptar_cambio=true además marca como revisado el cambio de contenido
+// pendiente del archivo y lo adopta como referencia, igual que
+// /api/admin/integridad/revisar con aceptar.
+func TriageCalidad(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	claims := auth.GetClaims(r)
+	permitido, err := auth.TienePermiso(claims, auth.PermisoSupervisarCalidad)
+	if err != nil {
+		http.Error(w, "Error consultando permisos", http.StatusInternalServerError)
+		return
+	}
+	if !permitido {
+		http.Error(w, "No tiene permiso para revisar reportes de calidad", http.StatusForbidden)
+		return
+	}
+
+	var datos struct {
+		ID            int    `json:"id"`
+		Estado        string `json:"estado"`
+		AsignadoA     *int   `json:"asignado_a"`
+		Comentario    string `json:"comentario"`
+		AceptarCambio bool   `json:"aceptar_cambio"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || datos.ID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	if !estadosCalidad[datos.Estado] {
+		http.Error(w, "estado inválido (abierto, en_redigitalizacion, corregido o no_se_corregira)",
+			http.StatusBadRequest)
+		return
+	}
+	if datos.AceptarCambio && datos.Estado != "corregido" {
+		http.Error(w, "Solo se acepta el cambio del archivo al marcar el reporte como corregido", http.StatusBadRequest)
+		return
+	}
+	datos.Comentario = strings.TrimSpace(datos.Comentario)
+	if len([]rune(datos.Comentario)) > maxComentarioCalidad {
+		http.Error(w, "El comentario excede 500 caracteres", http.StatusBadRequest)
+		return
+	}
+	var comentario interface{}
+	if datos.Comentario != "" {
+		comentario = datos.Comentario
+	}
+	if datos.AsignadoA != nil {
+		var existe bool
+		if err := database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM usuarios WHERE id = ?)", *datos.AsignadoA).
+			Scan(&existe); err != nil {
+			http.Error(w, "Error consultando usuarios", http.StatusInternalServerError)
+			return
+		}
+		if !existe {
+			http.Error(w, "El usuario asignado no existe", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Error iniciando transacción", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var raiz, ruta string
+	err = tx.QueryRow("SELECT raiz, ruta FROM reportes_calidad WHERE id = ? FOR UPDATE", datos.ID).Scan(&raiz, &ruta)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Reporte no encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error consultando reporte", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE reportes_calidad
+		SET estado = ?, asignado_a = ?, comentario = ?, actualizado_por = ?, actualizado_en = NOW()
+		WHERE id = ?`, datos.Estado, datos.AsignadoA, comentario, claims.UserID, datos.ID); err != nil {
+		http.Error(w, "Error actualizando reporte", http.StatusInternalServerError)
+		return
+	}
+
+	mensaje := "Reporte actualizado exitosamente"
+	if datos.AceptarCambio {
+		var archivoID int
+		err := tx.QueryRow(`
+			SELECT a.id FROM archivos_pdf a
+			WHERE a.raiz = ? AND a.ruta = ? AND EXISTS (
+				SELECT 1 FROM incidencias_integridad i
+				WHERE i.archivo_id = a.id AND i.tipo = 'cambiado' AND i.revisada = 0
+			)
+			FOR UPDATE`, raiz, ruta).Scan(&archivoID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "El archivo no tiene un cambio de contenido pendiente de revisar", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error consultando incidencias", http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.Exec(`
+			UPDATE incidencias_integridad SET revisada = 1, revisada_por = ?, revisada_en = NOW()
+			WHERE archivo_id = ? AND tipo = 'cambiado' AND revisada = 0`, claims.UserID, archivoID); err != nil {
+			http.Error(w, "Error actualizando incidencia", http.StatusInternalServerError)
+			return
+		}
+		if err := integridad.AceptarCambio(tx, archivoID); err != nil {
+			http.Error(w, "Error aceptando el cambio", http.StatusInternalServerError)
+			return
+		}
+		mensaje = "Reporte corregido; el contenido actual del archivo es ahora el de referencia"
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Error guardando cambios", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": mensaje})
+}
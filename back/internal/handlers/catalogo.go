@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
+	"visor-pdf/internal/models"
+)
+
+// Administración de los catálogos de municipios y localidades.
+//
+// Ningún registro se borra: los PDFs siguen guardados con la clave original,
+// así que desactivar o fusionar solo marca el registro. Al renombrar, el
+// nombre anterior pasa a nombres_historicos para que las actas viejas
+// muestren el nombre vigente en su fecha. Cada cambio queda en
+// catalogo_cambios.
+
+// entidadCatalogo identifica un municipio o una localidad de un municipio
+type entidadCatalogo struct {
+	tipo      string // "municipio" o "localidad"
+	municipio int
+	localidad int
+	estado    int // estado del municipio (o el pedido al crearlo)
+}
+
+func (e entidadCatalogo) tabla() string {
+	if e.tipo == "municipio" {
+		return "municipios"
+	}
+	return "localidades"
+}
+
+// condicion devuelve el WHERE que selecciona la entidad y sus argumentos
+func (e entidadCatalogo) condicion() (string, []interface{}) {
+	if e.tipo == "municipio" {
+		return "idmunicipios = ?", []interface{}{e.municipio}
+	}
+	return "idmunicipio = ? AND idlocalidades = ?", []interface{}{e.municipio, e.localidad}
+}
+
+// conClave devuelve la misma clase de entidad con otra clave (el destino de
+// una fusión)
+func (e entidadCatalogo) conClave(id int) entidadCatalogo {
+	if e.tipo == "municipio" {
+		e.municipio = id
+	} else {
+		e.localidad = id
+	}
+	return e
+}
+
+func (e entidadCatalogo) String() string {
+	if e.tipo == "municipio" {
+		return fmt.Sprintf("municipio %d", e.municipio)
+	}
+	return fmt.Sprintf("localidad %d del municipio %d", e.localidad, e.municipio)
+}
+
+// datosCatalogo es el cuerpo de las peticiones de administración del catálogo
+type datosCatalogo struct {
+	MunicipioID int    `json:"municipio_id"`
+	LocalidadID int    `json:"localidad_id"`
+	Nombre      string `json:"nombre"`
+	DestinoID   int    `json:"destino_id"` // fusionar
+	EstadoID    int    `json:"estado_id"`  // crear municipio (por defecto 20)
+	Clave       int    `json:"clave"`      // crear municipio: clave INEGI dentro del estado
+	Fecha       string `json:"fecha"`      // renombrar: desde cuándo rige el nombre nuevo (AAAA-MM-DD)
+	Activo      bool   `json:"activo"`     // cambiar estado
+}
+
+// errorCatalogo es un error de validación que se responde al cliente
+type errorCatalogo struct {
+	estado  int
+	mensaje string
+}
+
+func (e *errorCatalogo) Error() string { return e.mensaje }
+
+func invalido(formato string, args ...interface{}) error {
+	return &errorCatalogo{http.StatusBadRequest, fmt.Sprintf(formato, args...)}
+}
+
+// operacionCatalogo aplica un cambio dentro de la transacción y devuelve la
+// acción registrada en la bitácora y su detalle
+type operacionCatalogo func(tx *sql.Tx, e entidadCatalogo, d datosCatalogo) (accion, detalle string, err error)
+
+func CrearMunicipio(w http.ResponseWriter, r *http.Request) {
+	cambiarCatalogo(w, r, "municipio", crearEntidad)
+}
+
+func RenombrarMunicipio(w http.ResponseWriter, r *http.Request) {
+	cambiarCatalogo(w, r, "municipio", renombrarEntidad)
+}
+
+func FusionarMunicipio(w http.ResponseWriter, r *http.Request) {
+	cambiarCatalogo(w, r, "municipio", fusionarEntidad)
+}
+
+func CambiarEstadoMunicipio(w http.ResponseWriter, r *http.Request) {
+	cambiarCatalogo(w, r, "municipio", cambiarEstadoEntidad)
+}
+
+func CrearLocalidad(w http.ResponseWriter, r *http.Request) {
+	cambiarCatalogo(w, r, "localidad", crearEntidad)
+}
+
+func RenombrarLocalidad(w http.ResponseWriter, r *http.Request) {
+	cambiarCatalogo(w, r, "localidad", renombrarEntidad)
+}
+
+func FusionarLocalidad(w http.ResponseWriter, r *http.Request) {
+	cambiarCatalogo(w, r, "localidad", fusionarEntidad)
+}
+
+func CambiarEstadoLocalidad(w http.ResponseWriter, r *http.Request) {
+	cambiarCatalogo(w, r, "localidad", cambiarEstadoEntidad)
+}
+
+// cambiarCatalogo decodifica la petición, ejecuta la operación en una
+// transacción y la registra en catalogo_cambios
+func cambiarCatalogo(w http.ResponseWriter, r *http.Request, tipo string, op operacionCatalogo) {
+	var d datosCatalogo
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil || d.MunicipioID < 1 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	if tipo == "localidad" && d.LocalidadID < 1 {
+		http.Error(w, "Falta localidad_id", http.StatusBadRequest)
+		return
+	}
+	d.Nombre = strings.TrimSpace(d.Nombre)
+	e := entidadCatalogo{tipo: tipo, municipio: d.MunicipioID, localidad: d.LocalidadID}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Error iniciando transacción", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Solo se administran municipios de los estados que atiende el visor
+	err = tx.QueryRow("SELECT estado_id FROM municipios WHERE idmunicipios = ?", e.municipio).Scan(&e.estado)
+	if err == sql.ErrNoRows {
+		e.estado = d.EstadoID
+		if e.estado == 0 {
+			e.estado = estados.Predeterminado
+		}
+	} else if err != nil {
+		http.Error(w, "Error consultando municipio", http.StatusInternalServerError)
+		return
+	}
+	if !estados.Sirve(e.estado) {
+		http.Error(w, fmt.Sprintf("El estado %d no lo atiende este visor", e.estado), http.StatusBadRequest)
+		return
+	}
+
+	accion, detalle, err := op(tx, e, d)
+	if err != nil {
+		if ec, ok := err.(*errorCatalogo); ok {
+			http.Error(w, ec.mensaje, ec.estado)
+			return
+		}
+		http.Error(w, "Error actualizando catálogo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO catalogo_cambios (usuario_id, tipo, accion, municipio_id, localidad_id, detalle)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		auth.GetClaims(r).UserID, e.tipo, accion, e.municipio, e.localidad, detalle)
+	if err != nil {
+		http.Error(w, "Error registrando el cambio", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Error guardando cambios", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Catálogo actualizado: " + detalle})
+}
+
+// estadoEntidad lee el nombre y el estado actuales; sql.ErrNoRows si no existe
+func estadoEntidad(tx *sql.Tx, e entidadCatalogo) (nombre string, activo bool, err error) {
+	where, args := e.condicion()
+	var n sql.NullString
+	err = tx.QueryRow("SELECT nombre, activo FROM "+e.tabla()+" WHERE "+where+" FOR UPDATE", args...).
+		Scan(&n, &activo)
+	return n.String, activo, err
+}
+
+// validarNombre exige un nombre no vacío y que no lo use otra entidad
+// activa del mismo ámbito (otro municipio del estado, u otra localidad del
+// municipio)
+func validarNombre(tx *sql.Tx, e entidadCatalogo, nombre string) error {
+	if nombre == "" {
+		return invalido("El nombre es obligatorio")
+	}
+	if len([]rune(nombre)) > 150 {
+		return invalido("El nombre no puede pasar de 150 caracteres")
+	}
+
+	var existe bool
+	var err error
+	if e.tipo == "municipio" {
+		err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM municipios
+			WHERE nombre = ? AND activo = 1 AND idmunicipios <> ? AND estado_id = ?)`,
+			nombre, e.municipio, e.estado).Scan(&existe)
+	} else {
+		err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM localidades
+			WHERE nombre = ? AND activo = 1 AND idmunicipio = ? AND idlocalidades <> ?)`,
+			nombre, e.municipio, e.localidad).Scan(&existe)
+	}
+	if err != nil {
+		return err
+	}
+	if existe {
+		return &errorCatalogo{http.StatusConflict, fmt.Sprintf("Ya existe otro registro activo llamado %q", nombre)}
+	}
+	return nil
+}
+
+func crearEntidad(tx *sql.Tx, e entidadCatalogo, d datosCatalogo) (string, string, error) {
+	if _, _, err := estadoEntidad(tx, e); err == nil {
+		return "", "", &errorCatalogo{http.StatusConflict, fmt.Sprintf("Ya existe el %s", e)}
+	} else if err != sql.ErrNoRows {
+		return "", "", err
+	}
+	if e.tipo == "localidad" {
+		_, activo, err := estadoEntidad(tx, entidadCatalogo{tipo: "municipio", municipio: e.municipio})
+		if err == sql.ErrNoRows || (err == nil && !activo) {
+			return "", "", invalido("El municipio %d no existe o está inactivo", e.municipio)
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if err := validarNombre(tx, e, d.Nombre); err != nil {
+		return "", "", err
+	}
+
+	var err error
+	if e.tipo == "municipio" {
+		// La clave INEGI es la que forma el nombre del PDF
+		clave := d.Clave
+		if clave == 0 && e.estado == estados.Predeterminado {
+			clave = e.municipio
+		}
+		if clave < 1 || clave > 999 {
+			return "", "", invalido("Falta la clave INEGI del municipio (1-999)")
+		}
+		var existe bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM municipios WHERE estado_id = ? AND clave = ?)",
+			e.estado, clave).Scan(&existe); err != nil {
+			return "", "", err
+		}
+		if existe {
+			return "", "", &errorCatalogo{http.StatusConflict,
+				fmt.Sprintf("Ya existe un municipio con la clave %03d en el estado %d", clave, e.estado)}
+		}
+		_, err = tx.Exec("INSERT INTO municipios (idmunicipios, estado_id, clave, nombre) VALUES (?, ?, ?, ?)",
+			e.municipio, e.estado, clave, d.Nombre)
+	} else {
+		_, err = tx.Exec("INSERT INTO localidades (idlocalidades, idmunicipio, nombre) VALUES (?, ?, ?)",
+			e.localidad, e.municipio, d.Nombre)
+	}
+	return "crear", fmt.Sprintf("%s creado como %q", e, d.Nombre), err
+}
+
+func renombrarEntidad(tx *sql.Tx, e entidadCatalogo, d datosCatalogo) (string, string, error) {
+	anterior, _, err := estadoEntidad(tx, e)
+	if err == sql.ErrNoRows {
+		return "", "", &errorCatalogo{http.StatusNotFound, fmt.Sprintf("No existe el %s", e)}
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if anterior == d.Nombre {
+		return "", "", invalido("El nombre no cambió")
+	}
+	if err := validarNombre(tx, e, d.Nombre); err != nil {
+		return "", "", err
+	}
+
+	fecha := time.Now()
+	if d.Fecha != "" {
+		if fecha, err = time.Parse("2006-01-02", d.Fecha); err != nil {
+			return "", "", invalido("fecha inválida, se espera AAAA-MM-DD")
+		}
+	}
+
+	// El nombre anterior rigió hasta el día en que entra el nuevo
+	if anterior != "" {
+		if _, err := tx.Exec(`
+			INSERT INTO nombres_historicos (tipo, municipio_id, localidad_id, nombre, vigente_hasta)
+			VALUES (?, ?, ?, ?, ?)`,
+			e.tipo, e.municipio, e.localidad, anterior, fecha.Format("2006-01-02")); err != nil {
+			return "", "", err
+		}
+	}
+	where, args := e.condicion()
+	if _, err := tx.Exec("UPDATE "+e.tabla()+" SET nombre = ? WHERE "+where,
+		append([]interface{}{d.Nombre}, args...)...); err != nil {
+		return "", "", err
+	}
+	return "renombrar", fmt.Sprintf("%s renombrado de %q a %q desde %s",
+		e, anterior, d.Nombre, fecha.Format("2006-01-02")), nil
+}
+
+func fusionarEntidad(tx *sql.Tx, e entidadCatalogo, d datosCatalogo) (string, string, error) {
+	if d.DestinoID < 1 {
+		return "", "", invalido("Falta destino_id")
+	}
+	destino := e.conClave(d.DestinoID)
+	if destino == e {
+		return "", "", invalido("No se puede fusionar un registro consigo mismo")
+	}
+
+	_, activo, err := estadoEntidad(tx, e)
+	if err == sql.ErrNoRows {
+		return "", "", &errorCatalogo{http.StatusNotFound, fmt.Sprintf("No existe el %s", e)}
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if !activo {
+		return "", "", invalido("El %s ya está inactivo", e)
+	}
+	_, destinoActivo, err := estadoEntidad(tx, destino)
+	if err == sql.ErrNoRows || (err == nil && !destinoActivo) {
+		return "", "", invalido("El %s no existe o está inactivo", destino)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if e.tipo == "municipio" {
+		var mismoEstado bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM municipios WHERE idmunicipios = ? AND estado_id = ?)",
+			d.DestinoID, e.estado).Scan(&mismoEstado); err != nil {
+			return "", "", err
+		}
+		if !mismoEstado {
+			return "", "", invalido("Solo se puede fusionar con un municipio del mismo estado")
+		}
+	}
+
+	where, args := e.condicion()
+	if _, err := tx.Exec("UPDATE "+e.tabla()+" SET activo = 0, fusionado_en = ? WHERE "+where,
+		append([]interface{}{d.DestinoID}, args...)...); err != nil {
+		return "", "", err
+	}
+	return "fusionar", fmt.Sprintf("%s fusionado en el %s", e, destino), nil
+}
+
+func cambiarEstadoEntidad(tx *sql.Tx, e entidadCatalogo, d datosCatalogo) (string, string, error) {
+	_, activo, err := estadoEntidad(tx, e)
+	if err == sql.ErrNoRows {
+		return "", "", &errorCatalogo{http.StatusNotFound, fmt.Sprintf("No existe el %s", e)}
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if activo == d.Activo {
+		return "", "", invalido("El %s ya tiene ese estado", e)
+	}
+	if e.tipo == "localidad" && d.Activo {
+		_, municipioActivo, err := estadoEntidad(tx, entidadCatalogo{tipo: "municipio", municipio: e.municipio})
+		if err != nil {
+			return "", "", err
+		}
+		if !municipioActivo {
+			return "", "", invalido("El municipio %d está inactivo", e.municipio)
+		}
+	}
+
+	// Reactivar deshace una fusión anterior
+	where, args := e.condicion()
+	if _, err := tx.Exec("UPDATE "+e.tabla()+" SET activo = ?, fusionado_en = NULL WHERE "+where,
+		append([]interface{}{d.Activo}, args...)...); err != nil {
+		return "", "", err
+	}
+	if d.Activo {
+		return "activar", fmt.Sprintf("%s activado", e), nil
+	}
+	return "desactivar", fmt.Sprintf("%s desactivado", e), nil
+}
+
+// ListarCambiosCatalogo devuelve la bitácora de cambios al catálogo, los más
+// recientes primero. Filtros opcionales: municipio_id, limite (máx. 500).
+func ListarCambiosCatalogo(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	where := ""
+	var args []interface{}
+	if v := query.Get("municipio_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "municipio_id inválido", http.StatusBadRequest)
+			return
+		}
+		where = "WHERE c.municipio_id = ?"
+		args = append(args, id)
+	}
+
+	limite := 100
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 500 {
+		limite = 500
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT c.id, c.usuario_id, u.username, c.tipo, c.accion, c.municipio_id, c.localidad_id,
+			c.detalle, c.creado_en
+		FROM catalogo_cambios c
+		JOIN usuarios u ON c.usuario_id = u.id
+		`+where+`
+		ORDER BY c.id DESC
+		LIMIT ?`, append(args, limite)...)
+	if err != nil {
+		http.Error(w, "Error consultando cambios", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	cambios := []models.CambioCatalogo{}
+	for rows.Next() {
+		var c models.CambioCatalogo
+		if err := rows.Scan(&c.ID, &c.UsuarioID, &c.Username, &c.Tipo, &c.Accion, &c.MunicipioID,
+			&c.LocalidadID, &c.Detalle, &c.CreadoEn); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		cambios = append(cambios, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cambios)
+}
+
+// nombreVigenteSQL devuelve una expresión SQL con el nombre que tenía la
+// entidad en la fecha dada: el primer nombre histórico que rigió después de
+// esa fecha o, si no lo hay, el actual
+func nombreVigenteSQL(tipo, municipio, localidad, fecha, actual string) string {
+	return `COALESCE((SELECT h.nombre FROM nombres_historicos h
+		WHERE h.tipo = '` + tipo + `' AND h.municipio_id = ` + municipio + `
+			AND h.localidad_id = ` + localidad + ` AND h.vigente_hasta > ` + fecha + `
+		ORDER BY h.vigente_hasta LIMIT 1), ` + actual + `, '')`
+}
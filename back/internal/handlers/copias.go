@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/models"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Textos de las copias certificadas cuando la configuración no trae otros.
+// Admiten {folio}, {codigo} (de verificación), {oficial}, {fecha}, {acto},
+// {municipio}, {oficialia}, {localidad}, {anio}, {acta} y {url} (dirección
+// de verificación, ya con el código).
+const (
+	encabezadoCopia = "COPIA CERTIFICADA - Acta de {acto} núm. {acta} de {anio}, {municipio}, oficialía {oficialia}"
+	pieCopia        = "Folio {folio} - Código {codigo} - Expide: {oficial} - {fecha} - Verifique en {url}"
+)
+
+// limiteVerificacion cuenta las consultas públicas a /verificar por IP
+var limiteVerificacion limitadorIP
+
+// EmitirCopia genera la copia certificada de un acta: el PDF original con
+// encabezado, pie y un código QR que apunta a /verificar/{folio}?c={codigo}.
+// Requiere el permiso emitir_copias y, salvo a los administradores, que el
+// municipio esté asignado al usuario. Recibe los mismos parámetros que
+// /api/pdf y opcionalmente {"oficial": "..."}; sin él firma el titular
+// registrado de la oficialía.
+//
+// El folio es el consecutivo de la oficialía; se toma en la misma
+// transacción que registra la copia, así que si falla la generación del PDF
+// no se pierde ningún número. El SHA-256 del PDF entregado se guarda con el
+// registro para que quien verifique pueda comparar su archivo. Sin
+// CLAVE_VERIFICACION responde 503.
+func EmitirCopia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	if !copiasHabilitadas(w) {
+		return
+	}
+	claims := auth.GetClaims(r)
+	permitido, err := auth.TienePermiso(claims, auth.PermisoEmitirCopias)
+	if err != nil {
+		http.Error(w, "Error consultando permisos", http.StatusInternalServerError)
+		return
+	}
+	if !permitido {
+		http.Error(w, "No tiene permiso para emitir copias certificadas", http.StatusForbidden)
+		return
+	}
+
+	var datos struct {
+		Oficial string `json:"oficial"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&datos); err != nil && err != io.EOF {
+			http.Error(w, "Datos inválidos", http.StatusBadRequest)
+			return
+		}
+	}
+
+	visualizacion, acta, archivo, ok := ubicarActa(w, r, claims.UserID)
+	if !ok || !exigirMunicipio(w, claims, visualizacion.Municipio) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Error iniciando transacción", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Bloquear la oficialía mientras se toma su siguiente folio
+	var oficialiaID, ultimo int
+	var titular, municipioNombre string
+	err = tx.QueryRow(`
+		SELECT o.id, o.oficial, o.ultimo_folio, m.nombre
+		FROM oficialias o
+		JOIN municipios m ON o.municipio_id = m.idmunicipios
+		WHERE o.municipio_id = ? AND o.numero = ?
+		FOR UPDATE`, visualizacion.Municipio, visualizacion.Oficialia).Scan(&oficialiaID, &titular, &ultimo, &municipioNombre)
+	if err == sql.ErrNoRows {
+		// El folio consecutivo vive en el catálogo de oficialías
+		http.Error(w, "La oficialía no está en el catálogo; importa las oficialías del municipio para emitir copias", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error consultando oficialía", http.StatusInternalServerError)
+		return
+	}
+	oficial := strings.TrimSpace(datos.Oficial)
+	if oficial == "" {
+		oficial = titular
+	}
+	if oficial == "" {
+		http.Error(w, "Indica el oficial que expide la copia (la oficialía no tiene titular registrado)", http.StatusBadRequest)
+		return
+	}
+
+	consecutivo := ultimo + 1
+	folio := fmt.Sprintf("%02d%03d%02d-%06d", acta.Estado, acta.Municipio, acta.Oficialia, consecutivo)
+	emitida := time.Now()
+	if _, err := tx.Exec("UPDATE oficialias SET ultimo_folio = ? WHERE id = ?", consecutivo, oficialiaID); err != nil {
+		http.Error(w, "Error asignando folio", http.StatusInternalServerError)
+		return
+	}
+
+	codigo := codigoVerificacion(folio)
+	urlVerificacion := strings.TrimRight(Cfg.URLPublica, "/") + "/verificar/" + folio + "?c=" + codigo
+	qr, err := qrcode.Encode(urlVerificacion, qrcode.Medium, 256)
+	if err != nil {
+		http.Error(w, "Error generando código QR", http.StatusInternalServerError)
+		return
+	}
+	textos := strings.NewReplacer(
+		"{folio}", folio,
+		"{codigo}", codigo,
+		"{oficial}", oficial,
+		"{fecha}", emitida.Format("02/01/2006 15:04"),
+		"{acto}", visualizacion.ActoNombre,
+		"{municipio}", municipioNombre,
+		"{oficialia}", strconv.Itoa(visualizacion.Oficialia),
+		"{localidad}", strconv.Itoa(visualizacion.Localidad),
+		"{anio}", strconv.Itoa(visualizacion.Anio),
+		"{acta}", strconv.Itoa(visualizacion.NumActa),
+		"{url}", urlVerificacion,
+	)
+	encabezado, pie := Cfg.CopiaEncabezado, Cfg.CopiaPie
+	if encabezado == "" {
+		encabezado = encabezadoCopia
+	}
+	if pie == "" {
+		pie = pieCopia
+	}
+
+	pdf, err := archivo.Almacen.Abrir(archivo.Relativa)
+	if err != nil {
+		http.Error(w, "Error leyendo el PDF del acta", http.StatusBadGateway)
+		return
+	}
+	defer pdf.Close()
+	copia, status, err := certificarPDF(r.Context(), pdf, qr, textos.Replace(encabezado), textos.Replace(pie))
+	if err != nil {
+		http.Error(w, "Error llamando microservicio", http.StatusInternalServerError)
+		return
+	}
+	if status != http.StatusOK {
+		// Los errores del microservicio se reenvían tal cual; el folio no se usa
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(copia)
+		return
+	}
+
+	hash := sha256.Sum256(copia)
+	if _, err := tx.Exec(`
+		INSERT INTO copias_certificadas (folio, oficialia_id, consecutivo, acto, municipio_id, oficialia, localidad,
+			anio, num_acta, oficial, sha256, usuario_id, emitida_en)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		folio, oficialiaID, consecutivo, visualizacion.Acto, visualizacion.Municipio, visualizacion.Oficialia,
+		visualizacion.Localidad, visualizacion.Anio, visualizacion.NumActa, oficial, hex.EncodeToString(hash[:]),
+		claims.UserID, emitida); err != nil {
+		http.Error(w, "Error registrando copia: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Error guardando copia", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "copia-" + folio + ".pdf"}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Folio", folio)
+	w.Header().Set("X-Codigo-Verificacion", codigo)
+	w.Write(copia)
+}
+
+// clienteCertificacion llama al microservicio mientras EmitirCopia tiene
+// bloqueada la fila de la oficialía; el límite evita que un microservicio
+// colgado detenga todas las copias de esa oficialía y retenga la conexión a
+// la BD
+var clienteCertificacion = &http.Client{Timeout: 2 * time.Minute}
+
+// certificarPDF envía el PDF, el QR y los textos al microservicio y
+// devuelve la respuesta con su código HTTP. Se cancela si ctx termina (el
+// cliente cerró la conexión) o si pasa el límite de clienteCertificacion.
+func certificarPDF(ctx context.Context, pdf io.Reader, qr []byte, encabezado, pie string) ([]byte, int, error) {
+	var cuerpo bytes.Buffer
+	mw := multipart.NewWriter(&cuerpo)
+	mw.WriteField("encabezado", encabezado)
+	mw.WriteField("pie", pie)
+	parte, err := mw.CreateFormFile("pdf", "acta.pdf")
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := io.Copy(parte, pdf); err != nil {
+		return nil, 0, err
+	}
+	parte, err = mw.CreateFormFile("qr", "qr.png")
+	if err != nil {
+		return nil, 0, err
+	}
+	parte.Write(qr)
+	if err := mw.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost:5000/pdf_certificar", &cuerpo)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := clienteCertificacion.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
+
+// codigoVerificacion es el HMAC-SHA256 del folio con la clave de
+// verificación, recortado a 10 caracteres base32. Se imprime en la copia y
+// va en su QR; sin él no se puede consultar un folio, así que no basta con
+// recorrer los consecutivos para enumerar las copias emitidas.
+//
+// Solo se llama con CLAVE_VERIFICACION configurada (copiasHabilitadas): una
+// clave conocida permitiría calcular los códigos de cualquier folio.
+func codigoVerificacion(folio string) string {
+	mac := hmac.New(sha256.New, []byte(Cfg.ClaveVerificacion))
+	mac.Write([]byte(folio))
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(mac.Sum(nil))[:10]
+}
+
+// copiasHabilitadas responde 503 si falta CLAVE_VERIFICACION o si
+// URL_PUBLICA no es una dirección pública: sin ellas no se emiten copias
+// (el QR impreso no llevaría a ninguna parte y el folio ya se habría
+// consumido) ni se verifican folios
+func copiasHabilitadas(w http.ResponseWriter) bool {
+	if Cfg.ClaveVerificacion == "" {
+		http.Error(w, "Copias certificadas deshabilitadas: falta configurar CLAVE_VERIFICACION",
+			http.StatusServiceUnavailable)
+		return false
+	}
+	if !URLPublicaValida(Cfg.URLPublica) {
+		http.Error(w, "Copias certificadas deshabilitadas: URL_PUBLICA debe ser la dirección pública del visor",
+			http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// URLPublicaValida indica si la dirección es http(s) y no apunta al propio
+// equipo (localhost o una IP de loopback o sin especificar)
+func URLPublicaValida(direccion string) bool {
+	u, err := url.Parse(direccion)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsUnspecified()) {
+		return false
+	}
+	return true
+}
+
+// codigoValido compara el código recibido con el del folio; acepta
+// minúsculas, espacios y guiones por si se captura a mano
+func codigoValido(folio, codigo string) bool {
+	codigo = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(codigo))
+	return hmac.Equal([]byte(codigo), []byte(codigoVerificacion(folio)))
+}
+
+// VerificarCopia es la página pública a la que apunta el QR de las copias
+// certificadas (/verificar/{folio}?c={codigo}). No requiere sesión: confirma
+// el folio, el acta, quién y cuándo la expidió y el SHA-256 del PDF emitido,
+// sin mostrar el contenido del acta. Una copia revocada responde con
+// valida=false y los datos de la revocación.
+//
+// Un código incorrecto responde igual que un folio inexistente, y cada IP
+// tiene un máximo de consultas por minuto (limiteVerificacion). Sin
+// CLAVE_VERIFICACION responde 503.
+func VerificarCopia(w http.ResponseWriter, r *http.Request) {
+	if !copiasHabilitadas(w) {
+		return
+	}
+	if espera := limiteVerificacion.permitir(clientIP(r), Cfg.LimiteVerificacion, time.Minute); espera > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(espera.Seconds())+1))
+		http.Error(w, "Demasiadas consultas, intente más tarde", http.StatusTooManyRequests)
+		return
+	}
+
+	folio := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/verificar/"))
+	if folio == "" || strings.Contains(folio, "/") {
+		http.Error(w, "Folio inválido", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	noEncontrada := func() {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(models.VerificacionCopia{Folio: folio})
+	}
+	if !codigoValido(folio, r.URL.Query().Get("c")) {
+		noEncontrada()
+		return
+	}
+
+	v := models.VerificacionCopia{Folio: folio}
+	var hash, motivo sql.NullString
+	var revocadaEn sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT c.acto, COALESCE(ac.nombre, ''), m.nombre, c.oficialia, c.anio, c.num_acta, c.oficial, c.emitida_en,
+			c.sha256, c.revocada_en, c.motivo_revocacion
+		FROM copias_certificadas c
+		JOIN municipios m ON c.municipio_id = m.idmunicipios
+		LEFT JOIN actos ac ON c.acto = ac.codigo
+		WHERE c.folio = ?`, folio).Scan(&v.Acto, &v.ActoNombre, &v.Municipio, &v.Oficialia, &v.Anio, &v.NumActa,
+		&v.Oficial, &v.EmitidaEn, &hash, &revocadaEn, &motivo)
+	if err == sql.ErrNoRows {
+		noEncontrada()
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error consultando folio", http.StatusInternalServerError)
+		return
+	}
+	v.SHA256 = hash.String
+	v.MotivoRevocacion = motivo.String
+	if revocadaEn.Valid {
+		v.Revocada = true
+		v.RevocadaEn = &revocadaEn.Time
+	}
+	v.Valida = !v.Revocada
+	json.NewEncoder(w).Encode(v)
+}
+
+// ListarCopias devuelve las copias emitidas, con su código de verificación
+// para poder darlo a quien llame a confirmar una copia. Filtros: folio,
+// usuario_id, municipio_id, revocadas=1 y limite.
+func ListarCopias(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var condiciones []string
+	var args []interface{}
+	if v := query.Get("folio"); v != "" {
+		condiciones = append(condiciones, "c.folio = ?")
+		args = append(args, strings.TrimSpace(v))
+	}
+	for _, filtro := range []struct{ param, columna string }{
+		{"usuario_id", "c.usuario_id"},
+		{"municipio_id", "c.municipio_id"},
+	} {
+		v := query.Get(filtro.param)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, filtro.param+" inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, filtro.columna+" = ?")
+		args = append(args, id)
+	}
+	if query.Get("revocadas") == "1" {
+		condiciones = append(condiciones, "c.revocada_en IS NOT NULL")
+	}
+	where := ""
+	if len(condiciones) > 0 {
+		where = "WHERE " + strings.Join(condiciones, " AND ")
+	}
+
+	limite := 100
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 500 {
+		limite = 500
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT c.id, c.folio, c.acto, COALESCE(ac.nombre, ''), c.municipio_id, c.oficialia, c.localidad, c.anio,
+			c.num_acta, c.oficial, c.usuario_id, u.username, c.emitida_en, c.sha256, c.revocada_en, c.revocada_por,
+			c.motivo_revocacion
+		FROM copias_certificadas c
+		JOIN usuarios u ON c.usuario_id = u.id
+		LEFT JOIN actos ac ON c.acto = ac.codigo
+		`+where+`
+		ORDER BY c.id DESC
+		LIMIT ?`, append(args, limite)...)
+	if err != nil {
+		http.Error(w, "Error consultando copias", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	copias := []models.CopiaCertificada{}
+	for rows.Next() {
+		var c models.CopiaCertificada
+		var hash, motivo sql.NullString
+		var revocadaEn sql.NullTime
+		var revocadaPor sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.Folio, &c.Acto, &c.ActoNombre, &c.Municipio, &c.Oficialia, &c.Localidad,
+			&c.Anio, &c.NumActa, &c.Oficial, &c.UsuarioID, &c.Username, &c.EmitidaEn, &hash, &revocadaEn,
+			&revocadaPor, &motivo); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if Cfg.ClaveVerificacion != "" {
+			c.Codigo = codigoVerificacion(c.Folio)
+		}
+		c.SHA256 = hash.String
+		c.MotivoRevocacion = motivo.String
+		c.RevocadaPor = enteroNulo(revocadaPor)
+		if revocadaEn.Valid {
+			c.RevocadaEn = &revocadaEn.Time
+		}
+		copias = append(copias, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(copias)
+}
+
+// RevocarCopia marca una copia como revocada; la verificación pública la
+// sigue mostrando, pero como no válida y con el motivo
+func RevocarCopia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	var datos struct {
+		Folio  string `json:"folio"`
+		Motivo string `json:"motivo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || strings.TrimSpace(datos.Folio) == "" {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	motivo := strings.TrimSpace(datos.Motivo)
+	if motivo == "" {
+		http.Error(w, "Indica el motivo de la revocación", http.StatusBadRequest)
+		return
+	}
+
+	claims := auth.GetClaims(r)
+	result, err := database.DB.Exec(`
+		UPDATE copias_certificadas
+		SET revocada_en = NOW(), revocada_por = ?, motivo_revocacion = ?
+		WHERE folio = ? AND revocada_en IS NULL`, claims.UserID, motivo, strings.TrimSpace(datos.Folio))
+	if err != nil {
+		http.Error(w, "Error revocando copia", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Copia no encontrada o ya revocada", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Copia revocada exitosamente"})
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import "testing"
+
+func TestURLPublicaValida(t *testing.T) {
+	casos := []struct {
+		url    string
+		valida bool
+	}{
+		{"https://visor.registrocivil.oaxaca.gob.mx", true},
+		{"http://172.19.2.220:8080/", true},
+		{"", false},
+		{"http://localhost:8080", false},
+		{"https://LOCALHOST", false},
+		{"http://visor.localhost", false},
+		{"http://127.0.0.1:8080", false},
+		{"http://[::1]:8080", false},
+		{"http://0.0.0.0", false},
+		{"visor.gob.mx/verificar", false},
+		{"ftp://visor.gob.mx", false},
+	}
+	for _, c := range casos {
+		if got := URLPublicaValida(c.url); got != c.valida {
+			t.Errorf("URLPublicaValida(%q) = %v, se esperaba %v", c.url, got, c.valida)
+		}
+	}
+}
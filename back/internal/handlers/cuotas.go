@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/models"
+)
+
+// ListarCuotas devuelve las cuotas configuradas por rol y por usuario
+func ListarCuotas(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query(`
+		SELECT c.id, c.rol_id, r.nombre, c.usuario_id, u.username, c.max_por_hora, c.max_por_dia
+		FROM cuotas c
+		LEFT JOIN roles r ON c.rol_id = r.id
+		LEFT JOIN usuarios u ON c.usuario_id = u.id
+		ORDER BY c.usuario_id IS NOT NULL, r.nombre, u.username`)
+	if err != nil {
+		http.Error(w, "Error consultando cuotas", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	cuotas := []models.Cuota{}
+	for rows.Next() {
+		var c models.Cuota
+		var rolID, usuarioID, porHora, porDia sql.NullInt64
+		var rolNombre, username sql.NullString
+		if err := rows.Scan(&c.ID, &rolID, &rolNombre, &usuarioID, &username, &porHora, &porDia); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c.RolID = enteroNulo(rolID)
+		c.UsuarioID = enteroNulo(usuarioID)
+		c.MaxPorHora = enteroNulo(porHora)
+		c.MaxPorDia = enteroNulo(porDia)
+		c.RolNombre = rolNombre.String
+		c.Username = username.String
+		cuotas = append(cuotas, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cuotas)
+}
+
+// GuardarCuota crea o reemplaza la cuota de un rol o de un usuario.
+// Un límite en null significa "sin límite" para ese periodo.
+func GuardarCuota(w http.ResponseWriter, r *http.Request) {
+	var c models.Cuota
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	if (c.RolID == nil) == (c.UsuarioID == nil) {
+		http.Error(w, "Indica rol_id o usuario_id (solo uno)", http.StatusBadRequest)
+		return
+	}
+	if (c.MaxPorHora != nil && *c.MaxPorHora < 0) || (c.MaxPorDia != nil && *c.MaxPorDia < 0) {
+		http.Error(w, "Los límites no pueden ser negativos", http.StatusBadRequest)
+		return
+	}
+
+	_, err := database.DB.Exec(`
+		INSERT INTO cuotas (rol_id, usuario_id, max_por_hora, max_por_dia) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE max_por_hora = VALUES(max_por_hora), max_por_dia = VALUES(max_por_dia)`,
+		c.RolID, c.UsuarioID, c.MaxPorHora, c.MaxPorDia)
+	if err != nil {
+		http.Error(w, "Error guardando cuota: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Cuota guardada exitosamente"})
+}
+
+// EliminarCuota borra una cuota; el usuario vuelve a usar la de su rol
+func EliminarCuota(w http.ResponseWriter, r *http.Request) {
+	var datos struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || datos.ID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := database.DB.Exec("DELETE FROM cuotas WHERE id = ?", datos.ID); err != nil {
+		http.Error(w, "Error eliminando cuota", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Cuota eliminada exitosamente"})
+}
+
+// enteroNulo convierte un entero nullable de la BD en puntero para JSON
+func enteroNulo(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
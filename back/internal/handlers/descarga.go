@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/almacen"
+	"visor-pdf/internal/auditoria"
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/rutas"
+)
+
+// DescargarPDF entrega el PDF original del acta. Requiere el permiso
+// descarga_original y, salvo a los administradores, que el municipio esté
+// asignado al usuario. Mismos parámetros que /api/pdf más sello=1, que
+// agrega a cada página un pie con el usuario, la fecha y el código de la
+// descarga, y el parámetro filtros de /api/pdf, que entrega las páginas
+// filtradas a 300 DPI como PDF de imágenes.
+//
+// Sin sello ni filtros admite rangos (Range/If-Range) y peticiones condicionales con
+// ETag y Last-Modified; el PDF sellado o filtrado se genera en cada
+// petición y se entrega completo. Cada descarga queda en la bitácora: los
+// rangos seguidos del mismo archivo cuentan como una sola
+// (auditoria.RegistrarDescarga) y las revalidaciones contestadas con 304 no
+// se registran.
+func DescargarPDF(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetClaims(r)
+	permitido, err := auth.TienePermiso(claims, auth.PermisoDescargaOriginal)
+	if err != nil {
+		http.Error(w, "Error consultando permisos", http.StatusInternalServerError)
+		return
+	}
+	if !permitido {
+		http.Error(w, "No tiene permiso para descargar el PDF original", http.StatusForbidden)
+		return
+	}
+
+	filtros, ok := filtrosDePeticion(w, r)
+	if !ok {
+		return
+	}
+	visualizacion, acta, archivo, ok := ubicarActa(w, r, claims.UserID)
+	if !ok || !exigirMunicipio(w, claims, visualizacion.Municipio) {
+		return
+	}
+
+	info, err := archivo.Almacen.Info(archivo.Relativa)
+	if err != nil {
+		// El PDF pudo moverse después de guardarse su ubicación en caché
+		rutas.Olvidar(acta)
+		http.Error(w, "No se encontró el PDF del acta", http.StatusNotFound)
+		return
+	}
+
+	// El PDF filtrado se genera antes de registrar, para no dejar en la
+	// bitácora descargas que no se entregaron
+	var filtrado []byte
+	if len(filtros) > 0 {
+		filtrado, err = pdfFiltrado(archivo, info, filtros)
+		if !responderErrorPaginas(w, err) {
+			return
+		}
+	}
+
+	sellado := r.URL.Query().Get("sello") == "1"
+	descarga := auditoria.Descarga{
+		Visualizacion: visualizacion,
+		Raiz:          archivo.Raiz,
+		Ruta:          archivo.Relativa,
+		Sellado:       sellado,
+		Filtros:       filtros.String(),
+	}
+	// Mismo esquema que los servidores web comunes: cambia si cambia el archivo
+	etag := fmt.Sprintf(`"%x-%x"`, info.Modificado.Unix(), info.Tamano)
+	original := !sellado && filtrado == nil
+	if original {
+		descarga.Rango = r.Header.Get("Range")
+	}
+	// Una revalidación que se contesta con 304 no entrega el archivo
+	var descargaID int64
+	if !original || !respondera304(r, etag, info.Modificado) {
+		descargaID, err = auditoria.RegistrarDescarga(descarga)
+		if err != nil {
+			http.Error(w, "Error registrando descarga", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	nombre := path.Base(archivo.Relativa)
+	if !sellado && filtrado != nil {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": nombre}))
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("Accept-Ranges", "none")
+		w.Header().Set("Content-Length", strconv.Itoa(len(filtrado)))
+		w.Write(filtrado)
+		return
+	}
+	if !sellado {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": nombre}))
+		w.Header().Set("Cache-Control", "private, no-cache")
+		w.Header().Set("ETag", etag)
+
+		lector := almacen.NuevoLector(archivo.Almacen, archivo.Relativa, info.Tamano)
+		defer lector.Close()
+		http.ServeContent(w, r, nombre, info.Modificado, lector)
+		return
+	}
+
+	codigo := auditoria.CodigoDescarga(descargaID)
+	pie := fmt.Sprintf("Descargado por %s el %s - Código de verificación %s",
+		claims.Username, time.Now().Format("02/01/2006 15:04"), codigo)
+	var pdf io.Reader = bytes.NewReader(filtrado)
+	if filtrado == nil {
+		original, err := archivo.Almacen.Abrir(archivo.Relativa)
+		if err != nil {
+			http.Error(w, "Error leyendo el PDF del acta", http.StatusBadGateway)
+			return
+		}
+		defer original.Close()
+		pdf = original
+	}
+
+	resp, err := http.Post("http://localhost:5000/pdf_sellar?texto="+url.QueryEscape(pie), "application/pdf", pdf)
+	if err != nil {
+		http.Error(w, "Error llamando microservicio", http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		w.Write(body)
+		return
+	}
+
+	// El contenido lleva datos del usuario: no se guarda en cachés ni admite rangos
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": nombre}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("Accept-Ranges", "none")
+	w.Header().Set("X-Codigo-Verificacion", codigo)
+	if resp.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	io.Copy(w, resp.Body)
+}
+
+// respondera304 indica si http.ServeContent contestará 304 Not Modified:
+// If-None-Match con el ETag del archivo o, sin él, If-Modified-Since no
+// anterior a la fecha de modificación (RFC 7232)
+func respondera304(r *http.Request, etag string, modificado time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, e := range strings.Split(inm, ",") {
+			e = strings.TrimPrefix(strings.TrimSpace(e), "W/")
+			if e == "*" || e == etag {
+				return true
+			}
+		}
+		return false
+	}
+	t, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modificado.IsZero() || modificado.Unix() == 0 {
+		return false
+	}
+	return !modificado.Truncate(time.Second).After(t)
+}
+
+// municipioAsignado indica si el municipio está entre los del usuario
+// (directos o por distrito, región o estado)
+func municipioAsignado(usuarioID, municipioID int) (bool, error) {
+	var existe bool
+	err := database.DB.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM v_usuario_municipios WHERE usuario_id = ? AND municipio_id = ?
+		)`, usuarioID, municipioID).Scan(&existe)
+	return existe, err
+}
+
+// exigirMunicipio responde 403, salvo a los administradores, si el
+// municipio no está asignado al usuario
+func exigirMunicipio(w http.ResponseWriter, claims *auth.Claims, municipioID int) bool {
+	if claims.EsAdmin() {
+		return true
+	}
+	asignado, err := municipioAsignado(claims.UserID, municipioID)
+	if err != nil {
+		http.Error(w, "Error consultando permisos", http.StatusInternalServerError)
+		return false
+	}
+	if !asignado {
+		http.Error(w, "No tiene permisos para este municipio", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// respondera304 debe coincidir con lo que contesta http.ServeContent, que
+// es quien entrega el PDF original
+func TestRespondera304(t *testing.T) {
+	modificado := time.Date(2024, 3, 10, 12, 30, 45, 500, time.UTC)
+	etag := `"65eda8ad-400"`
+
+	casos := []struct {
+		nombre      string
+		metodo      string
+		encabezados map[string]string
+	}{
+		{"sin condiciones", http.MethodGet, nil},
+		{"etag igual", http.MethodGet, map[string]string{"If-None-Match": etag}},
+		{"etag débil", http.MethodGet, map[string]string{"If-None-Match": "W/" + etag}},
+		{"etag en lista", http.MethodGet, map[string]string{"If-None-Match": `"otro", ` + etag}},
+		{"etag comodín", http.MethodGet, map[string]string{"If-None-Match": "*"}},
+		{"etag distinto", http.MethodGet, map[string]string{"If-None-Match": `"otro"`}},
+		{"fecha igual", http.MethodGet, map[string]string{"If-Modified-Since": modificado.Format(http.TimeFormat)}},
+		{"fecha posterior", http.MethodGet, map[string]string{"If-Modified-Since": modificado.Add(time.Hour).Format(http.TimeFormat)}},
+		{"fecha anterior", http.MethodGet, map[string]string{"If-Modified-Since": modificado.Add(-time.Hour).Format(http.TimeFormat)}},
+		{"fecha inválida", http.MethodGet, map[string]string{"If-Modified-Since": "ayer"}},
+		{"etag manda sobre fecha", http.MethodGet, map[string]string{
+			"If-None-Match": `"otro"`, "If-Modified-Since": modificado.Format(http.TimeFormat)}},
+		{"rango con etag", http.MethodGet, map[string]string{"If-None-Match": etag, "Range": "bytes=0-9"}},
+		{"HEAD", http.MethodHead, map[string]string{"If-None-Match": etag}},
+		{"POST", http.MethodPost, map[string]string{"If-None-Match": etag}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			req := httptest.NewRequest(c.metodo, "/acta.pdf", nil)
+			for k, v := range c.encabezados {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			rec.Header().Set("ETag", etag)
+			http.ServeContent(rec, req, "acta.pdf", modificado, strings.NewReader(strings.Repeat("x", 1024)))
+
+			esperado := rec.Code == http.StatusNotModified
+			if got := respondera304(req, etag, modificado); got != esperado {
+				t.Errorf("respondera304 = %v, ServeContent respondió %d", got, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/almacen"
+	"visor-pdf/internal/auditoria"
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/imagen"
+	"visor-pdf/internal/models"
+	"visor-pdf/internal/rutas"
+)
+
+// Pirámide Deep Zoom: tiles de 256 píxeles con 1 de traslape, como los
+// genera la herramienta de referencia. Una visualización sirve tiles
+// mientras no venza, para no dejar URLs válidas indefinidamente.
+const (
+	tileDZI          = 256
+	traslapeDZI      = 1
+	vigenciaVistaDZI = 8 * time.Hour
+)
+
+// IniciarDZI abre un acta para verla como pirámide Deep Zoom. Recibe los
+// mismos parámetros que /api/pdf, cuenta contra la cuota y queda en la
+// bitácora igual que /api/pdf, y devuelve el código de la visualización
+// con la dirección del descriptor .dzi de cada página. Los tiles se
+// generan al pedirse, así que el cliente solo pide los visibles. Con
+// filtros, los descriptores ya llevan el parámetro. Exige, salvo a los
+// administradores, que el municipio esté asignado; los tiles (RecursoDZI)
+// solo se sirven al dueño de la visualización.
+func IniciarDZI(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetClaims(r)
+	filtros, ok := filtrosDePeticion(w, r)
+	if !ok {
+		return
+	}
+	visualizacion, acta, archivo, ok := ubicarActa(w, r, claims.UserID)
+	if !ok || !exigirMunicipio(w, claims, visualizacion.Municipio) {
+		return
+	}
+	info, ok := infoArchivo(w, acta, archivo)
+	if !ok {
+		return
+	}
+	// Las dimensiones de las páginas se leen antes de contar la visualización
+	pdf, err := infoPaginas(archivo, info)
+	if !responderErrorPaginas(w, err) {
+		return
+	}
+
+	vistaID, ok := registrarVista(w, claims, visualizacion)
+	if !ok {
+		return
+	}
+
+	codigo := auditoria.CodigoVista(vistaID)
+	consulta := ""
+	if len(filtros) > 0 {
+		consulta = "?filtros=" + url.QueryEscape(filtros.String())
+	}
+	vista := models.VistaDZI{Vista: codigo, Paginas: make([]models.PaginaDZI, 0, len(pdf.Dimensiones))}
+	for _, d := range pdf.Dimensiones {
+		p := imagen.NuevaPiramide(d.Ancho, d.Alto, zoomDZI(), tileDZI, traslapeDZI)
+		vista.Paginas = append(vista.Paginas, models.PaginaDZI{
+			Pagina:     d.Pagina,
+			Descriptor: fmt.Sprintf("/api/pdf/dzi/%s/%d.dzi%s", codigo, d.Pagina, consulta),
+			Ancho:      p.Ancho,
+			Alto:       p.Alto,
+			Niveles:    p.NivelMaximo() + 1,
+		})
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(vista)
+}
+
+// RecursoDZI sirve el descriptor y los tiles de una visualización abierta
+// con IniciarDZI, con las rutas de la convención Deep Zoom:
+//
+//	/api/pdf/dzi/{vista}/{pagina}.dzi
+//	/api/pdf/dzi/{vista}/{pagina}_files/{nivel}/{columna}_{fila}.png
+//
+// La visualización debe ser del usuario y no haber vencido. Los tiles
+// admiten el parámetro filtros de /api/pdf y se guardan en caché, ya
+// filtrados y sin marcas, por cadena de filtros; cada respuesta lleva la
+// marca de agua y la marca forense de esa visualización.
+func RecursoDZI(w http.ResponseWriter, r *http.Request) {
+	partes := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/pdf/dzi/"), "/")
+	var pagina, nivel, col, fila int
+	var err error
+	esTile := len(partes) == 4
+	switch {
+	case len(partes) == 2 && strings.HasSuffix(partes[1], ".dzi"):
+		pagina, err = strconv.Atoi(strings.TrimSuffix(partes[1], ".dzi"))
+	case esTile && strings.HasSuffix(partes[1], "_files") && strings.HasSuffix(partes[3], ".png"):
+		pagina, err = strconv.Atoi(strings.TrimSuffix(partes[1], "_files"))
+		if err == nil {
+			nivel, err = strconv.Atoi(partes[2])
+		}
+		if err == nil {
+			_, err = fmt.Sscanf(strings.TrimSuffix(partes[3], ".png"), "%d_%d", &col, &fila)
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil || pagina < 1 {
+		http.Error(w, "Ruta DZI inválida", http.StatusBadRequest)
+		return
+	}
+	filtros, ok := filtrosDePeticion(w, r)
+	if !ok {
+		return
+	}
+
+	claims := auth.GetClaims(r)
+	vistaID, err := auditoria.IDDesdeCodigo(partes[0])
+	if err != nil {
+		http.Error(w, "Código de visualización inválido", http.StatusBadRequest)
+		return
+	}
+	visualizacion, creada, err := auditoria.ObtenerVisualizacion(vistaID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Visualización no encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error consultando visualización", http.StatusInternalServerError)
+		return
+	}
+	if visualizacion.UsuarioID != claims.UserID {
+		http.Error(w, "La visualización es de otro usuario", http.StatusForbidden)
+		return
+	}
+	if time.Since(creada) > vigenciaVistaDZI {
+		http.Error(w, "La visualización venció; vuelva a abrir el acta", http.StatusGone)
+		return
+	}
+
+	acta, archivo, ok := ubicarActaRegistrada(w, visualizacion)
+	if !ok {
+		return
+	}
+	info, ok := infoArchivo(w, acta, archivo)
+	if !ok {
+		return
+	}
+	pdf, err := infoPaginas(archivo, info)
+	if !responderErrorPaginas(w, err) {
+		return
+	}
+	if pagina > pdf.Paginas {
+		http.Error(w, fmt.Sprintf("El acta tiene %d páginas", pdf.Paginas), http.StatusNotFound)
+		return
+	}
+	dim := pdf.Dimensiones[pagina-1]
+	piramide := imagen.NuevaPiramide(dim.Ancho, dim.Alto, zoomDZI(), tileDZI, traslapeDZI)
+
+	if !esTile {
+		descriptor, err := piramide.Descriptor("png")
+		if err != nil {
+			http.Error(w, "Error generando descriptor", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		servirPaginas(w, r, "pagina.dzi", info, descriptor)
+		return
+	}
+
+	rect, ok := piramide.RectTile(nivel, col, fila)
+	if !ok {
+		http.Error(w, "El tile no existe en la pirámide", http.StatusNotFound)
+		return
+	}
+	escala := piramide.Escala(nivel)
+	clave := fmt.Sprintf("dzi|%g|%d|%d|%d|%d|%s|%s", escala, pagina, nivel, col, fila, filtros, claveArchivo(archivo, info))
+	datos, err := cachePaginas.Generar(clave, func() ([]byte, error) {
+		if len(filtros) == 0 {
+			return llamarMicroservicio(archivo, info, fmt.Sprintf("/pdf_region?pagina=%d&escala=%g&x0=%d&y0=%d&x1=%d&y1=%d",
+				pagina, escala, rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y))
+		}
+		return tileFiltrado(archivo, info, dim, piramide, nivel, rect, filtros)
+	})
+	if !responderErrorPaginas(w, err) {
+		return
+	}
+	img, err := png.Decode(bytes.NewReader(datos))
+	if err != nil {
+		http.Error(w, "Respuesta inválida del microservicio", http.StatusBadGateway)
+		return
+	}
+
+	// Las mismas marcas que /api/pdf, alineadas con la posición del tile en el nivel
+	config, err := obtenerMarcaAgua(claims.RolID)
+	if err != nil {
+		http.Error(w, "Error consultando marca de agua", http.StatusInternalServerError)
+		return
+	}
+	marca := nuevaMarcaAgua(config, claims.Username, auditoria.CodigoVista(vistaID))
+	id := imagen.Identificador{UsuarioID: claims.UserID, VistaID: vistaID}
+	marcado := imagen.IncrustarForense(marca.Aplicar(ajustarTile(img, rect), rect.Min.X, rect.Min.Y),
+		rect.Min.X, rect.Min.Y, id)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, marcado); err != nil {
+		http.Error(w, "Error codificando tile", http.StatusInternalServerError)
+		return
+	}
+	// Cada tile lleva datos del usuario: no debe guardarse en cachés compartidas
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+// tileFiltrado renderiza el tile con el margen que piden los filtros, los
+// aplica con los valores medidos sobre la página completa y recorta el
+// tile, para que no se noten los bordes entre tiles vecinos
+func tileFiltrado(archivo rutas.Archivo, info almacen.Info, dim models.PaginaPDF, piramide imagen.Piramide,
+	nivel int, rect image.Rectangle, filtros imagen.Filtros) ([]byte, error) {
+	ref, err := referenciaPagina(archivo, info, dim, filtros)
+	if err != nil {
+		return nil, err
+	}
+	ancho, alto := piramide.Dimensiones(nivel)
+	escala := piramide.Escala(nivel)
+	pagina := image.Pt(ancho, alto)
+
+	margen := filtros.Margen(imagen.Region{Rect: rect, Pagina: pagina, Escala: escala}, ref)
+	ampliado := rect.Inset(-margen).Intersect(image.Rect(0, 0, ancho, alto))
+	img, err := renderRegion(archivo, info, dim.Pagina, escala, ampliado)
+	if err != nil {
+		return nil, err
+	}
+	filtrada, _ := filtros.Aplicar(ajustarTile(img, ampliado),
+		imagen.Region{Rect: ampliado, Pagina: pagina, Escala: escala}, &ref)
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, filtrada.SubImage(rect.Sub(ampliado.Min)))
+	return buf.Bytes(), err
+}
+
+// zoomDZI es el zoom del nivel más alto (DZI_ZOOM, 4 por omisión)
+func zoomDZI() float64 {
+	if Cfg.DZIZoom < 1 {
+		return 4
+	}
+	return float64(Cfg.DZIZoom)
+}
+
+// ajustarTile recorta o completa en blanco el render del microservicio al
+// tamaño exacto del tile, que puede diferir en un píxel por redondeo
+func ajustarTile(img image.Image, rect image.Rectangle) image.Image {
+	if img.Bounds().Dx() == rect.Dx() && img.Bounds().Dy() == rect.Dy() {
+		return img
+	}
+	ajustada := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(ajustada, ajustada.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(ajustada, ajustada.Bounds(), img, img.Bounds().Min, draw.Src)
+	return ajustada
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+
+	"visor-pdf/internal/almacen"
+	"visor-pdf/internal/imagen"
+	"visor-pdf/internal/models"
+	"visor-pdf/internal/rutas"
+)
+
+// Resoluciones de los renders que se filtran completos: la vista general de
+// la que se miden contraste e inclinación para los tiles DZI, y las páginas
+// de las descargas con filtros (300 DPI)
+const (
+	ladoVistaGeneral = 1024
+	escalaDescarga   = 300.0 / 72
+	calidadDescarga  = 90
+)
+
+// filtrosDePeticion lee el parámetro filtros ("gris,contraste,gamma:1.8").
+// Si es inválido ya respondió al cliente.
+func filtrosDePeticion(w http.ResponseWriter, r *http.Request) (imagen.Filtros, bool) {
+	filtros, err := imagen.ParsearFiltros(r.URL.Query().Get("filtros"))
+	if err != nil {
+		http.Error(w, "filtros inválidos: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return filtros, true
+}
+
+// filtrarPagina aplica los filtros a una página renderizada completa
+func filtrarPagina(filtros imagen.Filtros, pagina image.Image, escala float64) *image.RGBA {
+	b := pagina.Bounds()
+	filtrada, _ := filtros.Aplicar(pagina, imagen.Region{
+		Rect: image.Rect(0, 0, b.Dx(), b.Dy()), Pagina: b.Size(), Escala: escala,
+	}, nil)
+	return filtrada
+}
+
+// renderRegion pide al microservicio un rectángulo de la página a escala
+// píxeles por punto y lo decodifica
+func renderRegion(archivo rutas.Archivo, info almacen.Info, pagina int, escala float64, rect image.Rectangle) (image.Image, error) {
+	datos, err := llamarMicroservicio(archivo, info, fmt.Sprintf("/pdf_region?pagina=%d&escala=%g&x0=%d&y0=%d&x1=%d&y1=%d",
+		pagina, escala, rect.Min.X, rect.Min.Y, rect.Max.X, rect.Max.Y))
+	if err != nil {
+		return nil, err
+	}
+	return png.Decode(bytes.NewReader(datos))
+}
+
+// referenciaPagina mide sobre una vista general de la página los valores
+// que los filtros necesitan de la página completa. Se guarda en caché por
+// archivo, página y cadena de filtros.
+func referenciaPagina(archivo rutas.Archivo, info almacen.Info, dim models.PaginaPDF, filtros imagen.Filtros) (imagen.Referencia, error) {
+	var ref imagen.Referencia
+	if !filtros.NecesitaReferencia() {
+		return ref, nil
+	}
+	clave := fmt.Sprintf("ref|%s|%d|%s", filtros, dim.Pagina, claveArchivo(archivo, info))
+	datos, err := cachePaginas.Generar(clave, func() ([]byte, error) {
+		escala := ladoVistaGeneral / math.Max(dim.Ancho, dim.Alto)
+		ancho, alto := int(math.Ceil(dim.Ancho*escala)), int(math.Ceil(dim.Alto*escala))
+		img, err := renderRegion(archivo, info, dim.Pagina, escala, image.Rect(0, 0, ancho, alto))
+		if err != nil {
+			return nil, err
+		}
+		b := img.Bounds()
+		_, medida := filtros.Aplicar(img, imagen.Region{
+			Rect: image.Rect(0, 0, b.Dx(), b.Dy()), Pagina: b.Size(), Escala: escala,
+		}, nil)
+		return json.Marshal(medida)
+	})
+	if err != nil {
+		return ref, err
+	}
+	err = json.Unmarshal(datos, &ref)
+	return ref, err
+}
+
+// pdfFiltrado renderiza cada página a 300 DPI, le aplica los filtros y arma
+// un PDF de imágenes con el mismo tamaño de página que el original
+func pdfFiltrado(archivo rutas.Archivo, info almacen.Info, filtros imagen.Filtros) ([]byte, error) {
+	pdf, err := infoPaginas(archivo, info)
+	if err != nil {
+		return nil, err
+	}
+	paginas := make([]imagen.PaginaImagen, 0, len(pdf.Dimensiones))
+	for _, dim := range pdf.Dimensiones {
+		ancho, alto := int(math.Ceil(dim.Ancho*escalaDescarga)), int(math.Ceil(dim.Alto*escalaDescarga))
+		img, err := renderRegion(archivo, info, dim.Pagina, escalaDescarga, image.Rect(0, 0, ancho, alto))
+		if err != nil {
+			return nil, err
+		}
+		filtrada := filtrarPagina(filtros, img, escalaDescarga)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, filtrada, &jpeg.Options{Quality: calidadDescarga}); err != nil {
+			return nil, err
+		}
+		b := filtrada.Bounds()
+		paginas = append(paginas, imagen.PaginaImagen{
+			JPEG: buf.Bytes(), Ancho: b.Dx(), Alto: b.Dy(), AnchoPt: dim.Ancho, AltoPt: dim.Alto,
+		})
+	}
+	return imagen.PDFDeImagenes(paginas), nil
+}
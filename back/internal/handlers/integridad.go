@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/integridad"
+	"visor-pdf/internal/models"
+)
+
+// EstadoIntegridad devuelve el resumen de la última verificación de PDFs y
+// cuántos archivos hay en cada estado
+func EstadoIntegridad(w http.ResponseWriter, r *http.Request) {
+	resumen, enCurso := integridad.Estado()
+
+	rows, err := database.DB.Query("SELECT estado, COUNT(*) FROM archivos_pdf GROUP BY estado")
+	if err != nil {
+		http.Error(w, "Error consultando archivos", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	archivos := map[string]int{}
+	for rows.Next() {
+		var estado string
+		var n int
+		if err := rows.Scan(&estado, &n); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		archivos[estado] = n
+	}
+
+	var pendientes int
+	if err := database.DB.QueryRow("SELECT COUNT(*) FROM incidencias_integridad WHERE revisada = 0").
+		Scan(&pendientes); err != nil {
+		http.Error(w, "Error consultando incidencias", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"en_curso":               enCurso,
+		"ultima_verificacion":    resumen,
+		"archivos":               archivos,
+		"incidencias_pendientes": pendientes,
+	})
+}
+
+// VerificarIntegridad lanza una verificación fuera del calendario
+func VerificarIntegridad(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, enCurso := integridad.Estado(); enCurso {
+		http.Error(w, integridad.ErrEnCurso.Error(), http.StatusConflict)
+		return
+	}
+	go integridad.Verificar()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Verificación iniciada"})
+}
+
+// ListarIncidencias lista las incidencias de integridad, las más recientes
+// primero. Filtros opcionales: revisada (0/1), tipo, limite.
+func ListarIncidencias(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var condiciones []string
+	var args []interface{}
+	if v := query.Get("revisada"); v != "" {
+		if v != "0" && v != "1" {
+			http.Error(w, "revisada debe ser 0 o 1", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "i.revisada = ?")
+		args = append(args, v == "1")
+	}
+	if v := query.Get("tipo"); v != "" {
+		if v != integridad.EstadoCambiado && v != integridad.EstadoFaltante && v != integridad.EstadoCorrupto {
+			http.Error(w, "tipo debe ser cambiado, faltante o corrupto", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "i.tipo = ?")
+		args = append(args, v)
+	}
+	where := ""
+	if len(condiciones) > 0 {
+		where = "WHERE " + strings.Join(condiciones, " AND ")
+	}
+
+	limite := 100
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 500 {
+		limite = 500
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT i.id, i.archivo_id, a.raiz, a.ruta, a.acto, a.municipio_id, a.anio, a.num_acta, i.tipo,
+			i.sha256_anterior, i.sha256_nuevo, a.estado, i.detectada_en, i.revisada, u.username,
+			i.revisada_en
+		FROM incidencias_integridad i
+		JOIN archivos_pdf a ON i.archivo_id = a.id
+		LEFT JOIN usuarios u ON i.revisada_por = u.id
+		`+where+`
+		ORDER BY i.id DESC
+		LIMIT ?`, append(args, limite)...)
+	if err != nil {
+		http.Error(w, "Error consultando incidencias", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	incidencias := []models.IncidenciaIntegridad{}
+	for rows.Next() {
+		var i models.IncidenciaIntegridad
+		var acto, anterior, nuevo, revisadaPor sql.NullString
+		var municipio, anio, numActa sql.NullInt64
+		var revisadaEn sql.NullTime
+		if err := rows.Scan(&i.ID, &i.ArchivoID, &i.Raiz, &i.Ruta, &acto, &municipio, &anio, &numActa, &i.Tipo,
+			&anterior, &nuevo, &i.EstadoArchivo, &i.DetectadaEn, &i.Revisada, &revisadaPor,
+			&revisadaEn); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		i.Acto = textoNulo(acto)
+		i.MunicipioID = enteroNulo(municipio)
+		i.Anio = enteroNulo(anio)
+		i.NumActa = enteroNulo(numActa)
+		i.Sha256Anterior = textoNulo(anterior)
+		i.Sha256Nuevo = textoNulo(nuevo)
+		i.RevisadaPor = textoNulo(revisadaPor)
+		if revisadaEn.Valid {
+			i.RevisadaEn = &revisadaEn.Time
+		}
+		incidencias = append(incidencias, i)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(incidencias)
+}
+
+// RevisarIncidencia marca una incidencia como revisada. Con aceptar=true en
+// una incidencia de tipo cambiado, el contenido actual pasa a ser el de
+// referencia (por ejemplo, tras volver a digitalizar el acta).
+func RevisarIncidencia(w http.ResponseWriter, r *http.Request) {
+	var datos struct {
+		ID      int  `json:"id"`
+		Aceptar bool `json:"aceptar"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || datos.ID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Error iniciando transacción", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var archivoID int
+	var tipo string
+	err = tx.QueryRow("SELECT archivo_id, tipo FROM incidencias_integridad WHERE id = ? AND revisada = 0 FOR UPDATE",
+		datos.ID).Scan(&archivoID, &tipo)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Incidencia no encontrada o ya revisada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error consultando incidencia", http.StatusInternalServerError)
+		return
+	}
+	if datos.Aceptar && tipo != integridad.EstadoCambiado {
+		http.Error(w, "Solo se puede aceptar el contenido de un archivo cambiado", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE incidencias_integridad SET revisada = 1, revisada_por = ?, revisada_en = NOW()
+		WHERE id = ?`, auth.GetClaims(r).UserID, datos.ID); err != nil {
+		http.Error(w, "Error actualizando incidencia", http.StatusInternalServerError)
+		return
+	}
+	mensaje := "Incidencia marcada como revisada"
+	if datos.Aceptar {
+		if err := integridad.AceptarCambio(tx, archivoID); err != nil {
+			http.Error(w, "Error aceptando el cambio", http.StatusInternalServerError)
+			return
+		}
+		mensaje = "Incidencia revisada; el contenido actual es ahora el de referencia"
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Error guardando cambios", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": mensaje})
+}
+
+func textoNulo(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
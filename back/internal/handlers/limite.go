@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// limitadorIP cuenta peticiones por IP en ventanas fijas. Es para los
+// endpoints públicos, que no tienen usuario contra el cual llevar cuotas;
+// vive en memoria y se reinicia con el servidor.
+type limitadorIP struct {
+	mu       sync.Mutex
+	ventana  map[string]*ventanaIP
+	limpiado time.Time
+}
+
+type ventanaIP struct {
+	inicio time.Time
+	cuenta int
+}
+
+// permitir registra una petición de la IP y devuelve cuánto falta para que
+// se le permita otra, o 0 si esta se permite (maximo <= 0 desactiva el límite)
+func (l *limitadorIP) permitir(ip string, maximo int, periodo time.Duration) time.Duration {
+	if maximo <= 0 {
+		return 0
+	}
+	ahora := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ventana == nil {
+		l.ventana = make(map[string]*ventanaIP)
+	}
+	// Descartar las ventanas vencidas de vez en cuando para no acumular IPs
+	if ahora.Sub(l.limpiado) > periodo {
+		for clave, v := range l.ventana {
+			if ahora.Sub(v.inicio) >= periodo {
+				delete(l.ventana, clave)
+			}
+		}
+		l.limpiado = ahora
+	}
+
+	v, ok := l.ventana[ip]
+	if !ok || ahora.Sub(v.inicio) >= periodo {
+		l.ventana[ip] = &ventanaIP{inicio: ahora, cuenta: 1}
+		return 0
+	}
+	if v.cuenta >= maximo {
+		return v.inicio.Add(periodo).Sub(ahora)
+	}
+	v.cuenta++
+	return 0
+}
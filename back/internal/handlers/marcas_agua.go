@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"image/color"
+	"net/http"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/imagen"
+	"visor-pdf/internal/models"
+)
+
+// Configuración para los roles que no tienen fila en marcas_agua
+var marcaAguaPorDefecto = models.MarcaAgua{
+	Texto:    "{usuario} {fecha} {vista}",
+	Opacidad: 0.25,
+	Angulo:   30,
+}
+
+// obtenerMarcaAgua lee la configuración de la marca visible para un rol
+func obtenerMarcaAgua(rolID int) (models.MarcaAgua, error) {
+	m := marcaAguaPorDefecto
+	m.RolID = rolID
+	err := database.DB.QueryRow(
+		"SELECT texto, opacidad, angulo FROM marcas_agua WHERE rol_id = ?",
+		rolID).Scan(&m.Texto, &m.Opacidad, &m.Angulo)
+	if err == sql.ErrNoRows {
+		return m, nil
+	}
+	return m, err
+}
+
+// nuevaMarcaAgua sustituye los datos de la visualización en el texto configurado
+func nuevaMarcaAgua(m models.MarcaAgua, username, vista string) imagen.MarcaAgua {
+	texto := strings.NewReplacer(
+		"{usuario}", username,
+		"{fecha}", time.Now().Format("2006-01-02 15:04"),
+		"{vista}", vista,
+	).Replace(m.Texto)
+	return imagen.MarcaAgua{
+		Texto:    texto,
+		Opacidad: m.Opacidad,
+		Angulo:   m.Angulo,
+		Color:    color.RGBA{R: 90, G: 90, B: 90, A: 255},
+	}
+}
+
+// ListarMarcasAgua devuelve la configuración de la marca visible de cada rol
+func ListarMarcasAgua(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query(`
+		SELECT r.id, r.nombre, m.texto, m.opacidad, m.angulo
+		FROM roles r
+		LEFT JOIN marcas_agua m ON m.rol_id = r.id
+		ORDER BY r.id`)
+	if err != nil {
+		http.Error(w, "Error consultando marcas de agua", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	marcas := []models.MarcaAgua{}
+	for rows.Next() {
+		m := marcaAguaPorDefecto
+		var texto sql.NullString
+		var opacidad, angulo sql.NullFloat64
+		if err := rows.Scan(&m.RolID, &m.RolNombre, &texto, &opacidad, &angulo); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if texto.Valid {
+			m.Texto, m.Opacidad, m.Angulo = texto.String, opacidad.Float64, angulo.Float64
+		}
+		marcas = append(marcas, m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(marcas)
+}
+
+// GuardarMarcaAgua crea o reemplaza la marca visible de un rol
+func GuardarMarcaAgua(w http.ResponseWriter, r *http.Request) {
+	var m models.MarcaAgua
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil || m.RolID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+
+	// La marca debe permitir rastrear la captura: usuario, fecha y visualización
+	for _, campo := range []string{"{usuario}", "{fecha}", "{vista}"} {
+		if !strings.Contains(m.Texto, campo) {
+			http.Error(w, "El texto debe incluir "+campo, http.StatusBadRequest)
+			return
+		}
+	}
+	if m.Opacidad < 0.05 || m.Opacidad > 1 {
+		http.Error(w, "La opacidad debe estar entre 0.05 y 1", http.StatusBadRequest)
+		return
+	}
+	if m.Angulo < -90 || m.Angulo > 90 {
+		http.Error(w, "El ángulo debe estar entre -90 y 90", http.StatusBadRequest)
+		return
+	}
+
+	_, err := database.DB.Exec(`
+		INSERT INTO marcas_agua (rol_id, texto, opacidad, angulo) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE texto = VALUES(texto), opacidad = VALUES(opacidad), angulo = VALUES(angulo)`,
+		m.RolID, m.Texto, m.Opacidad, m.Angulo)
+	if err != nil {
+		http.Error(w, "Error guardando marca de agua: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Marca de agua guardada exitosamente"})
+}
@@ -0,0 +1,631 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"visor-pdf/internal/auditoria"
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
+	"visor-pdf/internal/models"
+)
+
+// Tipos de anotación marginal que se registran
+var tiposMarginal = map[string]bool{
+	"divorcio":       true,
+	"reconocimiento": true,
+	"rectificacion":  true,
+	"aclaracion":     true,
+	"adopcion":       true,
+	"nulidad":        true,
+	"defuncion":      true,
+	"otro":           true,
+}
+
+// Largo máximo del número de resolución, de las observaciones y del
+// motivo de una corrección o baja
+const (
+	maxResolucionMarginal    = 60
+	maxObservacionesMarginal = 2000
+	maxMotivoMarginal        = 255
+)
+
+// datosMarginal es el cuerpo de /api/marginales/crear y /editar
+type datosMarginal struct {
+	ID            int64           `json:"id"`
+	Acta          models.ActaRef  `json:"acta"`
+	Tipo          string          `json:"tipo"`
+	Fecha         string          `json:"fecha"`
+	Referencia    *models.ActaRef `json:"referencia"`
+	Resolucion    string          `json:"resolucion"`
+	Observaciones string          `json:"observaciones"`
+}
+
+// columnasReferencia devuelve los valores de las columnas ref_*, NULL sin referencia
+func (d datosMarginal) columnasReferencia() []interface{} {
+	if d.Referencia == nil {
+		return []interface{}{nil, nil, nil, nil, nil, nil}
+	}
+	r := d.Referencia
+	return []interface{}{r.Acto, r.Municipio, r.Oficialia, r.Localidad, r.Anio, r.NumActa}
+}
+
+// opcionales devuelve la resolución y las observaciones, NULL si están vacías
+func (d datosMarginal) opcionales() []interface{} {
+	valores := []interface{}{nil, nil}
+	if d.Resolucion != "" {
+		valores[0] = d.Resolucion
+	}
+	if d.Observaciones != "" {
+		valores[1] = d.Observaciones
+	}
+	return valores
+}
+
+// BuscarMarginales busca en el registro de anotaciones marginales.
+//
+// Parámetros opcionales: tipo; acto, municipio, oficialia, localidad, anio
+// y num_acta del acta base; ref_acto, ref_municipio, ref_anio y
+// ref_num_acta del acta que origina la anotación; resolucion (prefijo);
+// fecha_desde y fecha_hasta (AAAA-MM-DD) y limite (100 por omisión, máximo
+// 500). Los usuarios que no son administradores solo ven las de los
+// municipios que tienen asignados.
+func BuscarMarginales(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	claims := auth.GetClaims(r)
+
+	var condiciones []string
+	var args []interface{}
+
+	if v := query.Get("tipo"); v != "" {
+		if !tiposMarginal[v] {
+			http.Error(w, "tipo inválido", http.StatusBadRequest)
+			return
+		}
+		condiciones = append(condiciones, "am.tipo = ?")
+		args = append(args, v)
+	}
+	for _, f := range []struct{ parametro, condicion string }{
+		{"acto", "am.acto = ?"},
+		{"ref_acto", "am.ref_acto = ?"},
+	} {
+		if v := query.Get(f.parametro); v != "" {
+			condiciones = append(condiciones, f.condicion)
+			args = append(args, v)
+		}
+	}
+	filtrosNumericos := []struct {
+		parametro, condicion string
+	}{
+		{"municipio", "am.municipio_id = ?"},
+		{"oficialia", "am.oficialia = ?"},
+		{"localidad", "am.localidad = ?"},
+		{"anio", "am.anio = ?"},
+		{"num_acta", "am.num_acta = ?"},
+		{"ref_municipio", "am.ref_municipio_id = ?"},
+		{"ref_anio", "am.ref_anio = ?"},
+		{"ref_num_acta", "am.ref_num_acta = ?"},
+	}
+	for _, f := range filtrosNumericos {
+		if v := query.Get(f.parametro); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, f.parametro+" inválido", http.StatusBadRequest)
+				return
+			}
+			condiciones = append(condiciones, f.condicion)
+			args = append(args, n)
+		}
+	}
+	if v := strings.TrimSpace(query.Get("resolucion")); v != "" {
+		condiciones = append(condiciones, "am.resolucion LIKE ?")
+		args = append(args, v+"%")
+	}
+	for _, f := range []struct{ parametro, condicion string }{
+		{"fecha_desde", "am.fecha >= ?"},
+		{"fecha_hasta", "am.fecha <= ?"},
+	} {
+		if v := query.Get(f.parametro); v != "" {
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				http.Error(w, f.parametro+" inválida (AAAA-MM-DD)", http.StatusBadRequest)
+				return
+			}
+			condiciones = append(condiciones, f.condicion)
+			args = append(args, v)
+		}
+	}
+
+	// Solo actas de los estados que atiende el visor y, salvo a los
+	// administradores, de los municipios del usuario
+	condiciones = append(condiciones, estados.CondicionSQL("m.estado_id"))
+	if !claims.EsAdmin() {
+		condiciones = append(condiciones,
+			"am.municipio_id IN (SELECT municipio_id FROM v_usuario_municipios WHERE usuario_id = ?)")
+		args = append(args, claims.UserID)
+	}
+
+	limite := 100
+	if v := query.Get("limite"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limite inválido", http.StatusBadRequest)
+			return
+		}
+		limite = n
+	}
+	if limite > 500 {
+		limite = 500
+	}
+
+	marginales, err := consultarMarginales(condiciones, args, limite)
+	if err != nil {
+		http.Error(w, "Error consultando anotaciones marginales: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(marginales)
+}
+
+// CrearMarginal registra una anotación marginal. Requiere el permiso
+// anotaciones_marginales y, salvo a los administradores, que el municipio
+// del acta base esté asignado al usuario.
+func CrearMarginal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	claims := auth.GetClaims(r)
+	if !puedeEditarMarginales(w, claims) {
+		return
+	}
+	var datos datosMarginal
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	if !validarMarginal(w, claims, &datos) {
+		return
+	}
+
+	args := []interface{}{datos.Acta.Acto, datos.Acta.Municipio, datos.Acta.Oficialia, datos.Acta.Localidad,
+		datos.Acta.Anio, datos.Acta.NumActa, datos.Tipo, datos.Fecha}
+	args = append(args, datos.columnasReferencia()...)
+	args = append(args, datos.opcionales()...)
+	args = append(args, claims.UserID)
+	result, err := database.DB.Exec(`
+		INSERT INTO anotaciones_marginales
+			(acto, municipio_id, oficialia, localidad, anio, num_acta, tipo, fecha,
+			 ref_acto, ref_municipio_id, ref_oficialia, ref_localidad, ref_anio, ref_num_acta,
+			 resolucion, observaciones, capturada_por)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)
+	if err != nil {
+		http.Error(w, "Error guardando anotación marginal", http.StatusInternalServerError)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"message": "Anotación marginal registrada exitosamente",
+	})
+}
+
+// EditarMarginal corrige una anotación marginal; reemplaza todos sus datos
+// y registra quién la actualizó. La versión anterior queda en
+// anotaciones_marginales_historial con el motivo (opcional). Mismos
+// requisitos que CrearMarginal, para el acta base anterior y la nueva.
+func EditarMarginal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	claims := auth.GetClaims(r)
+	if !puedeEditarMarginales(w, claims) {
+		return
+	}
+	var datos struct {
+		datosMarginal
+		Motivo string `json:"motivo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || datos.ID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	motivo, ok := motivoMarginal(w, datos.Motivo, false)
+	if !ok {
+		return
+	}
+	municipio, ok := municipioMarginal(w, datos.ID)
+	if !ok || !exigirMunicipio(w, claims, municipio) {
+		return
+	}
+	if !validarMarginal(w, claims, &datos.datosMarginal) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Error iniciando transacción", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if !guardarVersionMarginal(w, tx, datos.ID, "editar", motivo, claims.UserID) {
+		return
+	}
+
+	args := []interface{}{datos.Acta.Acto, datos.Acta.Municipio, datos.Acta.Oficialia, datos.Acta.Localidad,
+		datos.Acta.Anio, datos.Acta.NumActa, datos.Tipo, datos.Fecha}
+	args = append(args, datos.columnasReferencia()...)
+	args = append(args, datos.opcionales()...)
+	args = append(args, claims.UserID, datos.ID)
+	_, err = tx.Exec(`
+		UPDATE anotaciones_marginales
+		SET acto = ?, municipio_id = ?, oficialia = ?, localidad = ?, anio = ?, num_acta = ?, tipo = ?, fecha = ?,
+			ref_acto = ?, ref_municipio_id = ?, ref_oficialia = ?, ref_localidad = ?, ref_anio = ?, ref_num_acta = ?,
+			resolucion = ?, observaciones = ?, actualizada_por = ?, actualizada_en = NOW()
+		WHERE id = ?`, args...)
+	if err != nil {
+		http.Error(w, "Error guardando anotación marginal", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Error guardando anotación marginal", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Anotación marginal actualizada exitosamente"})
+}
+
+// EliminarMarginal da de baja una anotación marginal capturada por error.
+// No borra la fila: la marca como eliminada con quién, cuándo y el motivo
+// (obligatorio), deja de listarse y su última versión queda en el
+// historial. Mismos requisitos que CrearMarginal.
+func EliminarMarginal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	claims := auth.GetClaims(r)
+	if !puedeEditarMarginales(w, claims) {
+		return
+	}
+	var datos struct {
+		ID     int64  `json:"id"`
+		Motivo string `json:"motivo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || datos.ID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	motivo, ok := motivoMarginal(w, datos.Motivo, true)
+	if !ok {
+		return
+	}
+	municipio, ok := municipioMarginal(w, datos.ID)
+	if !ok || !exigirMunicipio(w, claims, municipio) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Error iniciando transacción", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if !guardarVersionMarginal(w, tx, datos.ID, "eliminar", motivo, claims.UserID) {
+		return
+	}
+	_, err = tx.Exec(`
+		UPDATE anotaciones_marginales
+		SET eliminada_en = NOW(), eliminada_por = ?, motivo_eliminacion = ?
+		WHERE id = ?`, claims.UserID, motivo, datos.ID)
+	if err != nil {
+		http.Error(w, "Error eliminando anotación marginal", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Error eliminando anotación marginal", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Anotación marginal eliminada exitosamente"})
+}
+
+// HistorialMarginal devuelve las versiones anteriores de una anotación
+// marginal, de la más reciente a la más antigua, también si ya fue
+// eliminada. Parámetro: id. Salvo a los administradores, exige que el
+// municipio del acta base esté asignado al usuario.
+func HistorialMarginal(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetClaims(r)
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id < 1 {
+		http.Error(w, "id inválido", http.StatusBadRequest)
+		return
+	}
+	municipio, ok := municipioMarginal(w, id)
+	if !ok || !exigirMunicipio(w, claims, municipio) {
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT h.marginal_id, h.acto, h.municipio_id, h.oficialia, h.localidad, h.anio, h.num_acta, h.tipo, h.fecha,
+			h.ref_acto, h.ref_municipio_id, h.ref_oficialia, h.ref_localidad, h.ref_anio, h.ref_num_acta,
+			COALESCE(h.resolucion, ''), COALESCE(h.observaciones, ''), uc.username, h.capturada_en,
+			ua.username, h.actualizada_en,
+			h.id, h.accion, COALESCE(h.motivo, ''), u.username, h.creado_en
+		FROM anotaciones_marginales_historial h
+		JOIN usuarios u ON h.usuario_id = u.id
+		JOIN usuarios uc ON h.capturada_por = uc.id
+		LEFT JOIN usuarios ua ON h.actualizada_por = ua.id
+		WHERE h.marginal_id = ?
+		ORDER BY h.id DESC`, id)
+	if err != nil {
+		http.Error(w, "Error consultando historial", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	historial := []models.CambioMarginal{}
+	for rows.Next() {
+		var c models.CambioMarginal
+		if err := escanearMarginal(rows, &c.Anterior,
+			&c.ID, &c.Accion, &c.Motivo, &c.Usuario, &c.CreadoEn); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		historial = append(historial, c)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(historial)
+}
+
+// motivoMarginal valida el motivo de una corrección o baja. Si falla ya
+// respondió al cliente.
+func motivoMarginal(w http.ResponseWriter, motivo string, obligatorio bool) (string, bool) {
+	motivo = strings.TrimSpace(motivo)
+	if obligatorio && motivo == "" {
+		http.Error(w, "Indica el motivo de la eliminación", http.StatusBadRequest)
+		return "", false
+	}
+	if len([]rune(motivo)) > maxMotivoMarginal {
+		http.Error(w, "motivo demasiado largo", http.StatusBadRequest)
+		return "", false
+	}
+	return motivo, true
+}
+
+// guardarVersionMarginal bloquea la anotación hasta el fin de la
+// transacción y copia sus datos actuales al historial antes de editarla o
+// eliminarla. Si no está vigente o falla ya respondió al cliente.
+func guardarVersionMarginal(w http.ResponseWriter, tx *sql.Tx, id int64, accion, motivo string, usuario int) bool {
+	var eliminada sql.NullTime
+	err := tx.QueryRow("SELECT eliminada_en FROM anotaciones_marginales WHERE id = ? FOR UPDATE", id).
+		Scan(&eliminada)
+	if err == sql.ErrNoRows || eliminada.Valid {
+		http.Error(w, "Anotación marginal no encontrada o ya eliminada", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Error consultando anotación marginal", http.StatusInternalServerError)
+		return false
+	}
+
+	var motivoNulo interface{}
+	if motivo != "" {
+		motivoNulo = motivo
+	}
+	_, err = tx.Exec(`
+		INSERT INTO anotaciones_marginales_historial
+			(marginal_id, accion, motivo, usuario_id,
+			 acto, municipio_id, oficialia, localidad, anio, num_acta, tipo, fecha,
+			 ref_acto, ref_municipio_id, ref_oficialia, ref_localidad, ref_anio, ref_num_acta,
+			 resolucion, observaciones, capturada_por, capturada_en, actualizada_por, actualizada_en)
+		SELECT id, ?, ?, ?,
+			acto, municipio_id, oficialia, localidad, anio, num_acta, tipo, fecha,
+			ref_acto, ref_municipio_id, ref_oficialia, ref_localidad, ref_anio, ref_num_acta,
+			resolucion, observaciones, capturada_por, capturada_en, actualizada_por, actualizada_en
+		FROM anotaciones_marginales
+		WHERE id = ?`, accion, motivoNulo, usuario, id)
+	if err != nil {
+		http.Error(w, "Error registrando el historial", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// marginalesDeActa devuelve las anotaciones marginales del acta base, por
+// fecha. Como en BuscarMarginales, un usuario que no es administrador solo
+// las ve si el municipio le está asignado; si no, la lista sale vacía.
+func marginalesDeActa(claims *auth.Claims, v auditoria.Visualizacion) ([]models.AnotacionMarginal, error) {
+	condiciones := []string{
+		"am.acto = ?", "am.municipio_id = ?", "am.oficialia = ?", "am.localidad = ?", "am.anio = ?", "am.num_acta = ?",
+	}
+	args := []interface{}{v.Acto, v.Municipio, v.Oficialia, v.Localidad, v.Anio, v.NumActa}
+	if !claims.EsAdmin() {
+		condiciones = append(condiciones,
+			"am.municipio_id IN (SELECT municipio_id FROM v_usuario_municipios WHERE usuario_id = ?)")
+		args = append(args, claims.UserID)
+	}
+	return consultarMarginales(condiciones, args, 500)
+}
+
+// consultarMarginales lee las anotaciones marginales que cumplen las condiciones
+func consultarMarginales(condiciones []string, args []interface{}, limite int) ([]models.AnotacionMarginal, error) {
+	condiciones = append(condiciones, "am.eliminada_en IS NULL")
+	rows, err := database.DB.Query(`
+		SELECT am.id, am.acto, am.municipio_id, am.oficialia, am.localidad, am.anio, am.num_acta, am.tipo, am.fecha,
+			am.ref_acto, am.ref_municipio_id, am.ref_oficialia, am.ref_localidad, am.ref_anio, am.ref_num_acta,
+			COALESCE(am.resolucion, ''), COALESCE(am.observaciones, ''), uc.username, am.capturada_en,
+			ua.username, am.actualizada_en
+		FROM anotaciones_marginales am
+		JOIN municipios m ON am.municipio_id = m.idmunicipios
+		JOIN usuarios uc ON am.capturada_por = uc.id
+		LEFT JOIN usuarios ua ON am.actualizada_por = ua.id
+		WHERE `+strings.Join(condiciones, " AND ")+`
+		ORDER BY am.fecha, am.id
+		LIMIT ?`, append(args, limite)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	marginales := []models.AnotacionMarginal{}
+	for rows.Next() {
+		var a models.AnotacionMarginal
+		if err := escanearMarginal(rows, &a); err != nil {
+			return nil, err
+		}
+		marginales = append(marginales, a)
+	}
+	return marginales, rows.Err()
+}
+
+// escanearMarginal lee una anotación marginal de las columnas que
+// seleccionan consultarMarginales y HistorialMarginal; extra recibe las
+// columnas que siguen
+func escanearMarginal(rows *sql.Rows, a *models.AnotacionMarginal, extra ...interface{}) error {
+	var fecha time.Time
+	var refActo, actualizadaPor sql.NullString
+	var refMunicipio, refOficialia, refLocalidad, refAnio, refNumActa sql.NullInt64
+	var actualizadaEn sql.NullTime
+	destinos := []interface{}{&a.ID, &a.Acta.Acto, &a.Acta.Municipio, &a.Acta.Oficialia, &a.Acta.Localidad,
+		&a.Acta.Anio, &a.Acta.NumActa, &a.Tipo, &fecha, &refActo, &refMunicipio, &refOficialia,
+		&refLocalidad, &refAnio, &refNumActa, &a.Resolucion, &a.Observaciones, &a.CapturadaPor,
+		&a.CapturadaEn, &actualizadaPor, &actualizadaEn}
+	if err := rows.Scan(append(destinos, extra...)...); err != nil {
+		return err
+	}
+	a.Fecha = fecha.Format("2006-01-02")
+	if refActo.Valid {
+		a.Referencia = &models.ActaRef{
+			Acto:      refActo.String,
+			Municipio: int(refMunicipio.Int64),
+			Oficialia: int(refOficialia.Int64),
+			Localidad: int(refLocalidad.Int64),
+			Anio:      int(refAnio.Int64),
+			NumActa:   int(refNumActa.Int64),
+		}
+	}
+	a.ActualizadaPor = textoNulo(actualizadaPor)
+	if actualizadaEn.Valid {
+		a.ActualizadaEn = &actualizadaEn.Time
+	}
+	return nil
+}
+
+// puedeEditarMarginales exige el permiso anotaciones_marginales
+func puedeEditarMarginales(w http.ResponseWriter, claims *auth.Claims) bool {
+	permitido, err := auth.TienePermiso(claims, auth.PermisoAnotacionesMarginales)
+	if err != nil {
+		http.Error(w, "Error consultando permisos", http.StatusInternalServerError)
+		return false
+	}
+	if !permitido {
+		http.Error(w, "No tiene permiso para editar el registro de anotaciones marginales", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// municipioMarginal devuelve el municipio del acta base de una anotación
+// marginal guardada
+func municipioMarginal(w http.ResponseWriter, id int64) (int, bool) {
+	var municipio int
+	err := database.DB.QueryRow("SELECT municipio_id FROM anotaciones_marginales WHERE id = ?", id).Scan(&municipio)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Anotación marginal no encontrada", http.StatusNotFound)
+		return 0, false
+	}
+	if err != nil {
+		http.Error(w, "Error consultando anotación marginal", http.StatusInternalServerError)
+		return 0, false
+	}
+	return municipio, true
+}
+
+// validarMarginal normaliza y valida los datos: tipo, fecha (no anterior al
+// año del acta ni futura), acta base de un municipio y oficialía del visor
+// asignados al usuario y, si la hay, acta de referencia con un acto del
+// catálogo. Si falla ya respondió al cliente.
+func validarMarginal(w http.ResponseWriter, claims *auth.Claims, d *datosMarginal) bool {
+	d.Tipo = strings.TrimSpace(d.Tipo)
+	if !tiposMarginal[d.Tipo] {
+		http.Error(w, "tipo inválido", http.StatusBadRequest)
+		return false
+	}
+	fecha, err := time.Parse("2006-01-02", d.Fecha)
+	if err != nil {
+		http.Error(w, "fecha inválida (AAAA-MM-DD)", http.StatusBadRequest)
+		return false
+	}
+	if fecha.Year() < d.Acta.Anio || fecha.After(time.Now()) {
+		http.Error(w, "La fecha debe ser posterior al año del acta y no futura", http.StatusBadRequest)
+		return false
+	}
+	d.Resolucion = strings.TrimSpace(d.Resolucion)
+	d.Observaciones = strings.TrimSpace(d.Observaciones)
+	if len([]rune(d.Resolucion)) > maxResolucionMarginal || len([]rune(d.Observaciones)) > maxObservacionesMarginal {
+		http.Error(w, "La resolución o las observaciones son demasiado largas", http.StatusBadRequest)
+		return false
+	}
+
+	if !actaRefValida(w, d.Acta, "acta") {
+		return false
+	}
+	_, _, err = claveMunicipio(d.Acta.Municipio)
+	if err == sql.ErrNoRows {
+		http.Error(w, "El municipio no existe o su estado no lo atiende este visor", http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "Error consultando municipio", http.StatusInternalServerError)
+		return false
+	}
+	valida, err := oficialiaValida(d.Acta.Municipio, d.Acta.Oficialia, d.Acta.Anio)
+	if err != nil {
+		http.Error(w, "Error consultando oficialías", http.StatusInternalServerError)
+		return false
+	}
+	if !valida {
+		http.Error(w, "La oficialía no existe en ese municipio o no operaba ese año", http.StatusBadRequest)
+		return false
+	}
+	if !exigirMunicipio(w, claims, d.Acta.Municipio) {
+		return false
+	}
+
+	if d.Referencia != nil {
+		if *d.Referencia == d.Acta {
+			http.Error(w, "El acta de referencia es la misma que el acta base", http.StatusBadRequest)
+			return false
+		}
+		if !actaRefValida(w, *d.Referencia, "referencia") {
+			return false
+		}
+	}
+	return true
+}
+
+// actaRefValida revisa que el acto esté en el catálogo y que los números
+// sean válidos
+func actaRefValida(w http.ResponseWriter, a models.ActaRef, campo string) bool {
+	if a.Municipio < 1 || a.Oficialia < 0 || a.Localidad < 0 || a.Anio < 1000 || a.Anio > 9999 || a.NumActa < 0 {
+		http.Error(w, campo+": datos del acta inválidos", http.StatusBadRequest)
+		return false
+	}
+	_, ok := validarActo(w, a.Acto)
+	return ok
+}
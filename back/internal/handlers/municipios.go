@@ -6,9 +6,36 @@ import (
 	"net/http"
 
 	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
 	"visor-pdf/internal/models"
 )
 
+// GetEstados devuelve los estados que atiende este despliegue
+func GetEstados(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query("SELECT id, nombre, abreviatura FROM estados WHERE " +
+		estados.CondicionSQL("id") + " ORDER BY id")
+	if err != nil {
+		http.Error(w, "Error consultando estados: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	lista := []models.Estado{}
+	for rows.Next() {
+		var e models.Estado
+		if err := rows.Scan(&e.ID, &e.Nombre, &e.Abreviatura); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		lista = append(lista, e)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lista)
+}
+
+// GetMunicipios devuelve los municipios de los estados que atiende el
+// despliegue; con ?usuario_id= solo los permitidos a ese usuario
 func GetMunicipios(w http.ResponseWriter, r *http.Request) {
 	usuarioID := r.URL.Query().Get("usuario_id")
 
@@ -16,16 +43,21 @@ func GetMunicipios(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	if usuarioID != "" {
-		// Municipios permitidos para ese usuario (sin filtro por fecha)
+		// Municipios permitidos para ese usuario (directos, por distrito, región o estado)
 		rows, err = database.DB.Query(`
-			SELECT DISTINCT m.idmunicipios, m.nombre
-			FROM usuario_municipios um
+			SELECT DISTINCT m.idmunicipios, m.estado_id, e.abreviatura, m.clave, m.nombre, m.activo, m.fusionado_en
+			FROM v_usuario_municipios um
 			JOIN municipios m ON um.municipio_id = m.idmunicipios
-			WHERE um.usuario_id = ?`,
+			JOIN estados e ON m.estado_id = e.id
+			WHERE um.usuario_id = ? AND `+estados.CondicionSQL("m.estado_id"),
 			usuarioID)
 	} else {
 		// Todos los municipios (para administradores)
-		rows, err = database.DB.Query("SELECT idmunicipios, nombre FROM municipios")
+		rows, err = database.DB.Query(`
+			SELECT m.idmunicipios, m.estado_id, e.abreviatura, m.clave, m.nombre, m.activo, m.fusionado_en
+			FROM municipios m
+			JOIN estados e ON m.estado_id = e.id
+			WHERE ` + estados.CondicionSQL("m.estado_id"))
 	}
 
 	if err != nil {
@@ -37,10 +69,12 @@ func GetMunicipios(w http.ResponseWriter, r *http.Request) {
 	var municipios []models.Municipio
 	for rows.Next() {
 		var m models.Municipio
-		if err := rows.Scan(&m.ID, &m.Nombre); err != nil {
+		var fusionado sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.EstadoID, &m.Estado, &m.Clave, &m.Nombre, &m.Activo, &fusionado); err != nil {
 			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		m.FusionadoEn = enteroNulo(fusionado)
 		municipios = append(municipios, m)
 	}
 
@@ -55,7 +89,7 @@ func GetLocalidades(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := database.DB.Query("SELECT idlocalidades, idmunicipio, nombre FROM localidades WHERE idmunicipio = ?", idMunicipio)
+	rows, err := database.DB.Query("SELECT idlocalidades, idmunicipio, nombre, activo, fusionado_en FROM localidades WHERE idmunicipio = ?", idMunicipio)
 	if err != nil {
 		http.Error(w, "Error consultando localidades: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -65,13 +99,24 @@ func GetLocalidades(w http.ResponseWriter, r *http.Request) {
 	var localidades []models.Localidad
 	for rows.Next() {
 		var l models.Localidad
-		if err := rows.Scan(&l.ID, &l.IDMunicipio, &l.Nombre); err != nil {
+		var fusionado sql.NullInt64
+		if err := rows.Scan(&l.ID, &l.IDMunicipio, &l.Nombre, &l.Activo, &fusionado); err != nil {
 			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		l.FusionadoEn = enteroNulo(fusionado)
 		localidades = append(localidades, l)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(localidades)
 }
+
+// claveMunicipio devuelve el estado y la clave INEGI de un municipio, que
+// forman la ruta del PDF. sql.ErrNoRows si no existe o su estado no lo
+// atiende el despliegue.
+func claveMunicipio(id int) (estado, clave int, err error) {
+	err = database.DB.QueryRow("SELECT estado_id, clave FROM municipios WHERE idmunicipios = ? AND "+
+		estados.CondicionSQL("estado_id"), id).Scan(&estado, &clave)
+	return estado, clave, err
+}
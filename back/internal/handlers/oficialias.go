@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/models"
+)
+
+// GetOficialias devuelve las oficialías de un municipio. Con ?anio= solo las
+// que operaban ese año.
+func GetOficialias(w http.ResponseWriter, r *http.Request) {
+	idMunicipio := r.URL.Query().Get("idmunicipio")
+	if idMunicipio == "" {
+		http.Error(w, "Falta idmunicipio", http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		SELECT id, municipio_id, numero, nombre, anio_inicio, anio_fin
+		FROM oficialias
+		WHERE municipio_id = ?`
+	args := []interface{}{idMunicipio}
+	if v := r.URL.Query().Get("anio"); v != "" {
+		anio, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "anio inválido", http.StatusBadRequest)
+			return
+		}
+		query += " AND (anio_inicio IS NULL OR anio_inicio <= ?) AND (anio_fin IS NULL OR anio_fin >= ?)"
+		args = append(args, anio, anio)
+	}
+
+	rows, err := database.DB.Query(query+" ORDER BY numero", args...)
+	if err != nil {
+		http.Error(w, "Error consultando oficialías: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	oficialias := []models.Oficialia{}
+	for rows.Next() {
+		var o models.Oficialia
+		var inicio, fin sql.NullInt64
+		if err := rows.Scan(&o.ID, &o.IDMunicipio, &o.Numero, &o.Nombre, &inicio, &fin); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		o.AnioInicio = enteroNulo(inicio)
+		o.AnioFin = enteroNulo(fin)
+		oficialias = append(oficialias, o)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(oficialias)
+}
+
+// oficialiaValida indica si la oficialía existe en el municipio y operaba
+// ese año. Un municipio sin oficialías cargadas todavía no se valida: el
+// catálogo se importa por separado (importar-oficialias) y, mientras no
+// esté, no debe bloquear la consulta de sus actas.
+func oficialiaValida(municipio, oficialia, anio int) (bool, error) {
+	var valida bool
+	err := database.DB.QueryRow(`
+		SELECT NOT EXISTS (
+			SELECT 1 FROM oficialias WHERE municipio_id = ?
+		) OR EXISTS (
+			SELECT 1 FROM oficialias
+			WHERE municipio_id = ? AND numero = ?
+				AND (anio_inicio IS NULL OR anio_inicio <= ?)
+				AND (anio_fin IS NULL OR anio_fin >= ?)
+		)`, municipio, municipio, oficialia, anio, anio).Scan(&valida)
+	return valida, err
+}
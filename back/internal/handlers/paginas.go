@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"visor-pdf/internal/almacen"
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/cache"
+	"visor-pdf/internal/models"
+	"visor-pdf/internal/rutas"
+)
+
+// Ancho de las miniaturas en píxeles: el predeterminado y el máximo que se
+// acepta en ?ancho=. Son para navegar, no para leer el acta.
+const (
+	anchoMiniatura    = 120
+	anchoMaxMiniatura = 300
+)
+
+// cachePaginas guarda el contenido, los metadatos, las miniaturas y los
+// tiles DZI de los PDFs; SetConfig la reemplaza por una del tamaño de CACHE_MB
+var cachePaginas = cache.Nueva(0)
+
+// errorMicroservicio es una respuesta de error del microservicio, que se
+// reenvía al cliente tal cual
+type errorMicroservicio struct {
+	status int
+	cuerpo []byte
+}
+
+func (e *errorMicroservicio) Error() string {
+	return fmt.Sprintf("microservicio respondió %d", e.status)
+}
+
+// InfoPDF devuelve el número de páginas, sus dimensiones y DPI estimado, el
+// tamaño y la fecha de modificación del PDF del acta, y sus anotaciones
+// marginales. Mismos parámetros que /api/pdf; no cuenta como visualización,
+// pero exige, salvo a los administradores, que el municipio esté asignado.
+// Los metadatos del PDF se guardan en caché mientras el archivo no cambie;
+// las anotaciones marginales se consultan en cada petición.
+func InfoPDF(w http.ResponseWriter, r *http.Request) {
+	claims := auth.GetClaims(r)
+	visualizacion, acta, archivo, ok := ubicarActa(w, r, claims.UserID)
+	if !ok || !exigirMunicipio(w, claims, visualizacion.Municipio) {
+		return
+	}
+	info, ok := infoArchivo(w, acta, archivo)
+	if !ok {
+		return
+	}
+
+	pdf, err := infoPaginas(archivo, info)
+	if !responderErrorPaginas(w, err) {
+		return
+	}
+	pdf.Marginales, err = marginalesDeActa(claims, visualizacion)
+	if err != nil {
+		http.Error(w, "Error consultando anotaciones marginales", http.StatusInternalServerError)
+		return
+	}
+	datos, err := json.Marshal(pdf)
+	if err != nil {
+		http.Error(w, "Error generando respuesta", http.StatusInternalServerError)
+		return
+	}
+
+	// Las anotaciones marginales pueden cambiar sin que cambie el archivo:
+	// el navegador revalida siempre, con un ETag del contenido
+	suma := sha256.Sum256(datos)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, suma[:8]))
+	http.ServeContent(w, r, "info.json", time.Time{}, bytes.NewReader(datos))
+}
+
+// infoPaginas devuelve los metadatos del PDF, de la caché o del microservicio
+func infoPaginas(archivo rutas.Archivo, info almacen.Info) (models.InfoPDF, error) {
+	var pdf models.InfoPDF
+	datos, err := cachePaginas.Generar("info|"+claveArchivo(archivo, info), func() ([]byte, error) {
+		cuerpo, err := llamarMicroservicio(archivo, info, "/pdf_info")
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Pages []struct {
+				PageNumber int     `json:"page_number"`
+				Width      float64 `json:"width"`
+				Height     float64 `json:"height"`
+				DPI        int     `json:"dpi"`
+			} `json:"pages"`
+		}
+		if err := json.Unmarshal(cuerpo, &resp); err != nil {
+			return nil, err
+		}
+		pdf := models.InfoPDF{
+			Paginas:     len(resp.Pages),
+			Tamano:      info.Tamano,
+			Modificado:  info.Modificado,
+			Dimensiones: make([]models.PaginaPDF, 0, len(resp.Pages)),
+		}
+		for _, p := range resp.Pages {
+			pdf.Dimensiones = append(pdf.Dimensiones, models.PaginaPDF{
+				Pagina: p.PageNumber, Ancho: p.Width, Alto: p.Height, DPI: p.DPI,
+			})
+		}
+		return json.Marshal(pdf)
+	})
+	if err != nil {
+		return pdf, err
+	}
+	err = json.Unmarshal(datos, &pdf)
+	return pdf, err
+}
+
+// dimensionesPagina devuelve el tamaño en puntos de una página del PDF del
+// acta. Si no existe ya respondió al cliente.
+func dimensionesPagina(w http.ResponseWriter, acta rutas.Acta, archivo rutas.Archivo, pagina int) (models.PaginaPDF, bool) {
+	info, ok := infoArchivo(w, acta, archivo)
+	if !ok {
+		return models.PaginaPDF{}, false
+	}
+	pdf, err := infoPaginas(archivo, info)
+	if !responderErrorPaginas(w, err) {
+		return models.PaginaPDF{}, false
+	}
+	if pagina < 1 || pagina > pdf.Paginas {
+		http.Error(w, fmt.Sprintf("El acta tiene %d páginas", pdf.Paginas), http.StatusBadRequest)
+		return models.PaginaPDF{}, false
+	}
+	return pdf.Dimensiones[pagina-1], true
+}
+
+// MiniaturaPDF devuelve una página del PDF del acta como PNG pequeño.
+// Mismos parámetros que /api/pdf más pagina (desde 1, predeterminada 1) y
+// ancho en píxeles. Las miniaturas de todas las páginas se generan juntas
+// y se guardan en caché, así que la tira de páginas hace una sola llamada
+// al microservicio. Exige el municipio asignado, como InfoPDF.
+func MiniaturaPDF(w http.ResponseWriter, r *http.Request) {
+	pagina, ancho := 1, anchoMiniatura
+	if v := r.URL.Query().Get("pagina"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "pagina inválida", http.StatusBadRequest)
+			return
+		}
+		pagina = n
+	}
+	if v := r.URL.Query().Get("ancho"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 16 || n > anchoMaxMiniatura {
+			http.Error(w, fmt.Sprintf("ancho inválido (16 a %d)", anchoMaxMiniatura), http.StatusBadRequest)
+			return
+		}
+		ancho = n
+	}
+
+	claims := auth.GetClaims(r)
+	visualizacion, acta, archivo, ok := ubicarActa(w, r, claims.UserID)
+	if !ok || !exigirMunicipio(w, claims, visualizacion.Municipio) {
+		return
+	}
+	info, ok := infoArchivo(w, acta, archivo)
+	if !ok {
+		return
+	}
+
+	clave := fmt.Sprintf("miniaturas|%d|%s", ancho, claveArchivo(archivo, info))
+	datos, err := cachePaginas.Generar(clave, func() ([]byte, error) {
+		cuerpo, err := llamarMicroservicio(archivo, info, "/pdf_miniaturas?ancho="+strconv.Itoa(ancho))
+		if err != nil {
+			return nil, err
+		}
+		// Se guardan solo los PNG, en orden de página ([]byte va en base64)
+		var resp struct {
+			Pages []struct {
+				Image []byte `json:"image"`
+			} `json:"pages"`
+		}
+		if err := json.Unmarshal(cuerpo, &resp); err != nil {
+			return nil, err
+		}
+		imagenes := make([][]byte, len(resp.Pages))
+		for i, p := range resp.Pages {
+			imagenes[i] = p.Image
+		}
+		return json.Marshal(imagenes)
+	})
+	if !responderErrorPaginas(w, err) {
+		return
+	}
+
+	var imagenes [][]byte
+	if err := json.Unmarshal(datos, &imagenes); err != nil {
+		http.Error(w, "Error leyendo miniaturas", http.StatusInternalServerError)
+		return
+	}
+	if pagina > len(imagenes) {
+		http.Error(w, fmt.Sprintf("El acta tiene %d páginas", len(imagenes)), http.StatusNotFound)
+		return
+	}
+	servirPaginas(w, r, fmt.Sprintf("pagina-%d.png", pagina), info, imagenes[pagina-1])
+}
+
+// infoArchivo obtiene tamaño y fecha del PDF, que forman la clave de caché
+func infoArchivo(w http.ResponseWriter, acta rutas.Acta, archivo rutas.Archivo) (almacen.Info, bool) {
+	info, err := archivo.Almacen.Info(archivo.Relativa)
+	if err != nil {
+		// El PDF pudo moverse después de guardarse su ubicación en caché
+		rutas.Olvidar(acta)
+		http.Error(w, "No se encontró el PDF del acta", http.StatusNotFound)
+		return info, false
+	}
+	return info, true
+}
+
+// claveArchivo identifica una versión del PDF: si el archivo cambia, cambia
+// la clave y lo guardado de la versión anterior se descarta por antigüedad
+func claveArchivo(archivo rutas.Archivo, info almacen.Info) string {
+	return fmt.Sprintf("%s|%s|%x-%x", archivo.Raiz, archivo.Relativa, info.Modificado.UnixNano(), info.Tamano)
+}
+
+// contenidoPDF devuelve los bytes del PDF. Se guardan en la caché para no
+// volver a leerlos del almacén en cada tile o miniatura.
+func contenidoPDF(archivo rutas.Archivo, info almacen.Info) ([]byte, error) {
+	return cachePaginas.Generar("pdf|"+claveArchivo(archivo, info), func() ([]byte, error) {
+		pdf, err := archivo.Almacen.Abrir(archivo.Relativa)
+		if err != nil {
+			return nil, err
+		}
+		defer pdf.Close()
+		return io.ReadAll(pdf)
+	})
+}
+
+// llamarMicroservicio envía el PDF al endpoint del microservicio y devuelve
+// el cuerpo de la respuesta; si no es 200 devuelve *errorMicroservicio
+func llamarMicroservicio(archivo rutas.Archivo, info almacen.Info, endpoint string) ([]byte, error) {
+	pdf, err := contenidoPDF(archivo, info)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post("http://localhost:5000"+endpoint, "application/pdf", bytes.NewReader(pdf))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	cuerpo, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &errorMicroservicio{status: resp.StatusCode, cuerpo: cuerpo}
+	}
+	return cuerpo, nil
+}
+
+// responderErrorPaginas responde el error de generación, si lo hubo, y
+// devuelve si se puede continuar
+func responderErrorPaginas(w http.ResponseWriter, err error) bool {
+	if err == nil {
+		return true
+	}
+	var em *errorMicroservicio
+	if errors.As(err, &em) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(em.status)
+		w.Write(em.cuerpo)
+		return false
+	}
+	http.Error(w, "Error llamando microservicio", http.StatusInternalServerError)
+	return false
+}
+
+// servirPaginas entrega un resultado en caché. No lleva datos del usuario,
+// así que el navegador puede guardarlo unos minutos y revalidarlo con el
+// ETag del archivo.
+func servirPaginas(w http.ResponseWriter, r *http.Request, nombre string, info almacen.Info, datos []byte) {
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.Modificado.Unix(), info.Tamano))
+	http.ServeContent(w, r, nombre, info.Modificado, bytes.NewReader(datos))
+}
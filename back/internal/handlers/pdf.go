@@ -1,23 +1,145 @@
 package handlers
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
 	"io/ioutil"
+	"net"
 	"net/http"
-	"net/url"
-	"path/filepath"
 	"strconv"
 
+	"visor-pdf/internal/auditoria"
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/cache"
 	"visor-pdf/internal/config"
+	"visor-pdf/internal/imagen"
+	"visor-pdf/internal/rutas"
 )
 
 var Cfg config.Config
 
 func SetConfig(cfg config.Config) {
 	Cfg = cfg
+	cachePaginas = cache.Nueva(int64(cfg.CacheMB) << 20)
 }
 
 func GetPDFAsImage(w http.ResponseWriter, r *http.Request) {
+	// Buscar el PDF y revisar el municipio antes de contar la visualización
+	// contra la cuota
+	claims := auth.GetClaims(r)
+	filtros, ok := filtrosDePeticion(w, r)
+	if !ok {
+		return
+	}
+	visualizacion, acta, archivo, ok := ubicarActa(w, r, claims.UserID)
+	if !ok || !exigirMunicipio(w, claims, visualizacion.Municipio) {
+		return
+	}
+
+	vistaID, ok := registrarVista(w, claims, visualizacion)
+	if !ok {
+		return
+	}
+
+	// El PDF se lee del almacén de su raíz (disco o S3) y se envía al
+	// microservicio, que ya no necesita acceso al archivo
+	pdf, err := archivo.Almacen.Abrir(archivo.Relativa)
+	if err != nil {
+		// El PDF pudo moverse después de guardarse su ubicación en caché
+		rutas.Olvidar(acta)
+		http.Error(w, "Error leyendo el PDF del acta", http.StatusBadGateway)
+		return
+	}
+	defer pdf.Close()
+
+	resp, err := http.Post("http://localhost:5000/pdf_to_tiles", "application/pdf", pdf)
+	if err != nil {
+		http.Error(w, "Error llamando microservicio", 500)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, "Error leyendo respuesta del microservicio", 500)
+		return
+	}
+
+	// Los errores del microservicio se reenvían tal cual
+	if resp.StatusCode != http.StatusOK {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.StatusCode)
+		w.Write(body)
+		return
+	}
+
+	var tiles imagen.RespuestaTiles
+	if err := json.Unmarshal(body, &tiles); err != nil {
+		http.Error(w, "Respuesta inválida del microservicio", http.StatusBadGateway)
+		return
+	}
+
+	// Filtros de mejora sobre la página completa, antes de las marcas
+	if len(filtros) > 0 {
+		err = imagen.TransformarPaginas(&tiles, func(pagina *image.RGBA, _ int, escala float64) *image.RGBA {
+			return filtrarPagina(filtros, pagina, escala)
+		})
+		if err != nil {
+			http.Error(w, "Error aplicando filtros: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Marca de agua visible con el usuario, la fecha y el código de la visualización
+	config, err := obtenerMarcaAgua(claims.RolID)
+	if err != nil {
+		http.Error(w, "Error consultando marca de agua", http.StatusInternalServerError)
+		return
+	}
+	marca := nuevaMarcaAgua(config, claims.Username, auditoria.CodigoVista(vistaID))
+	// Marca forense invisible, que sobrevive aunque se recorte la visible
+	id := imagen.Identificador{UsuarioID: claims.UserID, VistaID: vistaID}
+	err = imagen.TransformarTiles(&tiles, func(img image.Image, x, y int) image.Image {
+		return imagen.IncrustarForense(marca.Aplicar(img, x, y), x, y, id)
+	})
+	if err != nil {
+		http.Error(w, "Error aplicando marca de agua: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Cada respuesta lleva datos del usuario: no debe guardarse en cachés compartidas
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tiles)
+}
+
+// registrarVista revisa las cuotas por hora y por día y registra la
+// visualización en la bitácora, en una sola transacción. Si algo falla ya
+// respondió al cliente.
+func registrarVista(w http.ResponseWriter, claims *auth.Claims, visualizacion auditoria.Visualizacion) (int64, bool) {
+	vistaID, err := auditoria.RegistrarVisualizacion(visualizacion, claims.RolID)
+	if err != nil {
+		var excedida *auditoria.CuotaExcedida
+		if errors.As(err, &excedida) {
+			w.Header().Set("Retry-After", strconv.Itoa(excedida.Reintentar))
+			http.Error(w, fmt.Sprintf("Cuota de visualización excedida: máximo %d actas por %s",
+				excedida.Maximo, excedida.Periodo), http.StatusTooManyRequests)
+			return 0, false
+		}
+		http.Error(w, "Error registrando visualización", http.StatusInternalServerError)
+		return 0, false
+	}
+	go auditoria.AnalizarVisualizacion(visualizacion)
+	return vistaID, true
+}
+
+// ubicarActa valida los parámetros del acta (acto, municipio atendido,
+// oficialía vigente) y busca su PDF en las raíces del archivo. Si algo
+// falla ya respondió al cliente y devuelve ok = false.
+func ubicarActa(w http.ResponseWriter, r *http.Request, usuarioID int) (auditoria.Visualizacion, rutas.Acta, rutas.Archivo, bool) {
 	year := r.URL.Query().Get("year")
 	acto := r.URL.Query().Get("acto")
 	municipio := r.URL.Query().Get("municipio")
@@ -28,58 +150,92 @@ func GetPDFAsImage(w http.ResponseWriter, r *http.Request) {
 	// Validar parámetros
 	if year == "" || acto == "" || municipio == "" || oficialia == "" || localidad == "" || numActa == "" {
 		http.Error(w, "Faltan parámetros", http.StatusBadRequest)
-		return
+		return auditoria.Visualizacion{}, rutas.Acta{}, rutas.Archivo{}, false
 	}
 
-	// Calcular década
-	decada, err := obtenerDecada(year)
-	if err != nil {
+	// Validar año
+	if _, err := obtenerDecada(year); err != nil {
 		http.Error(w, "Año inválido", http.StatusBadRequest)
-		return
+		return auditoria.Visualizacion{}, rutas.Acta{}, rutas.Archivo{}, false
+	}
+
+	// El acto debe existir y estar activo en el catálogo
+	catalogo, ok := validarActo(w, acto)
+	if !ok {
+		return auditoria.Visualizacion{}, rutas.Acta{}, rutas.Archivo{}, false
 	}
 
-	// Formatear con ceros a la izquierda
-	actoFmt := fmt.Sprintf("%1s", acto)
-	estado := "20"
-	municipioFmt := fmt.Sprintf("%03s", municipio)
-	oficialiaFmt := fmt.Sprintf("%02s", oficialia)
-	numActaFmt := fmt.Sprintf("%05s", numActa)
-	localidadFmt := fmt.Sprintf("%03s", localidad)
-
-	// Nombre del archivo PDF
-	fileName := fmt.Sprintf("%s%s%s%s%s%s%s0.pdf",
-		actoFmt, estado, municipioFmt, oficialiaFmt, year, numActaFmt, localidadFmt)
-
-	// Ruta del PDF
-	pdfPath := filepath.Join(
-		Cfg.PDFBasePath,
-		fmt.Sprintf("decada %s", decada),
-		actoFmt,
-		year,
-		municipioFmt,
-		oficialiaFmt,
-		localidadFmt,
-		fileName,
-	)
-
-	pythonURL := fmt.Sprintf("http://localhost:5000/pdf_to_tiles?pdf_path=%s", url.QueryEscape(pdfPath))
-
-	resp, err := http.Get(pythonURL)
+	visualizacion, err := nuevaVisualizacion(usuarioID, acto, municipio, oficialia, localidad, year, numActa)
 	if err != nil {
-		http.Error(w, "Error llamando microservicio", 500)
-		return
+		http.Error(w, "Parámetros inválidos", http.StatusBadRequest)
+		return visualizacion, rutas.Acta{}, rutas.Archivo{}, false
 	}
-	defer resp.Body.Close()
+	visualizacion.ActoNombre = catalogo.Nombre
 
-	body, err := ioutil.ReadAll(resp.Body)
+	// El municipio debe ser de un estado que atiende el visor; su estado y
+	// clave INEGI forman la ruta del PDF
+	estado, claveMun, err := claveMunicipio(visualizacion.Municipio)
+	if err == sql.ErrNoRows {
+		http.Error(w, "El municipio no existe o su estado no lo atiende este visor", http.StatusBadRequest)
+		return visualizacion, rutas.Acta{}, rutas.Archivo{}, false
+	}
 	if err != nil {
-		http.Error(w, "Error leyendo respuesta del microservicio", 500)
+		http.Error(w, "Error consultando municipio", http.StatusInternalServerError)
+		return visualizacion, rutas.Acta{}, rutas.Archivo{}, false
+	}
+
+	valida, err := oficialiaValida(visualizacion.Municipio, visualizacion.Oficialia, visualizacion.Anio)
+	if err != nil {
+		http.Error(w, "Error consultando oficialías", http.StatusInternalServerError)
+		return visualizacion, rutas.Acta{}, rutas.Archivo{}, false
+	}
+	if !valida {
+		http.Error(w, "La oficialía no existe en ese municipio o no operaba ese año", http.StatusBadRequest)
+		return visualizacion, rutas.Acta{}, rutas.Archivo{}, false
+	}
+	visualizacion.IP = clientIP(r)
+
+	acta := actaDeVisualizacion(visualizacion, estado, claveMun)
+	archivo, err := rutas.Buscar(acta)
+	if err != nil {
+		errorBusqueda(w, err)
+		return visualizacion, acta, archivo, false
+	}
+	return visualizacion, acta, archivo, true
+}
+
+// errorBusqueda responde al fallo de rutas.Buscar: 404 si el PDF no está en
+// ninguna raíz y 502 si alguna raíz no se pudo consultar
+func errorBusqueda(w http.ResponseWriter, err error) {
+	if errors.Is(err, rutas.ErrNoEncontrado) {
+		http.Error(w, "No se encontró el PDF del acta", http.StatusNotFound)
 		return
 	}
+	http.Error(w, "Error consultando el archivo de PDFs: "+err.Error(), http.StatusBadGateway)
+}
 
-	// Solo reenviar la respuesta JSON al frontend
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(body)
+// actaDeVisualizacion arma la búsqueda del PDF con el estado y la clave
+// INEGI del municipio
+func actaDeVisualizacion(v auditoria.Visualizacion, estado, claveMun int) rutas.Acta {
+	return rutas.Acta{Acto: v.Acto, Estado: estado, Municipio: claveMun, Oficialia: v.Oficialia,
+		Localidad: v.Localidad, Anio: v.Anio, NumActa: v.NumActa}
+}
+
+// ubicarActaRegistrada busca el PDF de un acta ya guardada en la base (una
+// visualización, una anotación). Si falla ya respondió al cliente.
+func ubicarActaRegistrada(w http.ResponseWriter, v auditoria.Visualizacion) (rutas.Acta, rutas.Archivo, bool) {
+	estado, claveMun, err := claveMunicipio(v.Municipio)
+	if err != nil {
+		http.Error(w, "Error consultando municipio", http.StatusInternalServerError)
+		return rutas.Acta{}, rutas.Archivo{}, false
+	}
+	acta := actaDeVisualizacion(v, estado, claveMun)
+	archivo, err := rutas.Buscar(acta)
+	if err != nil {
+		errorBusqueda(w, err)
+		return acta, archivo, false
+	}
+	return acta, archivo, true
 }
 
 func obtenerDecada(year string) (string, error) {
@@ -93,3 +249,35 @@ func obtenerDecada(year string) (string, error) {
 	decada := (y / 10) * 10
 	return fmt.Sprintf("%d", decada), nil
 }
+
+// nuevaVisualizacion convierte los parámetros de la petición en un registro de bitácora
+func nuevaVisualizacion(usuarioID int, acto, municipio, oficialia, localidad, year, numActa string) (auditoria.Visualizacion, error) {
+	v := auditoria.Visualizacion{UsuarioID: usuarioID, Acto: acto}
+	campos := []struct {
+		valor   string
+		destino *int
+	}{
+		{municipio, &v.Municipio},
+		{oficialia, &v.Oficialia},
+		{localidad, &v.Localidad},
+		{year, &v.Anio},
+		{numActa, &v.NumActa},
+	}
+	for _, c := range campos {
+		n, err := strconv.Atoi(c.valor)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("valor numérico inválido: %q", c.valor)
+		}
+		*c.destino = n
+	}
+	return v, nil
+}
+
+// clientIP obtiene la IP del cliente sin el puerto
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"visor-pdf/internal/auth"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/models"
+)
+
+// ListarPermisos devuelve los permisos otorgados por rol y por usuario
+func ListarPermisos(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query(`
+		SELECT p.id, p.permiso, p.rol_id, r.nombre, p.usuario_id, u.username
+		FROM permisos p
+		LEFT JOIN roles r ON p.rol_id = r.id
+		LEFT JOIN usuarios u ON p.usuario_id = u.id
+		ORDER BY p.permiso, p.usuario_id IS NOT NULL, r.nombre, u.username`)
+	if err != nil {
+		http.Error(w, "Error consultando permisos", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	permisos := []models.Permiso{}
+	for rows.Next() {
+		var p models.Permiso
+		var rolID, usuarioID sql.NullInt64
+		var rolNombre, username sql.NullString
+		if err := rows.Scan(&p.ID, &p.Permiso, &rolID, &rolNombre, &usuarioID, &username); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		p.RolID = enteroNulo(rolID)
+		p.UsuarioID = enteroNulo(usuarioID)
+		p.RolNombre = rolNombre.String
+		p.Username = username.String
+		permisos = append(permisos, p)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(permisos)
+}
+
+// OtorgarPermiso da un permiso a un rol o a un usuario
+func OtorgarPermiso(w http.ResponseWriter, r *http.Request) {
+	var p models.Permiso
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	if (p.RolID == nil) == (p.UsuarioID == nil) {
+		http.Error(w, "Indica rol_id o usuario_id (solo uno)", http.StatusBadRequest)
+		return
+	}
+	if !auth.PermisoValido(p.Permiso) {
+		http.Error(w, "Permiso desconocido", http.StatusBadRequest)
+		return
+	}
+
+	_, err := database.DB.Exec(`
+		INSERT INTO permisos (permiso, rol_id, usuario_id) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE permiso = permiso`,
+		p.Permiso, p.RolID, p.UsuarioID)
+	if err != nil {
+		http.Error(w, "Error guardando permiso: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Permiso otorgado exitosamente"})
+}
+
+// RevocarPermiso quita un permiso otorgado
+func RevocarPermiso(w http.ResponseWriter, r *http.Request) {
+	var datos struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&datos); err != nil || datos.ID == 0 {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := database.DB.Exec("DELETE FROM permisos WHERE id = ?", datos.ID); err != nil {
+		http.Error(w, "Error revocando permiso", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Permiso revocado exitosamente"})
+}
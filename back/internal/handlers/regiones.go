@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
+	"visor-pdf/internal/models"
+)
+
+// GetRegiones devuelve el catálogo de regiones de los estados que atiende
+// el despliegue, con sus distritos y el número de municipios mapeados a
+// cada distrito.
+func GetRegiones(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query(`
+		SELECT r.id, r.estado_id, r.nombre, d.id, d.nombre, COUNT(dm.municipio_id)
+		FROM regiones r
+		JOIN distritos d ON d.region_id = r.id
+		LEFT JOIN distrito_municipios dm ON dm.distrito_id = d.id
+		WHERE ` + estados.CondicionSQL("r.estado_id") + `
+		GROUP BY r.id, r.estado_id, r.nombre, d.id, d.nombre
+		ORDER BY r.estado_id, r.nombre, d.nombre`)
+	if err != nil {
+		http.Error(w, "Error consultando regiones: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	regiones := []models.Region{}
+	for rows.Next() {
+		var regionID, estadoID int
+		var regionNombre string
+		var d models.Distrito
+		if err := rows.Scan(&regionID, &estadoID, &regionNombre, &d.ID, &d.Nombre, &d.TotalMunicipios); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		d.RegionID = regionID
+
+		// Las filas vienen ordenadas por región, así que basta con revisar la última
+		if len(regiones) == 0 || regiones[len(regiones)-1].ID != regionID {
+			regiones = append(regiones, models.Region{ID: regionID, EstadoID: estadoID, Nombre: regionNombre})
+		}
+		ultima := &regiones[len(regiones)-1]
+		ultima.Distritos = append(ultima.Distritos, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(regiones)
+}
+
+// AsignarRegionesUsuario reemplaza las regiones y distritos asignados a un usuario
+// y, si se envía estados_ids, los estados completos.
+// Los municipios de cada estado, región o distrito se resuelven al consultar
+// (vista v_usuario_municipios), no se copian a usuario_municipios.
+func AsignarRegionesUsuario(w http.ResponseWriter, r *http.Request) {
+	var asignacion models.AsignacionRegiones
+	if err := json.NewDecoder(r.Body).Decode(&asignacion); err != nil {
+		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		return
+	}
+	if asignacion.UsuarioID == 0 {
+		http.Error(w, "Falta usuario_id", http.StatusBadRequest)
+		return
+	}
+	for _, estadoID := range asignacion.EstadosIDs {
+		if !estados.Sirve(estadoID) {
+			http.Error(w, fmt.Sprintf("El estado %d no lo atiende este visor", estadoID), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		http.Error(w, "Error iniciando transacción", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM usuario_regiones WHERE usuario_id = ?", asignacion.UsuarioID); err != nil {
+		http.Error(w, "Error eliminando regiones anteriores", http.StatusInternalServerError)
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM usuario_distritos WHERE usuario_id = ?", asignacion.UsuarioID); err != nil {
+		http.Error(w, "Error eliminando distritos anteriores", http.StatusInternalServerError)
+		return
+	}
+
+	if asignacion.EstadosIDs != nil {
+		if _, err := tx.Exec("DELETE FROM usuario_estados WHERE usuario_id = ?", asignacion.UsuarioID); err != nil {
+			http.Error(w, "Error eliminando estados anteriores", http.StatusInternalServerError)
+			return
+		}
+		for _, estadoID := range asignacion.EstadosIDs {
+			if _, err := tx.Exec(
+				"INSERT INTO usuario_estados (usuario_id, estado_id) VALUES (?, ?)",
+				asignacion.UsuarioID, estadoID); err != nil {
+				http.Error(w, "Error asignando estados: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	for _, regionID := range asignacion.RegionesIDs {
+		if _, err := tx.Exec(
+			"INSERT INTO usuario_regiones (usuario_id, region_id) VALUES (?, ?)",
+			asignacion.UsuarioID, regionID); err != nil {
+			http.Error(w, "Error asignando regiones: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	for _, distritoID := range asignacion.DistritosIDs {
+		if _, err := tx.Exec(
+			"INSERT INTO usuario_distritos (usuario_id, distrito_id) VALUES (?, ?)",
+			asignacion.UsuarioID, distritoID); err != nil {
+			http.Error(w, "Error asignando distritos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Error guardando asignaciones", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Regiones y distritos asignados exitosamente"})
+}
+
+// ObtenerRegionesUsuario devuelve los ids de estados, regiones y distritos asignados a un usuario
+func ObtenerRegionesUsuario(w http.ResponseWriter, r *http.Request) {
+	usuarioID, err := strconv.Atoi(r.URL.Query().Get("usuario_id"))
+	if err != nil {
+		http.Error(w, "usuario_id inválido", http.StatusBadRequest)
+		return
+	}
+
+	asignacion := models.AsignacionRegiones{UsuarioID: usuarioID, EstadosIDs: []int{}, RegionesIDs: []int{}, DistritosIDs: []int{}}
+
+	rows, err := database.DB.Query("SELECT estado_id FROM usuario_estados WHERE usuario_id = ?", usuarioID)
+	if err != nil {
+		http.Error(w, "Error consultando estados asignados", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		asignacion.EstadosIDs = append(asignacion.EstadosIDs, id)
+	}
+	rows.Close()
+
+	rows, err = database.DB.Query("SELECT region_id FROM usuario_regiones WHERE usuario_id = ?", usuarioID)
+	if err != nil {
+		http.Error(w, "Error consultando regiones asignadas", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		asignacion.RegionesIDs = append(asignacion.RegionesIDs, id)
+	}
+	rows.Close()
+
+	rows, err = database.DB.Query("SELECT distrito_id FROM usuario_distritos WHERE usuario_id = ?", usuarioID)
+	if err != nil {
+		http.Error(w, "Error consultando distritos asignados", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		asignacion.DistritosIDs = append(asignacion.DistritosIDs, id)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(asignacion)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
+	"visor-pdf/internal/integridad"
+	"visor-pdf/internal/models"
+	"visor-pdf/internal/reporte"
+)
+
+// problemaArchivoSQL obtiene el problema del PDF de cada acta de la última
+// pasada del verificador de integridad: vacío si está bien, NULL si no se
+// encontró. Si el acta tiene más de un archivo (en varias raíces) cuenta el
+// que está bien.
+const problemaArchivoSQL = `(
+	SELECT CASE
+		WHEN ap.estado = 'faltante' THEN 'no_existe'
+		WHEN ap.tamano = 0 THEN 'vacio'
+		WHEN ap.estado = 'corrupto' THEN 'corrupto'
+		ELSE '' END
+	FROM archivos_pdf ap
+	WHERE ap.acto = a.acto AND ap.municipio_id = a.municipio_id AND ap.oficialia = a.oficialia
+		AND ap.anio = a.anio AND ap.num_acta = a.num_acta AND ap.localidad = a.localidad
+	ORDER BY ap.estado IN ('ok', 'cambiado') AND ap.tamano > 0 DESC
+	LIMIT 1)`
+
+// ReporteCobertura resume las actas indexadas por municipio, oficialía, año
+// y acto: total, números faltantes dentro de la secuencia y números
+// repetidos (la misma acta en más de una localidad). Con archivos=1 también
+// indica los PDFs que no existen, están vacíos o no tienen estructura de PDF,
+// según la última pasada del verificador de integridad (archivos_pdf); no
+// abre los archivos durante la petición.
+//
+// Filtros opcionales: estado, municipio, oficialia, acto, anio_desde, anio_hasta.
+// formato=json (por defecto), csv o xlsx. Solo para administradores: el
+// inventario completo de actas y sus faltantes no es para cada capturista.
+func ReporteCobertura(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	formato := query.Get("formato")
+	if formato == "" {
+		formato = "json"
+	}
+	if formato != "json" && formato != "csv" && formato != "xlsx" {
+		http.Error(w, "formato debe ser json, csv o xlsx", http.StatusBadRequest)
+		return
+	}
+	revisarArchivos := query.Get("archivos") == "1"
+
+	condiciones := []string{estados.CondicionSQL("m.estado_id")}
+	var args []interface{}
+	filtros := []struct {
+		parametro, condicion string
+	}{
+		{"estado", "m.estado_id = ?"},
+		{"municipio", "a.municipio_id = ?"},
+		{"oficialia", "a.oficialia = ?"},
+		{"anio_desde", "a.anio >= ?"},
+		{"anio_hasta", "a.anio <= ?"},
+	}
+	for _, f := range filtros {
+		if v := query.Get(f.parametro); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, f.parametro+" inválido", http.StatusBadRequest)
+				return
+			}
+			condiciones = append(condiciones, f.condicion)
+			args = append(args, n)
+		}
+	}
+	if v := query.Get("acto"); v != "" {
+		condiciones = append(condiciones, "a.acto = ?")
+		args = append(args, v)
+	}
+	where := "WHERE " + strings.Join(condiciones, " AND ")
+
+	problemaArchivo := "''"
+	if revisarArchivos {
+		// Sin ninguna pasada del verificador todas las actas saldrían sin PDF
+		var verificados bool
+		if err := database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM archivos_pdf)").Scan(&verificados); err != nil {
+			http.Error(w, "Error consultando archivos", http.StatusInternalServerError)
+			return
+		}
+		if !verificados {
+			http.Error(w, "El verificador de integridad aún no ha registrado ningún PDF; ejecútalo antes de revisar archivos",
+				http.StatusConflict)
+			return
+		}
+		problemaArchivo = problemaArchivoSQL
+	}
+
+	// Las filas llegan ordenadas por grupo y número para recorrerlas una vez
+	rows, err := database.DB.Query(`
+		SELECT m.estado_id, a.municipio_id, m.nombre, a.oficialia, a.anio, a.acto, COALESCE(ac.nombre, ''),
+			a.num_acta, a.localidad, `+problemaArchivo+`
+		FROM actas a
+		JOIN municipios m ON a.municipio_id = m.idmunicipios
+		LEFT JOIN actos ac ON a.acto = ac.codigo
+		`+where+`
+		ORDER BY a.municipio_id, a.oficialia, a.anio, a.acto, a.num_acta, a.localidad`, args...)
+	if err != nil {
+		http.Error(w, "Error consultando actas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	grupos := []models.CoberturaGrupo{}
+	var g *models.CoberturaGrupo
+	anterior := 0
+	for rows.Next() {
+		var fila models.CoberturaGrupo
+		var numActa, localidad int
+		var problema sql.NullString
+		if err := rows.Scan(&fila.EstadoID, &fila.MunicipioID, &fila.Municipio, &fila.Oficialia, &fila.Anio, &fila.Acto,
+			&fila.ActoNombre, &numActa, &localidad, &problema); err != nil {
+			http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if g == nil || g.MunicipioID != fila.MunicipioID || g.Oficialia != fila.Oficialia ||
+			g.Anio != fila.Anio || g.Acto != fila.Acto {
+			fila.Primera = numActa
+			fila.Faltantes = []string{}
+			fila.Duplicados = []int{}
+			fila.ArchivosRevisados = revisarArchivos
+			grupos = append(grupos, fila)
+			g = &grupos[len(grupos)-1]
+		} else if numActa == anterior {
+			if len(g.Duplicados) == 0 || g.Duplicados[len(g.Duplicados)-1] != numActa {
+				g.Duplicados = append(g.Duplicados, numActa)
+			}
+		} else if numActa > anterior+1 {
+			g.TotalFaltantes += numActa - anterior - 1
+			g.Faltantes = append(g.Faltantes, rango(anterior+1, numActa-1))
+		}
+		g.Total++
+		g.Ultima = numActa
+		anterior = numActa
+
+		if revisarArchivos {
+			// Un acta sin registro en archivos_pdf no tiene PDF
+			if !problema.Valid {
+				problema.String = integridad.ProblemaNoExiste
+			}
+			if problema.String != "" {
+				g.Problemas = append(g.Problemas, models.ProblemaArchivo{
+					NumActa: numActa, Localidad: localidad, Problema: problema.String,
+				})
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Error leyendo datos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if formato == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(grupos)
+		return
+	}
+
+	tabla := tablaCobertura(grupos, revisarArchivos)
+	var buf bytes.Buffer
+	if formato == "csv" {
+		err = reporte.EscribirCSV(&buf, tabla)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		err = reporte.EscribirXLSX(&buf, tabla)
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	}
+	if err != nil {
+		http.Error(w, "Error generando reporte", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="cobertura.`+formato+`"`)
+	w.Write(buf.Bytes())
+}
+
+// tablaCobertura aplana los grupos para CSV y XLSX
+func tablaCobertura(grupos []models.CoberturaGrupo, revisarArchivos bool) reporte.Tabla {
+	t := reporte.Tabla{
+		Hoja: "Cobertura",
+		Encabezado: []string{"estado_id", "municipio_id", "municipio", "oficialia", "anio", "acto", "acto_nombre",
+			"total", "primera", "ultima", "total_faltantes", "faltantes", "duplicados"},
+	}
+	if revisarArchivos {
+		t.Encabezado = append(t.Encabezado, "no_existe", "vacios", "corruptos")
+	}
+	for _, g := range grupos {
+		duplicados := make([]string, len(g.Duplicados))
+		for i, d := range g.Duplicados {
+			duplicados[i] = strconv.Itoa(d)
+		}
+		fila := []string{
+			strconv.Itoa(g.EstadoID), strconv.Itoa(g.MunicipioID), g.Municipio, strconv.Itoa(g.Oficialia), strconv.Itoa(g.Anio),
+			g.Acto, g.ActoNombre, strconv.Itoa(g.Total), strconv.Itoa(g.Primera), strconv.Itoa(g.Ultima),
+			strconv.Itoa(g.TotalFaltantes), strings.Join(g.Faltantes, ", "), strings.Join(duplicados, ", "),
+		}
+		if revisarArchivos {
+			conteo := map[string][]string{}
+			for _, p := range g.Problemas {
+				conteo[p.Problema] = append(conteo[p.Problema], strconv.Itoa(p.NumActa))
+			}
+			fila = append(fila, strings.Join(conteo["no_existe"], ", "),
+				strings.Join(conteo["vacio"], ", "), strings.Join(conteo["corrupto"], ", "))
+		}
+		t.Filas = append(t.Filas, fila)
+	}
+	return t
+}
+
+func rango(desde, hasta int) string {
+	if desde == hasta {
+		return strconv.Itoa(desde)
+	}
+	return fmt.Sprintf("%d-%d", desde, hasta)
+}
@@ -0,0 +1,106 @@
+package imagen
+
+import (
+	"encoding/xml"
+	"image"
+	"math"
+)
+
+// Piramide es la geometría Deep Zoom (DZI) de una página. El nivel más alto
+// es la página completa renderizada a Zoom píxeles por punto PDF; cada
+// nivel inferior mide la mitad, hasta el nivel 0 de 1x1 píxel.
+type Piramide struct {
+	Ancho, Alto int     // píxeles del nivel más alto
+	Zoom        float64 // píxeles por punto en el nivel más alto
+	Tile        int     // lado de los tiles sin traslape
+	Traslape    int     // píxeles que cada tile comparte con sus vecinos
+}
+
+// NuevaPiramide calcula la pirámide de una página de anchoPt x altoPt puntos
+func NuevaPiramide(anchoPt, altoPt, zoom float64, tile, traslape int) Piramide {
+	return Piramide{
+		Ancho:    int(math.Ceil(anchoPt * zoom)),
+		Alto:     int(math.Ceil(altoPt * zoom)),
+		Zoom:     zoom,
+		Tile:     tile,
+		Traslape: traslape,
+	}
+}
+
+// NivelMaximo es el nivel de la página completa a Zoom
+func (p Piramide) NivelMaximo() int {
+	lado := p.Ancho
+	if p.Alto > lado {
+		lado = p.Alto
+	}
+	return int(math.Ceil(math.Log2(float64(lado))))
+}
+
+// Dimensiones devuelve el tamaño en píxeles del nivel
+func (p Piramide) Dimensiones(nivel int) (int, int) {
+	divisor := math.Exp2(float64(p.NivelMaximo() - nivel))
+	return int(math.Ceil(float64(p.Ancho) / divisor)), int(math.Ceil(float64(p.Alto) / divisor))
+}
+
+// Escala devuelve los píxeles por punto PDF del nivel
+func (p Piramide) Escala(nivel int) float64 {
+	return p.Zoom / math.Exp2(float64(p.NivelMaximo()-nivel))
+}
+
+// Tiles devuelve cuántas columnas y filas de tiles tiene el nivel
+func (p Piramide) Tiles(nivel int) (int, int) {
+	ancho, alto := p.Dimensiones(nivel)
+	return (ancho + p.Tile - 1) / p.Tile, (alto + p.Tile - 1) / p.Tile
+}
+
+// RectTile devuelve el rectángulo en píxeles del nivel que cubre el tile
+// (col, fila), con el traslape hacia los tiles vecinos. ok es false si el
+// nivel o el tile no existen.
+func (p Piramide) RectTile(nivel, col, fila int) (image.Rectangle, bool) {
+	if nivel < 0 || nivel > p.NivelMaximo() {
+		return image.Rectangle{}, false
+	}
+	cols, filas := p.Tiles(nivel)
+	if col < 0 || fila < 0 || col >= cols || fila >= filas {
+		return image.Rectangle{}, false
+	}
+	ancho, alto := p.Dimensiones(nivel)
+	r := image.Rect(col*p.Tile, fila*p.Tile, (col+1)*p.Tile, (fila+1)*p.Tile)
+	if col > 0 {
+		r.Min.X -= p.Traslape
+	}
+	if fila > 0 {
+		r.Min.Y -= p.Traslape
+	}
+	r.Max.X = minimo(r.Max.X+p.Traslape, ancho)
+	r.Max.Y = minimo(r.Max.Y+p.Traslape, alto)
+	return r, true
+}
+
+// Descriptor devuelve el XML .dzi de la pirámide
+func (p Piramide) Descriptor(formato string) ([]byte, error) {
+	d := struct {
+		XMLName  xml.Name `xml:"http://schemas.microsoft.com/deepzoom/2008 Image"`
+		Format   string   `xml:"Format,attr"`
+		Overlap  int      `xml:"Overlap,attr"`
+		TileSize int      `xml:"TileSize,attr"`
+		Size     struct {
+			Width  int `xml:"Width,attr"`
+			Height int `xml:"Height,attr"`
+		} `xml:"Size"`
+	}{Format: formato, Overlap: p.Traslape, TileSize: p.Tile}
+	d.Size.Width, d.Size.Height = p.Ancho, p.Alto
+
+	datos, err := xml.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), datos...), nil
+}
+
+func minimo(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
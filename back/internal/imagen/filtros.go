@@ -0,0 +1,487 @@
+package imagen
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Filtros de mejora para actas desvaídas o amarillentas.
+//
+// Se aplican sobre los rasters ya renderizados, en el orden pedido, salvo
+// enderezar, que siempre va primero. Los que dependen de la página completa
+// (el recorte de contraste y la inclinación automática) se miden una vez
+// sobre la página y se pasan como Referencia a cada tile, para que los
+// tiles contiguos queden iguales en sus bordes.
+
+// Filtro es un paso de la cadena con su parámetro
+type Filtro struct {
+	Nombre   string
+	Valor    float64
+	ConValor bool // false = valor predeterminado (en enderezar, automático)
+}
+
+// Filtros es una cadena de filtros lista para aplicarse
+type Filtros []Filtro
+
+type definicionFiltro struct {
+	predeterminado float64
+	minimo, maximo float64
+	conParametro   bool
+}
+
+// Filtros conocidos y el rango de su parámetro:
+//
+//	gris             escala de grises
+//	invertir         negativo
+//	contraste:p      estira la luminancia recortando p% en cada extremo (1)
+//	gamma:g          eleva a g; >1 oscurece los tonos medios (1.5)
+//	umbral:r         binarización adaptativa con ventana de radio r puntos (8)
+//	enfoque:a        máscara de enfoque de intensidad a (1)
+//	enderezar:grados corrige la inclinación; sin grados la estima (±10)
+var definicionesFiltro = map[string]definicionFiltro{
+	"gris":      {},
+	"invertir":  {},
+	"contraste": {predeterminado: 1, minimo: 0, maximo: 20, conParametro: true},
+	"gamma":     {predeterminado: 1.5, minimo: 0.2, maximo: 5, conParametro: true},
+	"umbral":    {predeterminado: 8, minimo: 1, maximo: 50, conParametro: true},
+	"enfoque":   {predeterminado: 1, minimo: 0.1, maximo: 5, conParametro: true},
+	"enderezar": {minimo: -10, maximo: 10, conParametro: true},
+}
+
+const (
+	maxFiltros         = 8
+	maxInclinacionAuto = 5.0 // grados que se prueban al estimar la inclinación
+)
+
+// ParsearFiltros interpreta la lista "gris,contraste,gamma:1.8" del
+// parámetro filtros. Una cadena vacía no aplica ningún filtro.
+func ParsearFiltros(texto string) (Filtros, error) {
+	var filtros Filtros
+	vistos := map[string]bool{}
+	for _, parte := range strings.Split(texto, ",") {
+		parte = strings.ToLower(strings.TrimSpace(parte))
+		if parte == "" {
+			continue
+		}
+		nombre, valor, conValor := strings.Cut(parte, ":")
+		def, ok := definicionesFiltro[nombre]
+		if !ok {
+			return nil, fmt.Errorf("filtro desconocido: %s", nombre)
+		}
+		if vistos[nombre] {
+			return nil, fmt.Errorf("filtro repetido: %s", nombre)
+		}
+		vistos[nombre] = true
+
+		f := Filtro{Nombre: nombre, Valor: def.predeterminado}
+		if conValor {
+			if !def.conParametro {
+				return nil, fmt.Errorf("el filtro %s no lleva valor", nombre)
+			}
+			v, err := strconv.ParseFloat(valor, 64)
+			if err != nil || math.IsNaN(v) || v < def.minimo || v > def.maximo {
+				return nil, fmt.Errorf("valor de %s fuera de rango (%g a %g)", nombre, def.minimo, def.maximo)
+			}
+			f.Valor, f.ConValor = v, true
+		}
+		if nombre == "enderezar" {
+			filtros = append(Filtros{f}, filtros...)
+		} else {
+			filtros = append(filtros, f)
+		}
+	}
+	if len(filtros) > maxFiltros {
+		return nil, fmt.Errorf("máximo %d filtros", maxFiltros)
+	}
+	return filtros, nil
+}
+
+// String devuelve la forma canónica de la cadena, para claves de caché y
+// para la bitácora
+func (f Filtros) String() string {
+	partes := make([]string, len(f))
+	for i, filtro := range f {
+		partes[i] = filtro.Nombre
+		if filtro.ConValor {
+			partes[i] += ":" + strconv.FormatFloat(filtro.Valor, 'g', -1, 64)
+		}
+	}
+	return strings.Join(partes, ",")
+}
+
+// NecesitaReferencia indica si algún filtro se mide sobre la página completa
+func (f Filtros) NecesitaReferencia() bool {
+	for _, filtro := range f {
+		if filtro.Nombre == "contraste" || (filtro.Nombre == "enderezar" && !filtro.ConValor) {
+			return true
+		}
+	}
+	return false
+}
+
+// Region ubica la imagen que se filtra dentro de la página renderizada
+type Region struct {
+	Rect   image.Rectangle // posición de la imagen en la página, en píxeles
+	Pagina image.Point     // tamaño de la página renderizada
+	Escala float64         // píxeles por punto PDF
+}
+
+// Referencia son los valores medidos sobre la página completa
+type Referencia struct {
+	Bajo        uint8   `json:"bajo"` // luminancias que estira contraste
+	Alto        uint8   `json:"alto"`
+	Inclinacion float64 `json:"inclinacion"` // grados que corrige enderezar
+}
+
+// Margen devuelve cuántos píxeles alrededor de Rect necesita la cadena para
+// que el resultado dentro de Rect sea igual al de la página completa: lo
+// que mueve la rotación y la ventana de la binarización y del enfoque
+func (f Filtros) Margen(r Region, ref Referencia) int {
+	margen := 0
+	for _, filtro := range f {
+		switch filtro.Nombre {
+		case "enderezar":
+			grados := ref.Inclinacion
+			if filtro.ConValor {
+				grados = filtro.Valor
+			}
+			centroX, centroY := float64(r.Pagina.X)/2, float64(r.Pagina.Y)/2
+			distancia := 0.0
+			for _, p := range []image.Point{r.Rect.Min, r.Rect.Max, {r.Rect.Min.X, r.Rect.Max.Y}, {r.Rect.Max.X, r.Rect.Min.Y}} {
+				distancia = math.Max(distancia, math.Hypot(float64(p.X)-centroX, float64(p.Y)-centroY))
+			}
+			margen += int(math.Ceil(distancia*math.Abs(grados)*math.Pi/180)) + 2
+		case "umbral":
+			margen += radioUmbral(filtro.Valor, r.Escala) + 1
+		case "enfoque":
+			margen++
+		}
+	}
+	return margen
+}
+
+// Aplicar ejecuta la cadena sobre la imagen, que ocupa r.Rect en la página.
+// Con ref nil la imagen debe ser la página completa: los valores de
+// referencia se miden sobre ella y se devuelven para filtrar sus tiles.
+func (f Filtros) Aplicar(img image.Image, r Region, ref *Referencia) (*image.RGBA, Referencia) {
+	dst := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	var medida Referencia
+	if ref != nil {
+		medida = *ref
+	}
+	for _, filtro := range f {
+		switch filtro.Nombre {
+		case "gris":
+			gris(dst)
+		case "invertir":
+			aplicarTabla(dst, tabla(func(v float64) float64 { return 255 - v }))
+		case "gamma":
+			aplicarTabla(dst, tabla(func(v float64) float64 { return 255 * math.Pow(v/255, filtro.Valor) }))
+		case "contraste":
+			if ref == nil {
+				medida.Bajo, medida.Alto = percentiles(dst, filtro.Valor)
+			}
+			estirarContraste(dst, medida.Bajo, medida.Alto)
+		case "umbral":
+			binarizar(dst, radioUmbral(filtro.Valor, r.Escala))
+		case "enfoque":
+			dst = enfocar(dst, filtro.Valor)
+		case "enderezar":
+			switch {
+			case filtro.ConValor:
+				medida.Inclinacion = filtro.Valor
+			case ref == nil:
+				medida.Inclinacion = EstimarInclinacion(dst)
+			}
+			dst = rotar(dst, r, medida.Inclinacion)
+		}
+	}
+	return dst, medida
+}
+
+// radioUmbral convierte el radio de la ventana de puntos a píxeles
+func radioUmbral(puntos, escala float64) int {
+	radio := int(math.Round(puntos * escala))
+	if radio < 1 {
+		return 1
+	}
+	return radio
+}
+
+func luminancia(r, g, b uint8) float64 {
+	return 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+}
+
+func gris(img *image.RGBA) {
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		l := saturar(luminancia(img.Pix[i], img.Pix[i+1], img.Pix[i+2]))
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2] = l, l, l
+	}
+}
+
+// tabla precalcula una función de 0-255 en 0-255
+func tabla(fn func(float64) float64) [256]uint8 {
+	var t [256]uint8
+	for v := range t {
+		t[v] = saturar(fn(float64(v)))
+	}
+	return t
+}
+
+func aplicarTabla(img *image.RGBA, t [256]uint8) {
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2] = t[img.Pix[i]], t[img.Pix[i+1]], t[img.Pix[i+2]]
+	}
+}
+
+// percentiles devuelve las luminancias que dejan fuera el pct% más oscuro
+// y el pct% más claro de la imagen
+func percentiles(img *image.RGBA, pct float64) (uint8, uint8) {
+	var hist [256]int
+	total := 0
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		hist[saturar(luminancia(img.Pix[i], img.Pix[i+1], img.Pix[i+2]))]++
+		total++
+	}
+	corte := int(float64(total) * pct / 100)
+	bajo, alto := 0, 255
+	for acumulado := 0; bajo < 255; bajo++ {
+		acumulado += hist[bajo]
+		if acumulado > corte {
+			break
+		}
+	}
+	for acumulado := 0; alto > 0; alto-- {
+		acumulado += hist[alto]
+		if acumulado > corte {
+			break
+		}
+	}
+	return uint8(bajo), uint8(alto)
+}
+
+func estirarContraste(img *image.RGBA, bajo, alto uint8) {
+	if alto <= bajo {
+		return
+	}
+	rango := float64(alto) - float64(bajo)
+	aplicarTabla(img, tabla(func(v float64) float64 { return (v - float64(bajo)) * 255 / rango }))
+}
+
+// binarizar aplica el método de Bradley: un píxel es tinta si es un 15%
+// más oscuro que el promedio de su ventana. Con tinta desvaída funciona
+// mejor que un umbral global porque el papel amarillea de forma desigual.
+func binarizar(img *image.RGBA, radio int) {
+	ancho, alto := img.Rect.Dx(), img.Rect.Dy()
+	// Imagen integral de la luminancia, con una fila y columna de ceros
+	integral := make([]float64, (ancho+1)*(alto+1))
+	lum := make([]float64, ancho*alto)
+	for y := 0; y < alto; y++ {
+		fila := 0.0
+		for x := 0; x < ancho; x++ {
+			i := img.PixOffset(x+img.Rect.Min.X, y+img.Rect.Min.Y)
+			l := luminancia(img.Pix[i], img.Pix[i+1], img.Pix[i+2])
+			lum[y*ancho+x] = l
+			fila += l
+			integral[(y+1)*(ancho+1)+x+1] = integral[y*(ancho+1)+x+1] + fila
+		}
+	}
+	for y := 0; y < alto; y++ {
+		y0, y1 := maximo(y-radio, 0), minimo(y+radio+1, alto)
+		for x := 0; x < ancho; x++ {
+			x0, x1 := maximo(x-radio, 0), minimo(x+radio+1, ancho)
+			suma := integral[y1*(ancho+1)+x1] - integral[y0*(ancho+1)+x1] -
+				integral[y1*(ancho+1)+x0] + integral[y0*(ancho+1)+x0]
+			promedio := suma / float64((x1-x0)*(y1-y0))
+			v := uint8(255)
+			if lum[y*ancho+x] < promedio*0.85 {
+				v = 0
+			}
+			i := img.PixOffset(x+img.Rect.Min.X, y+img.Rect.Min.Y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2] = v, v, v
+		}
+	}
+}
+
+// enfocar aplica una máscara de enfoque con desenfoque de caja 3x3
+func enfocar(img *image.RGBA, cantidad float64) *image.RGBA {
+	b := img.Rect
+	dst := image.NewRGBA(b)
+	copy(dst.Pix, img.Pix)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var suma [3]float64
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					vx := minimo(maximo(x+dx, b.Min.X), b.Max.X-1)
+					vy := minimo(maximo(y+dy, b.Min.Y), b.Max.Y-1)
+					i := img.PixOffset(vx, vy)
+					suma[0] += float64(img.Pix[i])
+					suma[1] += float64(img.Pix[i+1])
+					suma[2] += float64(img.Pix[i+2])
+				}
+			}
+			i := img.PixOffset(x, y)
+			for c := 0; c < 3; c++ {
+				v := float64(img.Pix[i+c])
+				dst.Pix[i+c] = saturar(v + cantidad*(v-suma[c]/9))
+			}
+		}
+	}
+	return dst
+}
+
+// rotar gira la imagen los grados indicados alrededor del centro de la
+// página (no de la imagen), para que los tiles giren como una sola pieza.
+// Lo que queda fuera del área renderizada se rellena de blanco.
+func rotar(img *image.RGBA, r Region, grados float64) *image.RGBA {
+	if grados == 0 {
+		return img
+	}
+	ancho, alto := img.Rect.Dx(), img.Rect.Dy()
+	dst := image.NewRGBA(img.Rect)
+	seno, coseno := math.Sincos(grados * math.Pi / 180)
+	centroX, centroY := float64(r.Pagina.X)/2, float64(r.Pagina.Y)/2
+
+	for y := 0; y < alto; y++ {
+		for x := 0; x < ancho; x++ {
+			// Posición en la página del píxel de salida y de su origen
+			dx := float64(r.Rect.Min.X+x) + 0.5 - centroX
+			dy := float64(r.Rect.Min.Y+y) + 0.5 - centroY
+			sx := centroX + dx*coseno - dy*seno - float64(r.Rect.Min.X) - 0.5
+			sy := centroY + dx*seno + dy*coseno - float64(r.Rect.Min.Y) - 0.5
+
+			i := dst.PixOffset(x+img.Rect.Min.X, y+img.Rect.Min.Y)
+			c := bilineal(img, sx, sy)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = c[0], c[1], c[2], 255
+		}
+	}
+	return dst
+}
+
+// bilineal interpola el color en una posición fraccionaria de la imagen
+// (relativa a su esquina); fuera de ella devuelve blanco
+func bilineal(img *image.RGBA, x, y float64) [3]uint8 {
+	ancho, alto := img.Rect.Dx(), img.Rect.Dy()
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+	var suma [3]float64
+	for _, p := range [4]struct {
+		x, y int
+		peso float64
+	}{
+		{x0, y0, (1 - fx) * (1 - fy)}, {x0 + 1, y0, fx * (1 - fy)},
+		{x0, y0 + 1, (1 - fx) * fy}, {x0 + 1, y0 + 1, fx * fy},
+	} {
+		if p.x < 0 || p.y < 0 || p.x >= ancho || p.y >= alto {
+			for c := range suma {
+				suma[c] += 255 * p.peso
+			}
+			continue
+		}
+		i := img.PixOffset(p.x+img.Rect.Min.X, p.y+img.Rect.Min.Y)
+		for c := range suma {
+			suma[c] += float64(img.Pix[i+c]) * p.peso
+		}
+	}
+	return [3]uint8{saturar(suma[0]), saturar(suma[1]), saturar(suma[2])}
+}
+
+// EstimarInclinacion devuelve los grados que están inclinados los
+// renglones de la página (positivo = bajan hacia la derecha). Prueba
+// ángulos de ±5° y se queda con el que concentra más la tinta en pocos
+// renglones (perfil de proyección con mayor varianza).
+func EstimarInclinacion(img *image.RGBA) float64 {
+	ancho, alto := img.Rect.Dx(), img.Rect.Dy()
+	// Tinta = píxeles más oscuros que el umbral de Otsu; se muestrea uno de
+	// cada dos en cada eje para acotar el trabajo
+	var hist [256]int
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		hist[saturar(luminancia(img.Pix[i], img.Pix[i+1], img.Pix[i+2]))]++
+	}
+	corte := otsu(hist)
+	var tinta []image.Point
+	for y := 0; y < alto; y += 2 {
+		for x := 0; x < ancho; x += 2 {
+			i := img.PixOffset(x+img.Rect.Min.X, y+img.Rect.Min.Y)
+			if saturar(luminancia(img.Pix[i], img.Pix[i+1], img.Pix[i+2])) < corte {
+				tinta = append(tinta, image.Point{x, y})
+			}
+		}
+	}
+	if len(tinta) == 0 {
+		return 0
+	}
+
+	diagonal := int(math.Hypot(float64(ancho), float64(alto))) + 2
+	perfil := make([]int, 2*diagonal)
+	mejor, mejorPuntaje := 0.0, -1.0
+	for decimas := -int(maxInclinacionAuto * 10); decimas <= int(maxInclinacionAuto*10); decimas++ {
+		grados := float64(decimas) / 10
+		seno, coseno := math.Sincos(grados * math.Pi / 180)
+		for i := range perfil {
+			perfil[i] = 0
+		}
+		for _, p := range tinta {
+			// Renglón del punto si la página se girara -grados
+			fila := int(math.Round(float64(p.Y)*coseno-float64(p.X)*seno)) + diagonal
+			perfil[fila]++
+		}
+		puntaje := 0.0
+		for _, n := range perfil {
+			puntaje += float64(n) * float64(n)
+		}
+		// Ante empates gana el ángulo más cercano a 0
+		if puntaje > mejorPuntaje || (puntaje == mejorPuntaje && math.Abs(grados) < math.Abs(mejor)) {
+			mejor, mejorPuntaje = grados, puntaje
+		}
+	}
+	return mejor
+}
+
+// otsu calcula el umbral que mejor separa tinta y papel en el histograma
+func otsu(hist [256]int) uint8 {
+	total, sumaTotal := 0, 0.0
+	for v, n := range hist {
+		total += n
+		sumaTotal += float64(v * n)
+	}
+	// Clases: oscuros (0..v) y claros (v+1..255)
+	var oscuros int
+	var sumaOscuros, mejorVarianza float64
+	umbral := 128
+	for v := 0; v < 256; v++ {
+		oscuros += hist[v]
+		sumaOscuros += float64(v * hist[v])
+		if oscuros == 0 {
+			continue
+		}
+		claros := total - oscuros
+		if claros == 0 {
+			break
+		}
+		mediaOscuros := sumaOscuros / float64(oscuros)
+		mediaClaros := (sumaTotal - sumaOscuros) / float64(claros)
+		varianza := float64(oscuros) * float64(claros) * (mediaOscuros - mediaClaros) * (mediaOscuros - mediaClaros)
+		if varianza > mejorVarianza {
+			mejorVarianza, umbral = varianza, v+1
+		}
+	}
+	if umbral > 255 {
+		umbral = 255
+	}
+	return uint8(umbral)
+}
+
+func maximo(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,245 @@
+package imagen
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestParsearFiltros(t *testing.T) {
+	casos := []struct {
+		texto    string
+		canonica string
+		error    bool
+	}{
+		{"", "", false},
+		{"gris", "gris", false},
+		{" Gris , CONTRASTE ", "gris,contraste", false},
+		{"gris,,invertir", "gris,invertir", false},
+		{"gamma:1.8,gris", "gamma:1.8,gris", false},
+		{"gamma:1.50", "gamma:1.5", false},
+		{"contraste:0", "contraste:0", false},
+		{"contraste:20", "contraste:20", false},
+		{"umbral:1,enfoque:5", "umbral:1,enfoque:5", false},
+		// enderezar siempre va primero
+		{"gris,enderezar:2", "enderezar:2,gris", false},
+		{"invertir,gris,enderezar", "enderezar,invertir,gris", false},
+		{"enderezar:-10", "enderezar:-10", false},
+
+		{"borroso", "", true},
+		{"gris,gris", "", true},
+		{"contraste,contraste:2", "", true},
+		{"gris:1", "", true},
+		{"invertir:0", "", true},
+		{"contraste:21", "", true},
+		{"contraste:-1", "", true},
+		{"gamma:0.1", "", true},
+		{"gamma:NaN", "", true},
+		{"gamma:abc", "", true},
+		{"gamma:", "", true},
+		{"umbral:0", "", true},
+		{"enfoque:6", "", true},
+		{"enderezar:10.5", "", true},
+	}
+	for _, c := range casos {
+		filtros, err := ParsearFiltros(c.texto)
+		if c.error {
+			if err == nil {
+				t.Errorf("ParsearFiltros(%q) = %v, se esperaba error", c.texto, filtros)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsearFiltros(%q): %v", c.texto, err)
+			continue
+		}
+		if got := filtros.String(); got != c.canonica {
+			t.Errorf("ParsearFiltros(%q).String() = %q, se esperaba %q", c.texto, got, c.canonica)
+		}
+		// La forma canónica es la clave de caché: debe volver a dar lo mismo
+		otra, err := ParsearFiltros(filtros.String())
+		if err != nil || otra.String() != c.canonica {
+			t.Errorf("ParsearFiltros(%q) = %v, %v; se esperaba %q", filtros.String(), otra, err, c.canonica)
+		}
+	}
+}
+
+func TestParsearFiltrosValores(t *testing.T) {
+	filtros, err := ParsearFiltros("contraste,gamma:2.5,enderezar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	esperados := Filtros{
+		{Nombre: "enderezar"},
+		{Nombre: "contraste", Valor: 1},
+		{Nombre: "gamma", Valor: 2.5, ConValor: true},
+	}
+	if len(filtros) != len(esperados) {
+		t.Fatalf("filtros = %+v, se esperaba %+v", filtros, esperados)
+	}
+	for i := range esperados {
+		if filtros[i] != esperados[i] {
+			t.Errorf("filtro %d = %+v, se esperaba %+v", i, filtros[i], esperados[i])
+		}
+	}
+}
+
+func TestNecesitaReferencia(t *testing.T) {
+	casos := []struct {
+		texto string
+		ref   bool
+	}{
+		{"", false},
+		{"gris,invertir,gamma,umbral,enfoque", false},
+		{"enderezar:3", false},
+		{"enderezar", true},
+		{"gris,contraste:5", true},
+	}
+	for _, c := range casos {
+		filtros, err := ParsearFiltros(c.texto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := filtros.NecesitaReferencia(); got != c.ref {
+			t.Errorf("%q: NecesitaReferencia = %v, se esperaba %v", c.texto, got, c.ref)
+		}
+	}
+}
+
+// actaPrueba genera una página de w x h con renglones de "texto" gris
+// oscuro sobre papel amarillento que se oscurece hacia la derecha, con
+// los renglones inclinados los grados indicados
+func actaPrueba(w, h int, grados float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	pendiente := math.Tan(grados * math.Pi / 180)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			papel := 235 - 40*x/w
+			c := color.RGBA{uint8(papel), uint8(papel - 10), uint8(papel - 50), 255}
+			renglon := int(math.Floor(float64(y)-float64(x)*pendiente)) - 10
+			if renglon >= 0 && renglon%16 < 4 && (x/7)%5 != 4 && x > 10 && x < w-10 {
+				c = color.RGBA{70, 60, 50, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestFiltrosTilesSinCosturas(t *testing.T) {
+	// Filtrar un tile con su margen y la referencia de la página debe dar
+	// los mismos píxeles que filtrar la página completa y recortarla
+	const ancho, alto, lado = 200, 160, 64
+	pagina := actaPrueba(ancho, alto, 1.5)
+	tamano := image.Pt(ancho, alto)
+	const escala = 1.0
+
+	for _, texto := range []string{
+		"contraste",
+		"gris,gamma,contraste:5,invertir",
+		"umbral:2",
+		"enfoque:2",
+		"enderezar:3",
+		"enderezar",
+		"enderezar:-2,umbral:1,enfoque",
+	} {
+		filtros, err := ParsearFiltros(texto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		completa, ref := filtros.Aplicar(pagina, Region{Rect: pagina.Rect, Pagina: tamano, Escala: escala}, nil)
+
+		for y := 0; y < alto; y += lado {
+			for x := 0; x < ancho; x += lado {
+				rect := image.Rect(x, y, x+lado, y+lado).Intersect(pagina.Rect)
+				margen := filtros.Margen(Region{Rect: rect, Pagina: tamano, Escala: escala}, ref)
+				ampliado := rect.Inset(-margen).Intersect(pagina.Rect)
+				tile, _ := filtros.Aplicar(pagina.SubImage(ampliado),
+					Region{Rect: ampliado, Pagina: tamano, Escala: escala}, &ref)
+
+				diferentes := 0
+				for py := rect.Min.Y; py < rect.Max.Y; py++ {
+					for px := rect.Min.X; px < rect.Max.X; px++ {
+						if tile.RGBAAt(px-ampliado.Min.X, py-ampliado.Min.Y) != completa.RGBAAt(px, py) {
+							diferentes++
+						}
+					}
+				}
+				if diferentes > 0 {
+					t.Errorf("%q, tile %v: %d píxeles distintos de la página completa", texto, rect, diferentes)
+				}
+			}
+		}
+	}
+}
+
+func TestFiltrosMargen(t *testing.T) {
+	tamano := image.Pt(1000, 800)
+	centro := Region{Rect: image.Rect(450, 350, 550, 450), Pagina: tamano, Escala: 2}
+	esquina := Region{Rect: image.Rect(0, 0, 100, 100), Pagina: tamano, Escala: 2}
+
+	casos := []struct {
+		texto  string
+		ref    Referencia
+		region Region
+		margen int
+	}{
+		{"gris,invertir,gamma,contraste", Referencia{}, centro, 0},
+		{"enfoque", Referencia{}, centro, 1},
+		// Radio de 3 puntos a 2 píxeles por punto, más uno
+		{"umbral:3", Referencia{}, centro, 7},
+		{"umbral:3,enfoque", Referencia{}, centro, 8},
+		// Sin inclinación la rotación no mueve nada
+		{"enderezar", Referencia{}, centro, 2},
+		// Lejos del centro la rotación desplaza más
+		{"enderezar:2", Referencia{}, esquina, int(math.Ceil(math.Hypot(500, 400)*2*math.Pi/180)) + 2},
+		{"enderezar", Referencia{Inclinacion: -2}, esquina, int(math.Ceil(math.Hypot(500, 400)*2*math.Pi/180)) + 2},
+	}
+	for _, c := range casos {
+		filtros, err := ParsearFiltros(c.texto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := filtros.Margen(c.region, c.ref); got != c.margen {
+			t.Errorf("%q: Margen = %d, se esperaba %d", c.texto, got, c.margen)
+		}
+	}
+}
+
+func TestReferenciaContraste(t *testing.T) {
+	// Mitad a 60 y mitad a 180: sin recorte estira a 0 y 255
+	img := paginaPrueba(func(x, y int) uint8 {
+		if x < 128 {
+			return 60
+		}
+		return 180
+	})
+	filtros, _ := ParsearFiltros("contraste:0")
+	salida, ref := filtros.Aplicar(img, Region{Rect: img.Rect, Pagina: img.Rect.Max, Escala: 1}, nil)
+	if ref.Bajo != 60 || ref.Alto != 180 {
+		t.Fatalf("referencia = %+v, se esperaba bajo 60 y alto 180", ref)
+	}
+	if a, b := salida.RGBAAt(0, 0).R, salida.RGBAAt(255, 0).R; a != 0 || b != 255 {
+		t.Errorf("contraste estirado = %d y %d, se esperaba 0 y 255", a, b)
+	}
+
+	// Con la referencia de otra página se usan sus valores, no los del tile
+	tile, _ := filtros.Aplicar(img.SubImage(image.Rect(128, 0, 256, 256)),
+		Region{Rect: image.Rect(128, 0, 256, 256), Pagina: img.Rect.Max, Escala: 1}, &Referencia{Bajo: 0, Alto: 180})
+	if v := tile.RGBAAt(0, 0).R; v != 255 {
+		t.Errorf("tile con referencia = %d, se esperaba 255", v)
+	}
+}
+
+func TestEstimarInclinacion(t *testing.T) {
+	for _, grados := range []float64{0, 2, -3} {
+		got := EstimarInclinacion(actaPrueba(400, 300, grados))
+		if math.Abs(got-grados) > 0.3 {
+			t.Errorf("inclinación de %g° estimada en %g°", grados, got)
+		}
+	}
+	if got := EstimarInclinacion(paginaPrueba(func(x, y int) uint8 { return 255 })); got != 0 {
+		t.Errorf("página en blanco: inclinación %g°, se esperaba 0", got)
+	}
+}
@@ -0,0 +1,363 @@
+package imagen
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// Marca forense invisible.
+//
+// Se incrusta un identificador de 64 bits (usuario 16 bits, visualización
+// 32 bits y CRC-16) en la luminancia de la imagen. Cada bloque de 8x8
+// píxeles guarda un bit por modulación de índice de cuantización (QIM) en
+// dos coeficientes DCT de frecuencia baja-media, que son los que mejor
+// sobreviven a la recompresión JPEG. Los 64 bits se reparten en un patrón
+// de 8x8 bloques que se repite por toda la página, así que cualquier
+// recorte alineado de al menos 64x64 píxeles contiene el identificador
+// completo varias veces.
+
+const (
+	pasoQIM     = 24.0 // separación entre niveles; mayor = más robusto y más visible
+	bitsForense = 64
+	ladoPatron  = 8 // el patrón de bits ocupa 8x8 bloques
+
+	// margenForense es cuánto se aleja de 0 o de 255 un bloque que satura
+	// al incrustar. Sin él, en fondos blancos y páginas binarizadas la
+	// modulación se pierde al saturar y el identificador no se recupera.
+	// Es el mayor cambio que la modulación hace en un píxel: dos
+	// coeficientes movidos hasta medio paso, con bases de amplitud 1/4.
+	margenForense = pasoQIM / 4
+)
+
+// Coeficientes DCT que llevan el bit (fila, columna)
+var coeficientesForense = [][2]int{{1, 2}, {2, 1}}
+
+// Identificador es lo que se incrusta en cada imagen servida
+type Identificador struct {
+	UsuarioID int
+	VistaID   int64
+}
+
+// Candidato es un identificador recuperado con su nivel de confianza (0-1)
+type Candidato struct {
+	Identificador
+	Confianza float64
+}
+
+// IncrustarForense agrega el identificador invisible a la imagen.
+// origenX y origenY son la posición de la imagen dentro de la página, para
+// que los bloques y el patrón coincidan entre tiles contiguos.
+func IncrustarForense(img image.Image, origenX, origenY int, id Identificador) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+
+	bits := codificarIdentificador(id)
+	ancho, alto := b.Dx(), b.Dy()
+
+	// Primer píxel de la imagen que cae en un borde de bloque de la página
+	inicioX := (8 - modInt(origenX, 8)) % 8
+	inicioY := (8 - modInt(origenY, 8)) % 8
+
+	for y := inicioY; y+8 <= alto; y += 8 {
+		for x := inicioX; x+8 <= ancho; x += 8 {
+			bx := (origenX + x) / 8
+			by := (origenY + y) / 8
+			bit := bits[modInt(bx, ladoPatron)+ladoPatron*modInt(by, ladoPatron)]
+
+			// Si la modulación satura algún canal, solo este bloque se
+			// acerca al centro lo necesario y se vuelve a modular
+			var delta [8][8]float64
+			bajo, alto := modularBloque(dst, x, y, bit, &delta)
+			for intento := 0; (bajo || alto) && intento < 3; intento++ {
+				acercarBloque(dst, x, y, bajo, alto)
+				bajo, alto = modularBloque(dst, x, y, bit, &delta)
+			}
+
+			// Sumar la diferencia de luminancia por igual a R, G y B
+			for j := 0; j < 8; j++ {
+				for i := 0; i < 8; i++ {
+					p := dst.PixOffset(x+i, y+j)
+					for k := 0; k < 3; k++ {
+						dst.Pix[p+k] = saturar(float64(dst.Pix[p+k]) + delta[j][i])
+					}
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// ExtraerForense busca el identificador en una imagen (por ejemplo una
+// captura filtrada). Prueba los 64 desplazamientos posibles de la rejilla
+// de bloques y las 64 rotaciones del patrón; devuelve los candidatos cuyo
+// CRC es válido, del más al menos confiable.
+func ExtraerForense(img image.Image) []Candidato {
+	b := img.Bounds()
+	lum := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(lum, lum.Bounds(), img, b.Min, draw.Src)
+
+	vistos := map[Identificador]int{}
+	var candidatos []Candidato
+
+	var bloque, coef [8][8]float64
+	for oy := 0; oy < 8; oy++ {
+		for ox := 0; ox < 8; ox++ {
+			// Suma de decisiones blandas por celda del patrón
+			var suma [bitsForense]float64
+			var total [bitsForense]int
+			for y := oy; y+8 <= b.Dy(); y += 8 {
+				for x := ox; x+8 <= b.Dx(); x += 8 {
+					celda := modInt((x-ox)/8, ladoPatron) + ladoPatron*modInt((y-oy)/8, ladoPatron)
+					leerLuminancia(lum, x, y, &bloque)
+					dct8(&bloque, &coef)
+					for _, c := range coeficientesForense {
+						suma[celda] += demodular(coef[c[0]][c[1]])
+						total[celda]++
+					}
+				}
+			}
+
+			for sy := 0; sy < ladoPatron; sy++ {
+				for sx := 0; sx < ladoPatron; sx++ {
+					var bits [bitsForense]int
+					confianza := 0.0
+					valido := true
+					for cy := 0; cy < ladoPatron; cy++ {
+						for cx := 0; cx < ladoPatron; cx++ {
+							celda := cx + ladoPatron*cy
+							if total[celda] == 0 {
+								valido = false
+								continue
+							}
+							media := suma[celda] / float64(total[celda])
+							indice := modInt(cx+sx, ladoPatron) + ladoPatron*modInt(cy+sy, ladoPatron)
+							if media < 0 {
+								bits[indice] = 1
+							}
+							confianza += math.Abs(media)
+						}
+					}
+					if !valido {
+						continue
+					}
+					id, ok := decodificarIdentificador(bits)
+					if !ok {
+						continue
+					}
+					confianza /= bitsForense
+					if i, existe := vistos[id]; existe {
+						if confianza > candidatos[i].Confianza {
+							candidatos[i].Confianza = confianza
+						}
+						continue
+					}
+					vistos[id] = len(candidatos)
+					candidatos = append(candidatos, Candidato{Identificador: id, Confianza: confianza})
+				}
+			}
+		}
+	}
+
+	sort.Slice(candidatos, func(i, j int) bool {
+		return candidatos[i].Confianza > candidatos[j].Confianza
+	})
+	return candidatos
+}
+
+// codificarIdentificador arma los 64 bits: usuario, visualización y CRC-16
+func codificarIdentificador(id Identificador) [bitsForense]int {
+	datos := []byte{
+		byte(id.UsuarioID >> 8), byte(id.UsuarioID),
+		byte(id.VistaID >> 24), byte(id.VistaID >> 16), byte(id.VistaID >> 8), byte(id.VistaID),
+	}
+	crc := crc16(datos)
+	datos = append(datos, byte(crc>>8), byte(crc))
+
+	var bits [bitsForense]int
+	for i := 0; i < bitsForense; i++ {
+		bits[i] = int(datos[i/8]>>(7-uint(i%8))) & 1
+	}
+	return bits
+}
+
+func decodificarIdentificador(bits [bitsForense]int) (Identificador, bool) {
+	datos := make([]byte, bitsForense/8)
+	for i, bit := range bits {
+		datos[i/8] |= byte(bit) << (7 - uint(i%8))
+	}
+	if crc16(datos[:6]) != uint16(datos[6])<<8|uint16(datos[7]) {
+		return Identificador{}, false
+	}
+	return Identificador{
+		UsuarioID: int(datos[0])<<8 | int(datos[1]),
+		VistaID:   int64(datos[2])<<24 | int64(datos[3])<<16 | int64(datos[4])<<8 | int64(datos[5]),
+	}, true
+}
+
+// crc16 calcula el CRC-16/CCITT-FALSE
+func crc16(datos []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, d := range datos {
+		crc ^= uint16(d) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// cuantizar lleva el coeficiente al nivel más cercano de la rejilla del bit:
+// múltiplos de pasoQIM para 0 y desplazados medio paso para 1
+func cuantizar(c float64, bit int) float64 {
+	desplazamiento := float64(bit) * pasoQIM / 2
+	return math.Round((c-desplazamiento)/pasoQIM)*pasoQIM + desplazamiento
+}
+
+// demodular devuelve +1 si el coeficiente está sobre la rejilla del 0,
+// -1 si está sobre la del 1, y valores intermedios según la distancia
+func demodular(c float64) float64 {
+	return math.Cos(2 * math.Pi * c / pasoQIM)
+}
+
+func leerLuminancia(img *image.RGBA, x, y int, bloque *[8][8]float64) {
+	for j := 0; j < 8; j++ {
+		for i := 0; i < 8; i++ {
+			p := img.PixOffset(x+i, y+j)
+			bloque[j][i] = 0.299*float64(img.Pix[p]) + 0.587*float64(img.Pix[p+1]) + 0.114*float64(img.Pix[p+2])
+		}
+	}
+}
+
+// Tabla de cosenos de la DCT-II ortonormal de 8 puntos
+var tablaDCT = func() (t [8][8]float64) {
+	for k := 0; k < 8; k++ {
+		escala := math.Sqrt(2.0 / 8)
+		if k == 0 {
+			escala = math.Sqrt(1.0 / 8)
+		}
+		for n := 0; n < 8; n++ {
+			t[k][n] = escala * math.Cos(math.Pi*(2*float64(n)+1)*float64(k)/16)
+		}
+	}
+	return t
+}()
+
+func dct8(entrada, salida *[8][8]float64) {
+	var tmp [8][8]float64
+	for j := 0; j < 8; j++ {
+		for k := 0; k < 8; k++ {
+			s := 0.0
+			for n := 0; n < 8; n++ {
+				s += tablaDCT[k][n] * entrada[j][n]
+			}
+			tmp[j][k] = s
+		}
+	}
+	for i := 0; i < 8; i++ {
+		for k := 0; k < 8; k++ {
+			s := 0.0
+			for n := 0; n < 8; n++ {
+				s += tablaDCT[k][n] * tmp[n][i]
+			}
+			salida[k][i] = s
+		}
+	}
+}
+
+func idct8(entrada, salida *[8][8]float64) {
+	// Transformada inversa separable: primero columnas, luego filas
+	var col [8][8]float64
+	for i := 0; i < 8; i++ {
+		for n := 0; n < 8; n++ {
+			s := 0.0
+			for k := 0; k < 8; k++ {
+				s += tablaDCT[k][n] * entrada[k][i]
+			}
+			col[n][i] = s
+		}
+	}
+	for j := 0; j < 8; j++ {
+		for n := 0; n < 8; n++ {
+			s := 0.0
+			for k := 0; k < 8; k++ {
+				s += tablaDCT[k][n] * col[j][k]
+			}
+			salida[j][n] = s
+		}
+	}
+}
+
+// modularBloque calcula en delta el cambio de luminancia que lleva el bit
+// al bloque de 8x8 en (x, y) e indica si algún canal quedaría por debajo
+// de 0 o por encima de 255
+func modularBloque(img *image.RGBA, x, y, bit int, delta *[8][8]float64) (bajo, alto bool) {
+	var bloque, coef, nuevo [8][8]float64
+	leerLuminancia(img, x, y, &bloque)
+	dct8(&bloque, &coef)
+	for _, c := range coeficientesForense {
+		coef[c[0]][c[1]] = cuantizar(coef[c[0]][c[1]], bit)
+	}
+	idct8(&coef, &nuevo)
+
+	for j := 0; j < 8; j++ {
+		for i := 0; i < 8; i++ {
+			delta[j][i] = nuevo[j][i] - bloque[j][i]
+			p := img.PixOffset(x+i, y+j)
+			for k := 0; k < 3; k++ {
+				v := float64(img.Pix[p+k]) + delta[j][i]
+				bajo = bajo || v < 0
+				alto = alto || v > 255
+			}
+		}
+	}
+	return bajo, alto
+}
+
+// acercarBloque comprime linealmente los canales del bloque de 8x8 en
+// (x, y) para alejarlos margenForense del extremo que satura: [0, 255]
+// pasa a [margenForense, 255] si bajo, a [0, 255-margenForense] si alto, o
+// a ambos. Depende solo del contenido del bloque, alineado a la página,
+// así que da lo mismo en todos los tiles que lo contienen completo.
+func acercarBloque(img *image.RGBA, x, y int, bajo, alto bool) {
+	minimo, maximo := 0.0, 255.0
+	if bajo {
+		minimo = margenForense
+	}
+	if alto {
+		maximo = 255 - margenForense
+	}
+	escala := (maximo - minimo) / 255
+	for j := 0; j < 8; j++ {
+		for i := 0; i < 8; i++ {
+			p := img.PixOffset(x+i, y+j)
+			for k := 0; k < 3; k++ {
+				img.Pix[p+k] = saturar(minimo + float64(img.Pix[p+k])*escala)
+			}
+		}
+	}
+}
+
+func saturar(v float64) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+func modInt(a, b int) int {
+	r := a % b
+	if r < 0 {
+		r += b
+	}
+	return r
+}
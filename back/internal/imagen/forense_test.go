@@ -0,0 +1,140 @@
+package imagen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// paginaPrueba genera una página de prueba de 256x256 píxeles
+func paginaPrueba(pixel func(x, y int) uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			v := pixel(x, y)
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func recodificarPNG(t *testing.T, img image.Image) image.Image {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dec
+}
+
+func recodificarJPEG(t *testing.T, img image.Image, calidad int) image.Image {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: calidad}); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dec
+}
+
+func TestForenseIdaYVuelta(t *testing.T) {
+	id := Identificador{UsuarioID: 4321, VistaID: 987654321}
+
+	paginas := map[string]*image.RGBA{
+		"blanca": paginaPrueba(func(x, y int) uint8 { return 255 }),
+		"negra":  paginaPrueba(func(x, y int) uint8 { return 0 }),
+		// Renglones de "texto" negro sobre blanco, como deja el filtro umbral
+		"binarizada": paginaPrueba(func(x, y int) uint8 {
+			if (y/6)%3 == 0 && (x/5)%4 != 3 {
+				return 0
+			}
+			return 255
+		}),
+		"gradiente": paginaPrueba(func(x, y int) uint8 { return uint8(x) }),
+	}
+	recodificaciones := map[string]func(*testing.T, image.Image) image.Image{
+		"png":      recodificarPNG,
+		"jpeg-q60": func(t *testing.T, img image.Image) image.Image { return recodificarJPEG(t, img, 60) },
+	}
+
+	for nombre, pagina := range paginas {
+		for formato, recodificar := range recodificaciones {
+			t.Run(nombre+"/"+formato, func(t *testing.T) {
+				marcada := recodificar(t, IncrustarForense(pagina, 0, 0, id))
+				candidatos := ExtraerForense(marcada)
+				if len(candidatos) == 0 {
+					t.Fatal("no se recuperó ningún identificador")
+				}
+				if candidatos[0].Identificador != id {
+					t.Fatalf("identificador = %+v, se esperaba %+v", candidatos[0].Identificador, id)
+				}
+			})
+		}
+	}
+}
+
+func TestForenseTileDesplazado(t *testing.T) {
+	// Un tile que empieza fuera de la rejilla de bloques de la página
+	id := Identificador{UsuarioID: 7, VistaID: 123}
+	pagina := paginaPrueba(func(x, y int) uint8 { return 255 })
+	marcada := recodificarPNG(t, IncrustarForense(pagina, 253, 130, id))
+	candidatos := ExtraerForense(marcada)
+	if len(candidatos) == 0 || candidatos[0].Identificador != id {
+		t.Fatalf("candidatos = %+v, se esperaba %+v", candidatos, id)
+	}
+}
+
+func TestForenseConservaContraste(t *testing.T) {
+	// Solo se acercan al centro los bloques que saturan, y solo
+	// margenForense: el resto de la página cambia lo que la modulación
+	id := Identificador{UsuarioID: 4321, VistaID: 987654321}
+	casos := []struct {
+		nombre string
+		pagina *image.RGBA
+	}{
+		{"gris", paginaPrueba(func(x, y int) uint8 { return 128 })},
+		{"binarizada", paginaPrueba(func(x, y int) uint8 {
+			if (y/6)%3 == 0 && (x/5)%4 != 3 {
+				return 0
+			}
+			return 255
+		})},
+	}
+	for _, c := range casos {
+		marcada := IncrustarForense(c.pagina, 0, 0, id)
+		maximo := 0
+		for i := range marcada.Pix {
+			d := int(marcada.Pix[i]) - int(c.pagina.Pix[i])
+			if d < 0 {
+				d = -d
+			}
+			if d > maximo {
+				maximo = d
+			}
+		}
+		if limite := int(2 * margenForense); maximo > limite {
+			t.Errorf("%s: un píxel cambió %d, se esperaba a lo más %d", c.nombre, maximo, limite)
+		}
+	}
+}
+
+func TestCodificarIdentificador(t *testing.T) {
+	id := Identificador{UsuarioID: 0xBEEF, VistaID: 0x12345678}
+	bits := codificarIdentificador(id)
+	got, ok := decodificarIdentificador(bits)
+	if !ok || got != id {
+		t.Fatalf("decodificar = %+v, %v", got, ok)
+	}
+	bits[10] ^= 1
+	if _, ok := decodificarIdentificador(bits); ok {
+		t.Fatal("un bit alterado debió invalidar el CRC")
+	}
+}
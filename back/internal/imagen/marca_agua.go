@@ -0,0 +1,144 @@
+package imagen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// tamanoTexto es el tamaño de la letra en píxeles de la página renderizada
+const tamanoTexto = 36
+
+// fuenteMarca es Go Regular, que a diferencia de la fuente de mapa de bits
+// de basicfont (solo ASCII) tiene la ñ y las vocales acentuadas de los
+// nombres de usuario y de los textos por rol
+var fuenteMarca = func() *opentype.Font {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}()
+
+// MarcaAgua es una marca visible que se repite en diagonal sobre la página
+type MarcaAgua struct {
+	Texto    string
+	Opacidad float64 // 0 a 1
+	Angulo   float64 // grados, positivo en sentido antihorario
+	Color    color.RGBA
+}
+
+// patron es el texto ya rasterizado y las medidas de la celda que se repite
+type patron struct {
+	mascara      *image.Alpha
+	ancho, alto  int // tamaño del texto
+	celdaAncho   int
+	celdaAlto    int
+	seno, coseno float64
+	opacidad     float64
+	r, g, b      float64
+}
+
+// Aplicar dibuja la marca sobre una imagen. origenX y origenY son la posición
+// de la imagen dentro de la página (en píxeles), de modo que los tiles
+// contiguos continúan el mismo patrón.
+func (m MarcaAgua) Aplicar(img image.Image, origenX, origenY int) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+
+	if m.Texto == "" || m.Opacidad <= 0 {
+		return dst
+	}
+	p := m.rasterizar()
+
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			a := p.alfa(origenX+x, origenY+y)
+			if a == 0 {
+				continue
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = mezclar(dst.Pix[i+0], p.r, a)
+			dst.Pix[i+1] = mezclar(dst.Pix[i+1], p.g, a)
+			dst.Pix[i+2] = mezclar(dst.Pix[i+2], p.b, a)
+		}
+	}
+	return dst
+}
+
+// rasterizar dibuja el texto una sola vez en una máscara alfa. La cara se
+// crea en cada llamada porque no admite uso concurrente.
+func (m MarcaAgua) rasterizar() patron {
+	cara, err := opentype.NewFace(fuenteMarca, &opentype.FaceOptions{
+		Size: tamanoTexto, DPI: 72, Hinting: font.HintingFull,
+	})
+	if err != nil {
+		// Solo falla con opciones inválidas
+		panic(err)
+	}
+	defer cara.Close()
+	metricas := cara.Metrics()
+	d := &font.Drawer{Face: cara}
+	ancho := d.MeasureString(m.Texto).Ceil()
+	alto := metricas.Height.Ceil()
+
+	mascara := image.NewAlpha(image.Rect(0, 0, ancho, alto))
+	d.Dst = mascara
+	d.Src = image.Opaque
+	d.Dot = fixed.Point26_6{Y: metricas.Ascent}
+	d.DrawString(m.Texto)
+
+	rad := m.Angulo * math.Pi / 180
+	opacidad := math.Min(m.Opacidad, 1)
+	return patron{
+		mascara:    mascara,
+		ancho:      ancho,
+		alto:       alto,
+		celdaAncho: ancho + ancho/2,
+		celdaAlto:  alto * 5,
+		seno:       math.Sin(rad),
+		coseno:     math.Cos(rad),
+		opacidad:   opacidad,
+		r:          float64(m.Color.R),
+		g:          float64(m.Color.G),
+		b:          float64(m.Color.B),
+	}
+}
+
+// alfa devuelve la cobertura (0-1) de la marca en un punto de la página
+func (p patron) alfa(x, y int) float64 {
+	// Rotar el punto al sistema de coordenadas del texto
+	fx, fy := float64(x), float64(y)
+	u := fx*p.coseno - fy*p.seno
+	v := fx*p.seno + fy*p.coseno
+
+	fila := math.Floor(v / float64(p.celdaAlto))
+	// Cada fila se desplaza media celda para que el patrón quede escalonado
+	u += fila * float64(p.celdaAncho) / 2
+
+	cu := int(modulo(u, float64(p.celdaAncho)))
+	cv := int(modulo(v, float64(p.celdaAlto)))
+	if cu >= p.ancho || cv >= p.alto {
+		return 0
+	}
+	return float64(p.mascara.AlphaAt(cu, cv).A) / 255 * p.opacidad
+}
+
+func modulo(a, b float64) float64 {
+	r := math.Mod(a, b)
+	if r < 0 {
+		r += b
+	}
+	return r
+}
+
+func mezclar(fondo uint8, tinta, alfa float64) uint8 {
+	return uint8(float64(fondo)*(1-alfa) + tinta*alfa + 0.5)
+}
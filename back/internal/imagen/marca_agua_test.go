@@ -0,0 +1,34 @@
+package imagen
+
+import (
+	"image/color"
+	"testing"
+)
+
+// tinta suma la cobertura de la máscara del texto
+func tinta(texto string) int {
+	p := MarcaAgua{Texto: texto, Opacidad: 1, Color: color.RGBA{A: 255}}.rasterizar()
+	total := 0
+	for _, a := range p.mascara.Pix {
+		total += int(a)
+	}
+	return total
+}
+
+func TestMarcaAguaLatin1(t *testing.T) {
+	// Las letras con tilde o acento llevan la base más el signo: más tinta
+	// que la letra sola, no una caja vacía
+	casos := []struct{ con, sin string }{
+		{"ñ", "n"},
+		{"Ñ", "N"},
+		{"á", "a"},
+		{"É", "E"},
+		{"ü", "u"},
+	}
+	for _, c := range casos {
+		con, sin := tinta(c.con), tinta(c.sin)
+		if con <= sin {
+			t.Errorf("%q tiene %d de tinta y %q %d: falta el glifo", c.con, con, c.sin, sin)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package imagen
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PaginaImagen es una página de un PDF hecho solo de imágenes
+type PaginaImagen struct {
+	JPEG        []byte  // imagen RGB codificada en JPEG
+	Ancho, Alto int     // píxeles de la imagen
+	AnchoPt     float64 // tamaño de la página en puntos PDF
+	AltoPt      float64
+}
+
+// PDFDeImagenes arma un PDF con una imagen a página completa por hoja. Lo
+// usan las descargas con filtros, que ya no conservan el texto ni los
+// vectores del original.
+func PDFDeImagenes(paginas []PaginaImagen) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	objeto := func(contenido string, stream []byte) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\n", len(offsets), contenido)
+		if stream != nil {
+			buf.WriteString("stream\n")
+			buf.Write(stream)
+			buf.WriteString("\nendstream\n")
+		}
+		buf.WriteString("endobj\n")
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	objeto("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	kids := &bytes.Buffer{}
+	for i := range paginas {
+		// Cada página ocupa tres objetos a partir del 3: página, contenido e imagen
+		fmt.Fprintf(kids, "%d 0 R ", 3+i*3)
+	}
+	objeto(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(paginas)), nil)
+
+	for i, p := range paginas {
+		pagina := 3 + i*3
+		contenido := fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", p.AnchoPt, p.AltoPt)
+		objeto(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			p.AnchoPt, p.AltoPt, pagina+2, pagina+1), nil)
+		objeto(fmt.Sprintf("<< /Length %d >>", len(contenido)), []byte(contenido))
+		objeto(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB "+
+			"/BitsPerComponent 8 /Filter /DCTDecode /Length %d >>", p.Ancho, p.Alto, len(p.JPEG)), p.JPEG)
+	}
+
+	inicioXref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, inicioXref)
+	return buf.Bytes()
+}
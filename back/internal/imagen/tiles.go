@@ -0,0 +1,125 @@
+package imagen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+// Tile es un fragmento de página tal como lo entrega el microservicio.
+// X, Y, Width y Height están en puntos PDF; Image es un PNG en base64.
+type Tile struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Image  string  `json:"image"`
+}
+
+type Pagina struct {
+	PageNumber int    `json:"page_number"`
+	Tiles      []Tile `json:"tiles"`
+}
+
+// RespuestaTiles es la respuesta de /pdf_to_tiles del microservicio
+type RespuestaTiles struct {
+	Pages []Pagina `json:"pages"`
+}
+
+// Transformacion modifica un tile. origenX y origenY son la posición del
+// tile dentro de la página renderizada, en píxeles.
+type Transformacion func(img image.Image, origenX, origenY int) image.Image
+
+// TransformarTiles decodifica cada tile, le aplica la transformación y lo
+// vuelve a codificar como PNG en base64
+func TransformarTiles(resp *RespuestaTiles, t Transformacion) error {
+	for p := range resp.Pages {
+		for i := range resp.Pages[p].Tiles {
+			tile := &resp.Pages[p].Tiles[i]
+
+			datos, err := base64.StdEncoding.DecodeString(tile.Image)
+			if err != nil {
+				return fmt.Errorf("tile %d de la página %d: %v", i, resp.Pages[p].PageNumber, err)
+			}
+			img, err := png.Decode(bytes.NewReader(datos))
+			if err != nil {
+				return fmt.Errorf("tile %d de la página %d: %v", i, resp.Pages[p].PageNumber, err)
+			}
+
+			// El microservicio renderiza con zoom; la escala sale del tamaño real del PNG
+			escala := 1.0
+			if tile.Width > 0 {
+				escala = float64(img.Bounds().Dx()) / tile.Width
+			}
+			origenX := int(math.Round(tile.X * escala))
+			origenY := int(math.Round(tile.Y * escala))
+
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, t(img, origenX, origenY)); err != nil {
+				return err
+			}
+			tile.Image = base64.StdEncoding.EncodeToString(buf.Bytes())
+		}
+	}
+	return nil
+}
+
+// TransformacionPagina modifica una página completa armada con sus tiles.
+// escala son los píxeles por punto PDF del render.
+type TransformacionPagina func(pagina *image.RGBA, numero int, escala float64) *image.RGBA
+
+// TransformarPaginas arma cada página con sus tiles, le aplica la
+// transformación y la vuelve a cortar en los mismos tiles. Sirve para los
+// filtros que necesitan ver la página entera (contraste, enderezar).
+func TransformarPaginas(resp *RespuestaTiles, t TransformacionPagina) error {
+	for p := range resp.Pages {
+		pagina := &resp.Pages[p]
+		imagenes := make([]image.Image, len(pagina.Tiles))
+		escala := 0.0
+		var limites image.Rectangle
+		for i, tile := range pagina.Tiles {
+			datos, err := base64.StdEncoding.DecodeString(tile.Image)
+			if err != nil {
+				return fmt.Errorf("tile %d de la página %d: %v", i, pagina.PageNumber, err)
+			}
+			img, err := png.Decode(bytes.NewReader(datos))
+			if err != nil {
+				return fmt.Errorf("tile %d de la página %d: %v", i, pagina.PageNumber, err)
+			}
+			imagenes[i] = img
+			if escala == 0 && tile.Width > 0 {
+				escala = float64(img.Bounds().Dx()) / tile.Width
+			}
+		}
+		if escala == 0 {
+			continue
+		}
+
+		// Posición de cada tile en la página renderizada
+		rects := make([]image.Rectangle, len(pagina.Tiles))
+		for i, tile := range pagina.Tiles {
+			origen := image.Pt(int(math.Round(tile.X*escala)), int(math.Round(tile.Y*escala)))
+			rects[i] = image.Rectangle{Min: origen, Max: origen.Add(imagenes[i].Bounds().Size())}
+			limites = limites.Union(rects[i])
+		}
+		// Los tiles empiezan en (0,0): la página va de ahí a la esquina del último
+		completa := image.NewRGBA(image.Rectangle{Max: limites.Max})
+		for i, img := range imagenes {
+			draw.Draw(completa, rects[i], img, img.Bounds().Min, draw.Src)
+		}
+
+		transformada := t(completa, pagina.PageNumber, escala)
+		for i := range pagina.Tiles {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, transformada.SubImage(rects[i])); err != nil {
+				return err
+			}
+			pagina.Tiles[i].Image = base64.StdEncoding.EncodeToString(buf.Bytes())
+		}
+	}
+	return nil
+}
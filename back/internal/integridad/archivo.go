@@ -0,0 +1,74 @@
+// Package integridad verifica que los PDFs del archivo no se hayan
+// modificado, borrado o dañado: guarda el SHA-256 de cada uno y lo compara
+// en revisiones periódicas.
+package integridad
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"visor-pdf/internal/almacen"
+)
+
+// Problemas que puede tener un PDF en el archivo
+const (
+	ProblemaNoExiste = "no_existe"
+	ProblemaVacio    = "vacio"
+	ProblemaCorrupto = "corrupto"
+)
+
+// RevisarPDF devuelve "" si el archivo parece un PDF completo, o el problema
+// encontrado: no_existe, vacio o corrupto (sin cabecera %PDF- o sin %%EOF al
+// final, típico de una copia interrumpida)
+//
+// Solo lee el inicio y el final del archivo, así que es barato también en
+// almacenes remotos.
+func RevisarPDF(a almacen.Almacen, ruta string) string {
+	info, err := a.Info(ruta)
+	if err != nil {
+		return ProblemaNoExiste
+	}
+	if info.Tamano == 0 {
+		return ProblemaVacio
+	}
+
+	cabecera, err := leerRango(a, ruta, 0, 5)
+	if err != nil || string(cabecera) != "%PDF-" {
+		return ProblemaCorrupto
+	}
+	cola := int64(1024)
+	if info.Tamano < cola {
+		cola = info.Tamano
+	}
+	final, err := leerRango(a, ruta, info.Tamano-cola, cola)
+	if err != nil || !bytes.Contains(final, []byte("%%EOF")) {
+		return ProblemaCorrupto
+	}
+	return ""
+}
+
+func leerRango(a almacen.Almacen, ruta string, desde, n int64) ([]byte, error) {
+	r, err := a.LeerRango(ruta, desde, n)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// CalcularSHA256 devuelve el hash en hexadecimal del archivo
+func CalcularSHA256(a almacen.Almacen, ruta string) (string, error) {
+	f, err := a.Abrir(ruta)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,318 @@
+package integridad
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"visor-pdf/internal/almacen"
+	"visor-pdf/internal/config"
+	"visor-pdf/internal/database"
+	"visor-pdf/internal/estados"
+	"visor-pdf/internal/rutas"
+)
+
+// Tipos de incidencia (coinciden con los estados de archivos_pdf)
+const (
+	EstadoOK       = "ok"
+	EstadoCambiado = "cambiado"
+	EstadoFaltante = "faltante"
+	EstadoCorrupto = "corrupto"
+)
+
+// ErrEnCurso se devuelve al pedir una verificación mientras otra corre
+var ErrEnCurso = errors.New("ya hay una verificación en curso")
+
+// Resumen describe una pasada del verificador
+type Resumen struct {
+	Inicio    time.Time  `json:"inicio"`
+	Fin       *time.Time `json:"fin"`
+	Revisados int        `json:"revisados"`
+	Nuevos    int        `json:"nuevos"`
+	Cambiados int        `json:"cambiados"`
+	Faltantes int        `json:"faltantes"`
+	Corruptos int        `json:"corruptos"`
+	Error     string     `json:"error,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	enCurso bool
+	ultimo  *Resumen
+)
+
+// Iniciar programa la verificación periódica según IntervaloVerificacion
+// (en horas). La primera pasada arranca un minuto después de iniciar el
+// servidor para no competir con el arranque.
+func Iniciar(c config.Config) {
+	if c.IntervaloVerificacion <= 0 {
+		fmt.Println("ℹ️  Verificación de integridad de PDFs desactivada")
+		return
+	}
+	go func() {
+		time.Sleep(time.Minute)
+		for {
+			if _, err := Verificar(); err != nil {
+				log.Printf("⚠️  Verificación de integridad: %v", err)
+			}
+			time.Sleep(time.Duration(c.IntervaloVerificacion) * time.Hour)
+		}
+	}()
+}
+
+// Estado devuelve el resumen de la última pasada (o la que está en curso)
+// y si hay una corriendo
+func Estado() (*Resumen, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if ultimo == nil {
+		return nil, enCurso
+	}
+	copia := *ultimo
+	return &copia, enCurso
+}
+
+// Verificar recorre una vez todas las raíces del archivo. Devuelve ErrEnCurso si ya hay una
+// pasada corriendo.
+func Verificar() (Resumen, error) {
+	mu.Lock()
+	if enCurso {
+		mu.Unlock()
+		return Resumen{}, ErrEnCurso
+	}
+	enCurso = true
+	r := &Resumen{Inicio: time.Now()}
+	ultimo = r
+	mu.Unlock()
+
+	err := verificar(r)
+
+	mu.Lock()
+	defer mu.Unlock()
+	fin := time.Now()
+	r.Fin = &fin
+	if err != nil {
+		r.Error = err.Error()
+	}
+	enCurso = false
+	return *r, err
+}
+
+// clave identifica un archivo registrado
+type clave struct {
+	raiz, ruta string
+}
+
+// registrado es lo que la BD sabe de un archivo
+type registrado struct {
+	id           int
+	sha256       string
+	sha256Ultimo string
+	estado       string
+	visto        bool
+}
+
+func verificar(r *Resumen) error {
+	conocidos, err := cargarRegistrados()
+	if err != nil {
+		return err
+	}
+	municipios, err := estados.Municipios()
+	if err != nil {
+		return err
+	}
+
+	var errores []string
+	for _, raiz := range rutas.Raices() {
+		revisados, err := recorrerRaiz(raiz, conocidos, municipios, r)
+		if err != nil {
+			// Si la unidad de red no está montada, o falló la lectura de algún
+			// subdirectorio, no se marca nada como faltante en esta raíz
+			log.Printf("⚠️  Verificación de integridad: %v", err)
+			errores = append(errores, err.Error())
+			continue
+		}
+		if revisados == 0 {
+			errores = append(errores, fmt.Sprintf("no se encontró ningún PDF en la raíz %q", raiz.Nombre))
+			continue
+		}
+
+		// Los registrados en esta raíz que no aparecieron en el recorrido
+		for k, c := range conocidos {
+			if k.raiz != raiz.Nombre || c.visto || c.estado == EstadoFaltante {
+				continue
+			}
+			if err := cambiarEstado(c.id, EstadoFaltante, c.sha256Ultimo, "", c.sha256Ultimo); err != nil {
+				log.Printf("⚠️  Verificación (faltante %d): %v", c.id, err)
+				continue
+			}
+			mu.Lock()
+			r.Faltantes++
+			mu.Unlock()
+		}
+	}
+	if len(errores) > 0 {
+		return errors.New(strings.Join(errores, "; "))
+	}
+	return nil
+}
+
+// recorrerRaiz revisa cada PDF de una raíz y devuelve cuántos encontró
+func recorrerRaiz(raiz rutas.Raiz, conocidos map[clave]*registrado, municipios map[[2]int]int, r *Resumen) (int, error) {
+	revisados := 0
+	err := raiz.Almacen.Listar("", func(info almacen.Info) error {
+		if !strings.EqualFold(path.Ext(info.Ruta), ".pdf") {
+			return nil
+		}
+
+		k := clave{raiz.Nombre, info.Ruta}
+		if err := revisarArchivo(raiz, info, conocidos[k], municipios, r); err != nil {
+			log.Printf("⚠️  Verificación de %s/%s: %v", raiz.Nombre, info.Ruta, err)
+		}
+		if c, ok := conocidos[k]; ok {
+			c.visto = true
+		}
+		revisados++
+		mu.Lock()
+		r.Revisados++
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return revisados, fmt.Errorf("no se puede leer la raíz %q (%s): %v", raiz.Nombre, raiz.Ruta, err)
+	}
+	return revisados, nil
+}
+
+func cargarRegistrados() (map[clave]*registrado, error) {
+	rows, err := database.DB.Query("SELECT id, raiz, ruta, sha256, sha256_ultimo, estado FROM archivos_pdf")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conocidos := map[clave]*registrado{}
+	for rows.Next() {
+		var k clave
+		c := &registrado{}
+		if err := rows.Scan(&c.id, &k.raiz, &k.ruta, &c.sha256, &c.sha256Ultimo, &c.estado); err != nil {
+			return nil, err
+		}
+		conocidos[k] = c
+	}
+	return conocidos, rows.Err()
+}
+
+// revisarArchivo calcula el hash de un PDF y actualiza su registro
+func revisarArchivo(raiz rutas.Raiz, info almacen.Info, c *registrado, municipios map[[2]int]int, r *Resumen) error {
+	hash, err := CalcularSHA256(raiz.Almacen, info.Ruta)
+	if err != nil {
+		return err
+	}
+	estado := EstadoOK
+	if RevisarPDF(raiz.Almacen, info.Ruta) != "" {
+		estado = EstadoCorrupto
+	}
+
+	if c == nil {
+		// Los datos del acta salen del nombre según la plantilla de la raíz
+		datos, ok := raiz.Parsear(info.Ruta)
+		var acto interface{}
+		var municipio, oficialia, localidad, anio, numActa interface{}
+		if ok {
+			acto, oficialia = datos.Acto, datos.Oficialia
+			localidad, anio, numActa = datos.Localidad, datos.Anio, datos.NumActa
+			// El nombre trae las claves INEGI; se guarda el id interno
+			if id, existe := municipios[[2]int{datos.Estado, datos.Municipio}]; existe {
+				municipio = id
+			}
+		}
+		result, err := database.DB.Exec(`
+			INSERT INTO archivos_pdf (raiz, ruta, acto, municipio_id, oficialia, localidad, anio, num_acta,
+				tamano, modificado, sha256, sha256_ultimo, estado, verificado_en)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())`,
+			raiz.Nombre, info.Ruta, acto, municipio, oficialia, localidad, anio, numActa,
+			info.Tamano, info.Modificado, hash, hash, estado)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		r.Nuevos++
+		mu.Unlock()
+		if estado == EstadoCorrupto {
+			id, _ := result.LastInsertId()
+			mu.Lock()
+			r.Corruptos++
+			mu.Unlock()
+			return registrarIncidencia(int(id), EstadoCorrupto, "", hash)
+		}
+		return nil
+	}
+
+	// El contenido se compara siempre contra el hash de referencia
+	if estado != EstadoCorrupto && hash != c.sha256 {
+		estado = EstadoCambiado
+	}
+
+	if _, err := database.DB.Exec(`
+		UPDATE archivos_pdf
+		SET tamano = ?, modificado = ?, sha256_ultimo = ?, verificado_en = NOW()
+		WHERE id = ?`, info.Tamano, info.Modificado, hash, c.id); err != nil {
+		return err
+	}
+
+	// Solo se abre incidencia cuando cambia el estado o el contenido
+	if estado == c.estado && hash == c.sha256Ultimo {
+		return nil
+	}
+	if estado == EstadoOK {
+		// Volvió al contenido de referencia (p. ej. se restauró el respaldo)
+		_, err := database.DB.Exec("UPDATE archivos_pdf SET estado = 'ok' WHERE id = ?", c.id)
+		return err
+	}
+	mu.Lock()
+	if estado == EstadoCambiado {
+		r.Cambiados++
+	} else {
+		r.Corruptos++
+	}
+	mu.Unlock()
+	return cambiarEstado(c.id, estado, c.sha256Ultimo, hash, hash)
+}
+
+// cambiarEstado actualiza el estado del archivo y abre la incidencia
+func cambiarEstado(id int, estado, anterior, nuevo, ultimo string) error {
+	if _, err := database.DB.Exec("UPDATE archivos_pdf SET estado = ?, sha256_ultimo = ? WHERE id = ?",
+		estado, ultimo, id); err != nil {
+		return err
+	}
+	return registrarIncidencia(id, estado, anterior, nuevo)
+}
+
+func registrarIncidencia(archivoID int, tipo, anterior, nuevo string) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO incidencias_integridad (archivo_id, tipo, sha256_anterior, sha256_nuevo)
+		VALUES (?, ?, ?, ?)`, archivoID, tipo, nuloSiVacio(anterior), nuloSiVacio(nuevo))
+	return err
+}
+
+// AceptarCambio adopta el contenido actual como nuevo valor de referencia
+// (por ejemplo, tras volver a digitalizar un acta)
+func AceptarCambio(tx *sql.Tx, archivoID int) error {
+	_, err := tx.Exec(`
+		UPDATE archivos_pdf SET sha256 = sha256_ultimo, estado = 'ok'
+		WHERE id = ? AND estado = 'cambiado'`, archivoID)
+	return err
+}
+
+func nuloSiVacio(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
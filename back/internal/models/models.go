@@ -1,14 +1,56 @@
 package models
 
+import "time"
+
+type Estado struct {
+	ID          int    `json:"id"`
+	Nombre      string `json:"nombre"`
+	Abreviatura string `json:"abreviatura"`
+}
+
 type Municipio struct {
-	ID     int    `json:"id"`
+	ID          int    `json:"id"`
+	EstadoID    int    `json:"estado_id"`
+	Estado      string `json:"estado"` // abreviatura
+	Clave       int    `json:"clave"`  // clave INEGI dentro del estado
+	Nombre      string `json:"nombre"`
+	Activo      bool   `json:"activo"`
+	FusionadoEn *int   `json:"fusionado_en,omitempty"`
+}
+
+type Oficialia struct {
+	ID          int    `json:"id"`
+	IDMunicipio int    `json:"id_municipio"`
+	Numero      int    `json:"numero"`
+	Nombre      string `json:"nombre"`
+	AnioInicio  *int   `json:"anio_inicio"`
+	AnioFin     *int   `json:"anio_fin"`
+}
+
+type Acto struct {
+	Codigo string `json:"codigo"`
 	Nombre string `json:"nombre"`
+	Activo bool   `json:"activo"`
 }
 
 type Localidad struct {
 	ID          int    `json:"idlocalidades"`
 	IDMunicipio int    `json:"id_municipio"`
 	Nombre      string `json:"nombre"`
+	Activo      bool   `json:"activo"`
+	FusionadoEn *int   `json:"fusionado_en,omitempty"`
+}
+
+type CambioCatalogo struct {
+	ID          int       `json:"id"`
+	UsuarioID   int       `json:"usuario_id"`
+	Username    string    `json:"username"`
+	Tipo        string    `json:"tipo"`
+	Accion      string    `json:"accion"`
+	MunicipioID int       `json:"municipio_id"`
+	LocalidadID int       `json:"localidad_id"`
+	Detalle     string    `json:"detalle"`
+	CreadoEn    time.Time `json:"creado_en"`
 }
 
 type PDFRequest struct {
@@ -21,12 +63,22 @@ type PDFRequest struct {
 }
 
 type Usuario struct {
-	ID        int    `json:"id"`
-	Username  string `json:"username"`
-	Password  string `json:"password,omitempty"`
-	Activo    bool   `json:"activo"`
-	RolID     int    `json:"rol_id"`
-	RolNombre string `json:"rol_nombre,omitempty"`
+	ID                int        `json:"id"`
+	Username          string     `json:"username"`
+	Password          string     `json:"password,omitempty"`
+	Activo            bool       `json:"activo"`
+	RolID             int        `json:"rol_id"`
+	RolNombre         string     `json:"rol_nombre,omitempty"`
+	UltimoLogin       *time.Time `json:"ultimo_login,omitempty"`
+	TotalAsignaciones *int       `json:"total_asignaciones,omitempty"`
+}
+
+// ListaUsuarios es la respuesta paginada del listado de administración
+type ListaUsuarios struct {
+	Usuarios []Usuario `json:"usuarios"`
+	Total    int       `json:"total"`
+	Limite   int       `json:"limite"`
+	Offset   int       `json:"offset"`
 }
 
 type Rol struct {
@@ -43,3 +95,337 @@ type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
+
+type Region struct {
+	ID        int        `json:"id"`
+	EstadoID  int        `json:"estado_id"`
+	Nombre    string     `json:"nombre"`
+	Distritos []Distrito `json:"distritos"`
+}
+
+type Distrito struct {
+	ID              int    `json:"id"`
+	RegionID        int    `json:"region_id"`
+	Nombre          string `json:"nombre"`
+	TotalMunicipios int    `json:"total_municipios"`
+}
+
+type AsignacionRegiones struct {
+	UsuarioID    int   `json:"usuario_id"`
+	EstadosIDs   []int `json:"estados_ids"` // si no se envía, no se cambian
+	RegionesIDs  []int `json:"regiones_ids"`
+	DistritosIDs []int `json:"distritos_ids"`
+}
+
+type Cuota struct {
+	ID         int    `json:"id"`
+	RolID      *int   `json:"rol_id"`
+	RolNombre  string `json:"rol_nombre,omitempty"`
+	UsuarioID  *int   `json:"usuario_id"`
+	Username   string `json:"username,omitempty"`
+	MaxPorHora *int   `json:"max_por_hora"`
+	MaxPorDia  *int   `json:"max_por_dia"`
+}
+
+// Permiso otorga un permiso adicional a un rol o a un usuario
+type Permiso struct {
+	ID        int    `json:"id"`
+	Permiso   string `json:"permiso"`
+	RolID     *int   `json:"rol_id"`
+	RolNombre string `json:"rol_nombre,omitempty"`
+	UsuarioID *int   `json:"usuario_id"`
+	Username  string `json:"username,omitempty"`
+}
+
+type Alerta struct {
+	ID          int        `json:"id"`
+	UsuarioID   int        `json:"usuario_id"`
+	Username    string     `json:"username"`
+	Tipo        string     `json:"tipo"`
+	Detalle     string     `json:"detalle"`
+	CreadoEn    time.Time  `json:"creado_en"`
+	Revisada    bool       `json:"revisada"`
+	RevisadaPor *string    `json:"revisada_por,omitempty"`
+	RevisadaEn  *time.Time `json:"revisada_en,omitempty"`
+}
+
+type MarcaAgua struct {
+	RolID     int     `json:"rol_id"`
+	RolNombre string  `json:"rol_nombre,omitempty"`
+	Texto     string  `json:"texto"`
+	Opacidad  float64 `json:"opacidad"`
+	Angulo    float64 `json:"angulo"`
+}
+
+type RegistroVisualizacion struct {
+	ID         int64     `json:"id"`
+	Vista      string    `json:"vista"`
+	UsuarioID  int       `json:"usuario_id"`
+	Username   string    `json:"username"`
+	Acto       string    `json:"acto"`
+	ActoNombre string    `json:"acto_nombre"`
+	Municipio  int       `json:"municipio"`
+	Oficialia  int       `json:"oficialia"`
+	Localidad  int       `json:"localidad"`
+	Anio       int       `json:"anio"`
+	NumActa    int       `json:"num_acta"`
+	IP         string    `json:"ip"`
+	CreadoEn   time.Time `json:"creado_en"`
+}
+
+type RegistroDescarga struct {
+	ID         int64     `json:"id"`
+	Codigo     string    `json:"codigo"`
+	UsuarioID  int       `json:"usuario_id"`
+	Username   string    `json:"username"`
+	Acto       string    `json:"acto"`
+	ActoNombre string    `json:"acto_nombre"`
+	Municipio  int       `json:"municipio"`
+	Oficialia  int       `json:"oficialia"`
+	Localidad  int       `json:"localidad"`
+	Anio       int       `json:"anio"`
+	NumActa    int       `json:"num_acta"`
+	Raiz       string    `json:"raiz"`
+	Ruta       string    `json:"ruta"`
+	Sellado    bool      `json:"sellado"`
+	Filtros    string    `json:"filtros,omitempty"`
+	Rango      string    `json:"rango,omitempty"`
+	Peticiones int       `json:"peticiones"` // rangos sumados a la misma descarga
+	IP         string    `json:"ip"`
+	CreadoEn   time.Time `json:"creado_en"`
+	UltimaEn   time.Time `json:"ultima_peticion"`
+}
+
+// VerificacionCopia es lo que muestra la verificación pública de una copia
+// certificada: datos para identificar el acta y el hash del PDF emitido,
+// nunca su contenido. Una copia revocada no es válida pero se sigue
+// mostrando con la fecha y el motivo de la revocación.
+type VerificacionCopia struct {
+	Valida           bool       `json:"valida"`
+	Folio            string     `json:"folio"`
+	Acto             string     `json:"acto,omitempty"`
+	ActoNombre       string     `json:"acto_nombre,omitempty"`
+	Municipio        string     `json:"municipio,omitempty"`
+	Oficialia        int        `json:"oficialia,omitempty"`
+	Anio             int        `json:"anio,omitempty"`
+	NumActa          int        `json:"num_acta,omitempty"`
+	Oficial          string     `json:"oficial,omitempty"`
+	EmitidaEn        time.Time  `json:"emitida_en,omitempty"`
+	SHA256           string     `json:"sha256,omitempty"`
+	Revocada         bool       `json:"revocada"`
+	RevocadaEn       *time.Time `json:"revocada_en,omitempty"`
+	MotivoRevocacion string     `json:"motivo_revocacion,omitempty"`
+}
+
+// CopiaCertificada es el registro de una copia emitida, para administración
+type CopiaCertificada struct {
+	ID               int        `json:"id"`
+	Folio            string     `json:"folio"`
+	Codigo           string     `json:"codigo"`
+	Acto             string     `json:"acto"`
+	ActoNombre       string     `json:"acto_nombre"`
+	Municipio        int        `json:"municipio"`
+	Oficialia        int        `json:"oficialia"`
+	Localidad        int        `json:"localidad"`
+	Anio             int        `json:"anio"`
+	NumActa          int        `json:"num_acta"`
+	Oficial          string     `json:"oficial"`
+	UsuarioID        int        `json:"usuario_id"`
+	Username         string     `json:"username"`
+	EmitidaEn        time.Time  `json:"emitida_en"`
+	SHA256           string     `json:"sha256,omitempty"`
+	RevocadaEn       *time.Time `json:"revocada_en,omitempty"`
+	RevocadaPor      *int       `json:"revocada_por,omitempty"`
+	MotivoRevocacion string     `json:"motivo_revocacion,omitempty"`
+}
+
+type Acta struct {
+	ID              int           `json:"id"`
+	Acto            string        `json:"acto"`
+	ActoNombre      string        `json:"acto_nombre"`
+	Municipio       int           `json:"municipio"`
+	MunicipioNombre string        `json:"municipio_nombre"`
+	Oficialia       int           `json:"oficialia"`
+	Localidad       int           `json:"localidad"`
+	LocalidadNombre string        `json:"localidad_nombre"`
+	Anio            int           `json:"anio"`
+	NumActa         int           `json:"num_acta"`
+	FechaEvento     *string       `json:"fecha_evento"`
+	Personas        []PersonaActa `json:"personas"`
+	Relevancia      float64       `json:"relevancia,omitempty"`
+}
+
+type PersonaActa struct {
+	Rol             string `json:"rol"`
+	Nombre          string `json:"nombre"`
+	PrimerApellido  string `json:"primer_apellido"`
+	SegundoApellido string `json:"segundo_apellido"`
+}
+
+type CoberturaGrupo struct {
+	EstadoID          int               `json:"estado_id"`
+	MunicipioID       int               `json:"municipio_id"`
+	Municipio         string            `json:"municipio"`
+	Oficialia         int               `json:"oficialia"`
+	Anio              int               `json:"anio"`
+	Acto              string            `json:"acto"`
+	ActoNombre        string            `json:"acto_nombre"`
+	Total             int               `json:"total"`
+	Primera           int               `json:"primera"`
+	Ultima            int               `json:"ultima"`
+	TotalFaltantes    int               `json:"total_faltantes"`
+	Faltantes         []string          `json:"faltantes"` // rangos "12-15" o números sueltos
+	Duplicados        []int             `json:"duplicados"`
+	ArchivosRevisados bool              `json:"archivos_revisados"`
+	Problemas         []ProblemaArchivo `json:"problemas,omitempty"`
+}
+
+type ProblemaArchivo struct {
+	NumActa   int    `json:"num_acta"`
+	Localidad int    `json:"localidad"`
+	Problema  string `json:"problema"` // no_existe, vacio o corrupto
+}
+
+type IncidenciaIntegridad struct {
+	ID             int        `json:"id"`
+	ArchivoID      int        `json:"archivo_id"`
+	Raiz           string     `json:"raiz"`
+	Ruta           string     `json:"ruta"`
+	Acto           *string    `json:"acto"`
+	MunicipioID    *int       `json:"municipio_id"`
+	Anio           *int       `json:"anio"`
+	NumActa        *int       `json:"num_acta"`
+	Tipo           string     `json:"tipo"` // cambiado, faltante o corrupto
+	Sha256Anterior *string    `json:"sha256_anterior"`
+	Sha256Nuevo    *string    `json:"sha256_nuevo"`
+	EstadoArchivo  string     `json:"estado_archivo"`
+	DetectadaEn    time.Time  `json:"detectada_en"`
+	Revisada       bool       `json:"revisada"`
+	RevisadaPor    *string    `json:"revisada_por"`
+	RevisadaEn     *time.Time `json:"revisada_en"`
+}
+
+// InfoPDF son los metadatos del PDF de un acta, para mostrar la tira de
+// páginas antes de pedir los tiles, y el registro de anotaciones
+// marginales del acta
+type InfoPDF struct {
+	Paginas     int                 `json:"paginas"`
+	Tamano      int64               `json:"tamano"` // bytes
+	Modificado  time.Time           `json:"modificado"`
+	Dimensiones []PaginaPDF         `json:"dimensiones"`
+	Marginales  []AnotacionMarginal `json:"marginales,omitempty"`
+}
+
+type PaginaPDF struct {
+	Pagina int     `json:"pagina"`
+	Ancho  float64 `json:"ancho"` // puntos PDF (1/72 de pulgada)
+	Alto   float64 `json:"alto"`
+	DPI    int     `json:"dpi,omitempty"` // estimado de la imagen escaneada; sin imagen se omite
+}
+
+// VistaDZI es una visualización abierta como pirámide Deep Zoom: el código
+// que llevan las rutas de sus tiles y el descriptor de cada página
+type VistaDZI struct {
+	Vista   string      `json:"vista"`
+	Paginas []PaginaDZI `json:"paginas"`
+}
+
+type PaginaDZI struct {
+	Pagina     int    `json:"pagina"`
+	Descriptor string `json:"descriptor"` // ruta del .dzi; los tiles van en {pagina}_files/
+	Ancho      int    `json:"ancho"`      // píxeles del nivel más alto
+	Alto       int    `json:"alto"`
+	Niveles    int    `json:"niveles"`
+}
+
+// RegionPagina es un rectángulo de una página en puntos PDF desde la
+// esquina superior izquierda, como las dimensiones de InfoPDF
+type RegionPagina struct {
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Ancho float64 `json:"ancho"`
+	Alto  float64 `json:"alto"`
+}
+
+// Anotacion es una nota sobre una región de una página del acta. La región
+// va en puntos PDF desde la esquina superior izquierda, como las
+// dimensiones de InfoPDF. Las de visibilidad "equipo" las ven los usuarios
+// con el municipio asignado; las "privada", solo su autor.
+type Anotacion struct {
+	ID            int64     `json:"id"`
+	Pagina        int       `json:"pagina"`
+	X             float64   `json:"x"`
+	Y             float64   `json:"y"`
+	Ancho         float64   `json:"ancho"`
+	Alto          float64   `json:"alto"`
+	Texto         string    `json:"texto"`
+	Visibilidad   string    `json:"visibilidad"`
+	UsuarioID     int       `json:"usuario_id"`
+	Username      string    `json:"username"`
+	CreadaEn      time.Time `json:"creada_en"`
+	ActualizadaEn time.Time `json:"actualizada_en"`
+}
+
+// ActaRef identifica un acta con los datos que forman el nombre de su PDF
+type ActaRef struct {
+	Acto      string `json:"acto"`
+	Municipio int    `json:"municipio"`
+	Oficialia int    `json:"oficialia"`
+	Localidad int    `json:"localidad"`
+	Anio      int    `json:"anio"`
+	NumActa   int    `json:"num_acta"`
+}
+
+// AnotacionMarginal es un acto asentado al margen de un acta base
+// (divorcio, reconocimiento, rectificación...), con el acta que lo origina
+type AnotacionMarginal struct {
+	ID             int64      `json:"id"`
+	Acta           ActaRef    `json:"acta"`
+	Tipo           string     `json:"tipo"`
+	Fecha          string     `json:"fecha"` // AAAA-MM-DD
+	Referencia     *ActaRef   `json:"referencia"`
+	Resolucion     string     `json:"resolucion,omitempty"`
+	Observaciones  string     `json:"observaciones,omitempty"`
+	CapturadaPor   string     `json:"capturada_por"`
+	CapturadaEn    time.Time  `json:"capturada_en"`
+	ActualizadaPor *string    `json:"actualizada_por"`
+	ActualizadaEn  *time.Time `json:"actualizada_en"`
+}
+
+// CambioMarginal es una versión anterior de una anotación marginal: los
+// datos que tenía antes de que Usuario la editara o la eliminara.
+type CambioMarginal struct {
+	ID       int64             `json:"id"`
+	Accion   string            `json:"accion"`
+	Motivo   string            `json:"motivo,omitempty"`
+	Usuario  string            `json:"usuario"`
+	CreadoEn time.Time         `json:"creado_en"`
+	Anterior AnotacionMarginal `json:"anterior"`
+}
+
+// ReporteCalidad es un problema de digitalización reportado en una página
+// de un acta, con su estado en la cola de revisión. SugerirCierre indica
+// que el PDF cambió después del reporte (otro SHA-256 en la verificación
+// de integridad) y el reporte sigue abierto.
+type ReporteCalidad struct {
+	ID             int           `json:"id"`
+	Acta           ActaRef       `json:"acta"`
+	Pagina         int           `json:"pagina"`
+	Categoria      string        `json:"categoria"`
+	Descripcion    string        `json:"descripcion"`
+	Region         *RegionPagina `json:"region"`
+	Estado         string        `json:"estado"`
+	AsignadoA      *int          `json:"asignado_a"`
+	AsignadoNombre *string       `json:"asignado_nombre"`
+	Comentario     *string       `json:"comentario"`
+	ReportadoPor   string        `json:"reportado_por"`
+	ReportadoEn    time.Time     `json:"reportado_en"`
+	ActualizadoPor *string       `json:"actualizado_por"`
+	ActualizadoEn  *time.Time    `json:"actualizado_en"`
+	Raiz           string        `json:"raiz"`
+	Ruta           string        `json:"ruta"`
+	SHA256         string        `json:"sha256"`
+	SHA256Actual   *string       `json:"sha256_actual"` // última verificación de integridad
+	IncidenciaID   *int          `json:"incidencia_id"` // cambio de contenido detectado después del reporte
+	SugerirCierre  bool          `json:"sugerir_cierre"`
+}
@@ -0,0 +1,121 @@
+// Package reporte escribe tablas en CSV o XLSX para los reportes que se
+// descargan desde el panel de administración.
+package reporte
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Tabla es un reporte tabular: encabezado y filas del mismo ancho
+type Tabla struct {
+	Hoja       string // nombre de la hoja en XLSX
+	Encabezado []string
+	Filas      [][]string
+}
+
+// EscribirCSV escribe la tabla como CSV con BOM, para que Excel reconozca
+// los acentos al abrirlo
+func EscribirCSV(w io.Writer, t Tabla) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(t.Encabezado)
+	cw.WriteAll(t.Filas)
+	return cw.Error()
+}
+
+// EscribirXLSX escribe la tabla como libro de Excel de una hoja. Los valores
+// que son enteros se guardan como números y el resto como texto.
+func EscribirXLSX(w io.Writer, t Tabla) error {
+	z := zip.NewWriter(w)
+	hoja := t.Hoja
+	if hoja == "" {
+		hoja = "Reporte"
+	}
+
+	archivos := []struct {
+		nombre, contenido string
+	}{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="` + escaparXML(hoja) + `" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`},
+	}
+	for _, a := range archivos {
+		f, err := z.Create(a.nombre)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, a.contenido); err != nil {
+			return err
+		}
+	}
+
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	escribirFila(&b, 1, t.Encabezado)
+	for i, fila := range t.Filas {
+		escribirFila(&b, i+2, fila)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	if _, err := io.WriteString(f, b.String()); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+func escribirFila(b *strings.Builder, numero int, celdas []string) {
+	fmt.Fprintf(b, `<row r="%d">`, numero)
+	for i, v := range celdas {
+		ref := columna(i) + strconv.Itoa(numero)
+		if _, err := strconv.Atoi(v); err == nil && numero > 1 && len(v) < 16 {
+			fmt.Fprintf(b, `<c r="%s"><v>%s</v></c>`, ref, v)
+			continue
+		}
+		fmt.Fprintf(b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escaparXML(v))
+	}
+	b.WriteString(`</row>`)
+}
+
+// columna convierte un índice (0 = A) en la letra de columna de Excel
+func columna(i int) string {
+	nombre := ""
+	for i >= 0 {
+		nombre = string(rune('A'+i%26)) + nombre
+		i = i/26 - 1
+	}
+	return nombre
+}
+
+func escaparXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,207 @@
+// Package rutas encuentra el PDF de un acta en una o varias raíces del
+// archivo. Cada raíz tiene su propia plantilla de directorios y nombre de
+// archivo, porque los lotes de distintos proveedores de digitalización no
+// siguen la misma convención.
+//
+// Una plantilla es texto literal con campos entre llaves; {campo:N} rellena
+// con ceros a la izquierda hasta N caracteres:
+//
+//	decada {decada}/{acto}/{anio}/{municipio:3}/{oficialia:2}/{localidad:3}/{acto}{estado:2}{municipio:3}{oficialia:2}{anio}{acta:5}{localidad:3}0.pdf
+//
+// Campos: acto, estado, municipio, oficialia, localidad, anio, acta y decada
+// (el año redondeado a la década). Los directorios se separan con '/'.
+package rutas
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PlantillaPredeterminada es la convención original del archivo
+const PlantillaPredeterminada = "decada {decada}/{acto}/{anio}/{municipio:3}/{oficialia:2}/{localidad:3}/" +
+	"{acto}{estado:2}{municipio:3}{oficialia:2}{anio}{acta:5}{localidad:3}0.pdf"
+
+// Acta son los datos que identifican el PDF de un acta. Estado y Municipio
+// son las claves INEGI, no el id interno del municipio.
+type Acta struct {
+	Acto      string
+	Estado    int
+	Municipio int
+	Oficialia int
+	Localidad int
+	Anio      int
+	NumActa   int
+}
+
+var camposValidos = map[string]bool{
+	"acto": true, "estado": true, "municipio": true, "oficialia": true,
+	"localidad": true, "anio": true, "acta": true, "decada": true,
+}
+
+// segmento es un tramo literal o un campo de la plantilla
+type segmento struct {
+	literal string
+	campo   string
+	ancho   int
+}
+
+// Plantilla es una plantilla ya validada
+type Plantilla struct {
+	texto     string
+	segmentos []segmento
+	patron    *regexp.Regexp
+	grupos    []string // campo de cada grupo del patrón
+}
+
+var reCampo = regexp.MustCompile(`\{([a-z]+)(?::([0-9]+))?\}`)
+
+// NuevaPlantilla valida el texto de una plantilla
+func NuevaPlantilla(texto string) (*Plantilla, error) {
+	if strings.ContainsAny(reCampo.ReplaceAllString(texto, ""), "{}") {
+		return nil, fmt.Errorf("llave sin cerrar o campo mal escrito en la plantilla %q", texto)
+	}
+	p := &Plantilla{texto: texto}
+	var patron strings.Builder
+	patron.WriteString("^")
+
+	pos := 0
+	for _, m := range reCampo.FindAllStringSubmatchIndex(texto, -1) {
+		if m[0] > pos {
+			literal := texto[pos:m[0]]
+			p.segmentos = append(p.segmentos, segmento{literal: literal})
+			patron.WriteString(regexp.QuoteMeta(literal))
+		}
+		campo := texto[m[2]:m[3]]
+		if !camposValidos[campo] {
+			return nil, fmt.Errorf("campo desconocido {%s} en la plantilla", campo)
+		}
+		ancho := 0
+		if m[4] >= 0 {
+			ancho, _ = strconv.Atoi(texto[m[4]:m[5]])
+			if ancho < 1 || ancho > 10 {
+				return nil, fmt.Errorf("ancho inválido en {%s}", texto[m[2]:m[5]])
+			}
+		}
+		p.segmentos = append(p.segmentos, segmento{campo: campo, ancho: ancho})
+		p.grupos = append(p.grupos, campo)
+
+		clase := `[0-9]`
+		if campo == "acto" {
+			clase = `[0-9A-Za-z]`
+		}
+		if ancho > 0 {
+			fmt.Fprintf(&patron, "(%s{%d})", clase, ancho)
+		} else {
+			// Sin ancho fijo se prefiere el valor más corto, para que los
+			// campos de ancho fijo que siguen queden bien alineados
+			fmt.Fprintf(&patron, "(%s+?)", clase)
+		}
+		pos = m[1]
+	}
+	if pos < len(texto) {
+		p.segmentos = append(p.segmentos, segmento{literal: texto[pos:]})
+		patron.WriteString(regexp.QuoteMeta(texto[pos:]))
+	}
+	patron.WriteString("$")
+
+	for _, c := range []string{"acto", "municipio", "anio", "acta"} {
+		if !strings.Contains(texto, "{"+c) {
+			return nil, fmt.Errorf("la plantilla debe incluir {%s}", c)
+		}
+	}
+	// Sin rutas absolutas ni salidas de la raíz
+	if strings.HasPrefix(texto, "/") || strings.Contains("/"+texto+"/", "/../") {
+		return nil, fmt.Errorf("la plantilla debe ser relativa a la raíz")
+	}
+
+	var err error
+	if p.patron, err = regexp.Compile(patron.String()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Plantilla) String() string {
+	return p.texto
+}
+
+// Ruta arma la ruta relativa (con '/') del PDF de un acta
+func (p *Plantilla) Ruta(a Acta) string {
+	var b strings.Builder
+	for _, s := range p.segmentos {
+		if s.campo == "" {
+			b.WriteString(s.literal)
+			continue
+		}
+		var valor string
+		switch s.campo {
+		case "acto":
+			valor = a.Acto
+		case "estado":
+			valor = strconv.Itoa(a.Estado)
+		case "municipio":
+			valor = strconv.Itoa(a.Municipio)
+		case "oficialia":
+			valor = strconv.Itoa(a.Oficialia)
+		case "localidad":
+			valor = strconv.Itoa(a.Localidad)
+		case "anio":
+			valor = strconv.Itoa(a.Anio)
+		case "acta":
+			valor = strconv.Itoa(a.NumActa)
+		case "decada":
+			valor = strconv.Itoa(a.Anio / 10 * 10)
+		}
+		if len(valor) < s.ancho {
+			valor = strings.Repeat("0", s.ancho-len(valor)) + valor
+		}
+		b.WriteString(valor)
+	}
+	return b.String()
+}
+
+// Parsear interpreta una ruta relativa a la raíz según la plantilla. Un
+// campo que aparece varias veces debe tener el mismo valor en todas.
+func (p *Plantilla) Parsear(ruta string) (Acta, bool) {
+	m := p.patron.FindStringSubmatch(filepath.ToSlash(ruta))
+	if m == nil {
+		return Acta{}, false
+	}
+
+	valores := map[string]string{}
+	for i, campo := range p.grupos {
+		v := m[i+1]
+		if campo != "acto" {
+			// Comparar como número: "007" y "7" son el mismo municipio
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return Acta{}, false
+			}
+			v = strconv.Itoa(n)
+		}
+		if previo, ok := valores[campo]; ok && previo != v {
+			return Acta{}, false
+		}
+		valores[campo] = v
+	}
+	numero := func(campo string) int {
+		n, _ := strconv.Atoi(valores[campo])
+		return n
+	}
+	a := Acta{
+		Acto:      valores["acto"],
+		Estado:    numero("estado"),
+		Municipio: numero("municipio"),
+		Oficialia: numero("oficialia"),
+		Localidad: numero("localidad"),
+		Anio:      numero("anio"),
+		NumActa:   numero("acta"),
+	}
+	if d, ok := valores["decada"]; ok && d != strconv.Itoa(a.Anio/10*10) {
+		return Acta{}, false
+	}
+	return a, true
+}
@@ -0,0 +1,115 @@
+package rutas
+
+import "testing"
+
+func TestNuevaPlantillaInvalida(t *testing.T) {
+	casos := []string{
+		"{acto}/{municipio}/{anio}/{acta}.pdf}",
+		"{acto}/{municipio}/{anio}/{acta.pdf",
+		"{acto}/{municipio}/{anio}/{Acta}.pdf",
+		"{acto}/{municipio}/{anio}/{acta}/{libro}.pdf",
+		"{acto}/{municipio:0}/{anio}/{acta}.pdf",
+		"{acto}/{municipio:11}/{anio}/{acta}.pdf",
+		"{municipio}/{anio}/{acta}.pdf",
+		"{acto}/{anio}/{acta}.pdf",
+		"{acto}/{municipio}/{acta}.pdf",
+		"{acto}/{municipio}/{anio}.pdf",
+		"/{acto}/{municipio}/{anio}/{acta}.pdf",
+		"../{acto}/{municipio}/{anio}/{acta}.pdf",
+		"{acto}/../{municipio}/{anio}/{acta}.pdf",
+	}
+	for _, texto := range casos {
+		if _, err := NuevaPlantilla(texto); err == nil {
+			t.Errorf("NuevaPlantilla(%q) debió fallar", texto)
+		}
+	}
+}
+
+func TestPlantillaRutaYParsear(t *testing.T) {
+	acta := Acta{Acto: "1", Estado: 20, Municipio: 67, Oficialia: 1, Localidad: 1, Anio: 1985, NumActa: 1234}
+	casos := []struct {
+		plantilla string
+		ruta      string
+		// Lo que se recupera al parsear; el estado no siempre está en la ruta
+		parseada Acta
+	}{
+		{
+			PlantillaPredeterminada,
+			"decada 1980/1/1985/067/01/001/120067011985012340010.pdf",
+			acta,
+		},
+		{
+			"{estado}/{municipio}/{anio}/{acto}-{acta}.pdf",
+			"20/67/1985/1-1234.pdf",
+			Acta{Acto: "1", Estado: 20, Municipio: 67, Anio: 1985, NumActa: 1234},
+		},
+		{
+			"lote.b/{acto:2}_{municipio:4}_{oficialia:3}_{localidad:4}_{anio}_{acta:6}.PDF",
+			"lote.b/01_0067_001_0001_1985_001234.PDF",
+			Acta{Acto: "01", Municipio: 67, Oficialia: 1, Localidad: 1, Anio: 1985, NumActa: 1234},
+		},
+	}
+	for _, c := range casos {
+		p, err := NuevaPlantilla(c.plantilla)
+		if err != nil {
+			t.Fatalf("NuevaPlantilla(%q): %v", c.plantilla, err)
+		}
+		if got := p.Ruta(acta); got != c.ruta {
+			t.Errorf("Ruta con %q = %q, se esperaba %q", c.plantilla, got, c.ruta)
+		}
+		got, ok := p.Parsear(c.ruta)
+		if !ok || got != c.parseada {
+			t.Errorf("Parsear(%q) = %+v, %v; se esperaba %+v", c.ruta, got, ok, c.parseada)
+		}
+	}
+}
+
+func TestPlantillaParsearRechaza(t *testing.T) {
+	p, err := NuevaPlantilla(PlantillaPredeterminada)
+	if err != nil {
+		t.Fatal(err)
+	}
+	casos := []struct {
+		nombre string
+		ruta   string
+	}{
+		{"otro directorio", "decada 1980/1/1985/067/01/001/x.pdf"},
+		{"otra extensión", "decada 1980/1/1985/067/01/001/120067011985012340010.tif"},
+		{"acto distinto en directorio y nombre", "decada 1980/2/1985/067/01/001/120067011985012340010.pdf"},
+		{"municipio distinto", "decada 1980/1/1985/068/01/001/120067011985012340010.pdf"},
+		{"década que no es la del año", "decada 1990/1/1985/067/01/001/120067011985012340010.pdf"},
+		{"ruta absoluta", "/decada 1980/1/1985/067/01/001/120067011985012340010.pdf"},
+	}
+	for _, c := range casos {
+		if a, ok := p.Parsear(c.ruta); ok {
+			t.Errorf("%s: Parsear(%q) = %+v, se esperaba rechazo", c.nombre, c.ruta, a)
+		}
+	}
+}
+
+func TestPlantillaCerosEquivalentes(t *testing.T) {
+	// "007" y "7" son el mismo municipio aunque una ruta los escriba distinto
+	p, err := NuevaPlantilla("{municipio:3}/{acto}/{anio}/{municipio}-{acta}.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := p.Parsear("007/01/1985/7-12.pdf")
+	if !ok || a.Municipio != 7 || a.NumActa != 12 {
+		t.Fatalf("Parsear = %+v, %v", a, ok)
+	}
+	if _, ok := p.Parsear("007/01/1985/8-12.pdf"); ok {
+		t.Error("un municipio distinto en el nombre debió rechazarse")
+	}
+}
+
+func TestRaizParsearEstado(t *testing.T) {
+	p, err := NuevaPlantilla("{municipio}/{anio}/{acto}-{acta}.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Raiz{Nombre: "lote", Plantilla: p, Estado: 20}
+	a, ok := r.Parsear("67/1985/01-1234.pdf")
+	if !ok || a.Estado != 20 {
+		t.Fatalf("Parsear = %+v, %v; se esperaba el estado de la raíz", a, ok)
+	}
+}
@@ -0,0 +1,183 @@
+package rutas
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+
+	"visor-pdf/internal/almacen"
+	"visor-pdf/internal/config"
+)
+
+// NombrePrincipal identifica la raíz configurada en PDFBasePath
+const NombrePrincipal = "principal"
+
+// Vigencia de las búsquedas en caché. Las negativas duran menos para que un
+// PDF recién copiado aparezca pronto.
+const (
+	vigenciaEncontrado   = 10 * time.Minute
+	vigenciaNoEncontrado = time.Minute
+	maxEntradasCache     = 100000
+)
+
+// ErrNoEncontrado indica que el PDF no está en ninguna raíz
+var ErrNoEncontrado = errors.New("PDF no encontrado en ninguna raíz del archivo")
+
+// Raiz es un directorio o bucket del archivo con su convención de nombres
+type Raiz struct {
+	Nombre    string
+	Ruta      string // tal como se configuró (directorio o s3://bucket/prefijo)
+	Plantilla *Plantilla
+	Estado    int // 0 = cualquiera
+	Almacen   almacen.Almacen
+}
+
+// Parsear interpreta una ruta relativa a la raíz; si la plantilla no
+// incluye {estado}, el acta es del estado de la raíz
+func (r Raiz) Parsear(ruta string) (Acta, bool) {
+	a, ok := r.Plantilla.Parsear(ruta)
+	if ok && a.Estado == 0 {
+		a.Estado = r.Estado
+	}
+	return a, ok
+}
+
+// Archivo es la ubicación de un PDF
+type Archivo struct {
+	Raiz     string // nombre de la raíz
+	Relativa string // ruta dentro de la raíz, con '/'
+	Almacen  almacen.Almacen
+}
+
+type entradaCache struct {
+	archivo Archivo
+	ok      bool
+	expira  time.Time
+}
+
+var (
+	raices []Raiz
+	mu     sync.Mutex
+	cache  = map[Acta]entradaCache{}
+)
+
+// Configurar arma las raíces a partir de la configuración: la principal
+// (PDFBasePath con PDFPlantilla) y las de RaicesPDF, ordenadas por
+// prioridad (menor primero; la principal tiene prioridad 0).
+func Configurar(c config.Config) error {
+	type candidata struct {
+		config.RaizPDF
+		orden int
+	}
+	candidatas := []candidata{{RaizPDF: config.RaizPDF{
+		Nombre: NombrePrincipal, Ruta: c.PDFBasePath, Plantilla: c.PDFPlantilla,
+	}}}
+	for i, r := range c.RaicesPDF {
+		candidatas = append(candidatas, candidata{RaizPDF: r, orden: i + 1})
+	}
+	sort.SliceStable(candidatas, func(i, j int) bool {
+		return candidatas[i].Prioridad < candidatas[j].Prioridad
+	})
+
+	nuevas := make([]Raiz, 0, len(candidatas))
+	nombres := map[string]bool{}
+	for _, r := range candidatas {
+		if r.Nombre == "" || r.Ruta == "" {
+			return fmt.Errorf("la raíz %d necesita nombre y ruta", r.orden)
+		}
+		if nombres[r.Nombre] {
+			return fmt.Errorf("raíz %q repetida", r.Nombre)
+		}
+		nombres[r.Nombre] = true
+
+		texto := r.Plantilla
+		if texto == "" {
+			texto = PlantillaPredeterminada
+		}
+		p, err := NuevaPlantilla(texto)
+		if err != nil {
+			return fmt.Errorf("raíz %q: %v", r.Nombre, err)
+		}
+		a, err := almacen.Nuevo(r.Ruta, c)
+		if err != nil {
+			return fmt.Errorf("raíz %q: %v", r.Nombre, err)
+		}
+		nuevas = append(nuevas, Raiz{Nombre: r.Nombre, Ruta: r.Ruta, Plantilla: p, Estado: r.Estado, Almacen: a})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	raices = nuevas
+	cache = map[Acta]entradaCache{}
+	return nil
+}
+
+// Raices devuelve las raíces en orden de prioridad
+func Raices() []Raiz {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Raiz(nil), raices...)
+}
+
+// Buscar prueba las raíces en orden y devuelve la primera donde existe el
+// PDF del acta. Solo un fs.ErrNotExist hace pasar a la siguiente raíz; si
+// una raíz no responde (un recurso compartido sin montar, un error o
+// tiempo agotado de S3) se devuelve ese error sin guardarlo en caché, para
+// no servir la copia de otra raíz ni dar por inexistente el PDF. Los
+// resultados, positivos y negativos, se guardan en caché.
+func Buscar(a Acta) (Archivo, error) {
+	ahora := time.Now()
+	mu.Lock()
+	e, ok := cache[a]
+	lista := raices
+	mu.Unlock()
+	if ok && ahora.Before(e.expira) {
+		if !e.ok {
+			return Archivo{}, ErrNoEncontrado
+		}
+		return e.archivo, nil
+	}
+
+	e = entradaCache{expira: ahora.Add(vigenciaNoEncontrado)}
+	for _, r := range lista {
+		if r.Estado != 0 && r.Estado != a.Estado {
+			continue
+		}
+		rel := r.Plantilla.Ruta(a)
+		_, err := r.Almacen.Info(rel)
+		if err == nil {
+			e = entradaCache{
+				archivo: Archivo{Raiz: r.Nombre, Relativa: rel, Almacen: r.Almacen},
+				ok:      true,
+				expira:  ahora.Add(vigenciaEncontrado),
+			}
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return Archivo{}, fmt.Errorf("raíz %q: %w", r.Nombre, err)
+		}
+	}
+
+	mu.Lock()
+	if len(cache) >= maxEntradasCache {
+		cache = map[Acta]entradaCache{}
+	}
+	cache[a] = e
+	mu.Unlock()
+
+	if !e.ok {
+		return Archivo{}, ErrNoEncontrado
+	}
+	return e.archivo, nil
+}
+
+// Olvidar descarta de la caché la búsqueda de un acta, por ejemplo cuando
+// el PDF en caché ya no existe
+func Olvidar(a Acta) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(cache, a)
+}
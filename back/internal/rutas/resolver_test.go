@@ -0,0 +1,81 @@
+package rutas
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"visor-pdf/internal/config"
+)
+
+const plantillaPrueba = "{municipio}/{anio}/{acto}-{acta}.pdf"
+
+func crearPDF(t *testing.T, base, ruta string) {
+	t.Helper()
+	completa := filepath.Join(base, filepath.FromSlash(ruta))
+	if err := os.MkdirAll(filepath.Dir(completa), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(completa, []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func configurarPrueba(t *testing.T, principal, secundaria string) {
+	t.Helper()
+	err := Configurar(config.Config{
+		PDFBasePath:  principal,
+		PDFPlantilla: plantillaPrueba,
+		RaicesPDF:    []config.RaizPDF{{Nombre: "lote", Ruta: secundaria, Plantilla: plantillaPrueba, Prioridad: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuscarPorPrioridad(t *testing.T) {
+	principal, lote := t.TempDir(), t.TempDir()
+	crearPDF(t, principal, "67/1985/1-10.pdf")
+	crearPDF(t, lote, "67/1985/1-10.pdf")
+	crearPDF(t, lote, "67/1985/1-11.pdf")
+	configurarPrueba(t, principal, lote)
+
+	casos := []struct {
+		acta Acta
+		raiz string
+	}{
+		{Acta{Acto: "1", Municipio: 67, Anio: 1985, NumActa: 10}, NombrePrincipal},
+		{Acta{Acto: "1", Municipio: 67, Anio: 1985, NumActa: 11}, "lote"},
+	}
+	for _, c := range casos {
+		archivo, err := Buscar(c.acta)
+		if err != nil || archivo.Raiz != c.raiz {
+			t.Errorf("Buscar(%+v) = %+v, %v; se esperaba la raíz %q", c.acta, archivo, err, c.raiz)
+		}
+	}
+	if _, err := Buscar(Acta{Acto: "1", Municipio: 67, Anio: 1985, NumActa: 12}); !errors.Is(err, ErrNoEncontrado) {
+		t.Errorf("acta inexistente: %v, se esperaba ErrNoEncontrado", err)
+	}
+}
+
+func TestBuscarRaizNoDisponible(t *testing.T) {
+	// Si la raíz principal no responde no se sirve la copia del lote ni se
+	// guarda el error en caché
+	principal := filepath.Join(t.TempDir(), "sin-montar")
+	lote := t.TempDir()
+	crearPDF(t, lote, "67/1985/1-10.pdf")
+	configurarPrueba(t, principal, lote)
+
+	acta := Acta{Acto: "1", Municipio: 67, Anio: 1985, NumActa: 10}
+	archivo, err := Buscar(acta)
+	if err == nil || errors.Is(err, ErrNoEncontrado) {
+		t.Fatalf("Buscar = %+v, %v; se esperaba el error de la raíz principal", archivo, err)
+	}
+
+	crearPDF(t, principal, "67/1985/1-10.pdf")
+	archivo, err = Buscar(acta)
+	if err != nil || archivo.Raiz != NombrePrincipal {
+		t.Fatalf("Buscar con la raíz montada = %+v, %v", archivo, err)
+	}
+}
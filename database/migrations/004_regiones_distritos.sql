@@ -0,0 +1,84 @@
+-- =====================================================
+-- Migración: Regiones y distritos judiciales
+-- =====================================================
+-- Oaxaca agrupa sus 570 municipios en 8 regiones y 30
+-- distritos. Estas tablas permiten asignar a un usuario una
+-- región o un distrito completo en lugar de municipio por
+-- municipio. La membresía se resuelve por la vista
+-- v_usuario_municipios, así que un municipio agregado después
+-- a un distrito queda cubierto sin reasignar.
+
+USE digitalizacion;
+
+-- PASO 1: Catálogo de regiones
+CREATE TABLE IF NOT EXISTS regiones (
+    id INT(11) NOT NULL,
+    nombre VARCHAR(100) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY nombre (nombre)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 2: Catálogo de distritos (cada distrito pertenece a una región)
+CREATE TABLE IF NOT EXISTS distritos (
+    id INT(11) NOT NULL,
+    region_id INT(11) NOT NULL,
+    nombre VARCHAR(100) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY nombre (nombre),
+    KEY region_id (region_id),
+    CONSTRAINT distritos_ibfk_1 FOREIGN KEY (region_id) REFERENCES regiones (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 3: Mapeo municipio -> distrito (un municipio pertenece a un solo distrito)
+CREATE TABLE IF NOT EXISTS distrito_municipios (
+    municipio_id INT(11) NOT NULL,
+    distrito_id INT(11) NOT NULL,
+    PRIMARY KEY (municipio_id),
+    KEY distrito_id (distrito_id),
+    CONSTRAINT distrito_municipios_ibfk_1 FOREIGN KEY (municipio_id) REFERENCES municipios (idmunicipios),
+    CONSTRAINT distrito_municipios_ibfk_2 FOREIGN KEY (distrito_id) REFERENCES distritos (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 4: Asignaciones de regiones y distritos a usuarios
+CREATE TABLE IF NOT EXISTS usuario_regiones (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    usuario_id INT(11) NOT NULL,
+    region_id INT(11) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY unique_usuario_region (usuario_id, region_id),
+    KEY region_id (region_id),
+    CONSTRAINT usuario_regiones_ibfk_1 FOREIGN KEY (usuario_id) REFERENCES usuarios (id),
+    CONSTRAINT usuario_regiones_ibfk_2 FOREIGN KEY (region_id) REFERENCES regiones (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE IF NOT EXISTS usuario_distritos (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    usuario_id INT(11) NOT NULL,
+    distrito_id INT(11) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY unique_usuario_distrito (usuario_id, distrito_id),
+    KEY distrito_id (distrito_id),
+    CONSTRAINT usuario_distritos_ibfk_1 FOREIGN KEY (usuario_id) REFERENCES usuarios (id),
+    CONSTRAINT usuario_distritos_ibfk_2 FOREIGN KEY (distrito_id) REFERENCES distritos (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 5: Vista de municipios efectivos por usuario
+-- Une las asignaciones directas con las que vienen de distritos y regiones.
+CREATE OR REPLACE VIEW v_usuario_municipios AS
+SELECT um.usuario_id, um.municipio_id, 'municipio' AS origen
+FROM usuario_municipios um
+UNION
+SELECT ud.usuario_id, dm.municipio_id, 'distrito' AS origen
+FROM usuario_distritos ud
+JOIN distrito_municipios dm ON dm.distrito_id = ud.distrito_id
+UNION
+SELECT ur.usuario_id, dm.municipio_id, 'region' AS origen
+FROM usuario_regiones ur
+JOIN distritos d ON d.region_id = ur.region_id
+JOIN distrito_municipios dm ON dm.distrito_id = d.id;
+
+SELECT '✅ Migración de regiones y distritos completada' AS resultado;
+
+-- NOTA: Cargar el catálogo con database/seeds/regiones_distritos.sql y
+-- después mapear los municipios con:
+--   cd back && go run ./cmd/importar-regiones -csv catalogo_inegi.csv
@@ -0,0 +1,16 @@
+-- =====================================================
+-- Migración: Registrar último inicio de sesión
+-- =====================================================
+-- El listado de administración muestra cuándo entró cada
+-- usuario por última vez. Se actualiza en cada login exitoso.
+
+USE digitalizacion;
+
+ALTER TABLE usuarios
+ADD COLUMN ultimo_login DATETIME NULL DEFAULT NULL AFTER creado_en;
+
+-- Índice para los filtros por rol y estado del listado de usuarios
+ALTER TABLE usuarios
+ADD INDEX rol_activo (rol_id, activo);
+
+SELECT '✅ Migración de último login completada' AS resultado;
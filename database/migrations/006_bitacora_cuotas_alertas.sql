@@ -0,0 +1,64 @@
+-- =====================================================
+-- Migración: Bitácora de visualizaciones, cuotas y alertas
+-- =====================================================
+-- Cada llamada a /api/pdf queda registrada en la bitácora.
+-- Las cuotas limitan cuántas actas puede abrir un usuario por
+-- hora y por día (por rol o por usuario; la del usuario tiene
+-- prioridad). Las alertas las genera el detector de patrones
+-- inusuales y las revisan los administradores.
+
+USE digitalizacion;
+
+-- PASO 1: Bitácora de visualizaciones
+CREATE TABLE IF NOT EXISTS bitacora_visualizaciones (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    usuario_id INT(11) NOT NULL,
+    acto VARCHAR(2) NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    oficialia INT(11) NOT NULL,
+    localidad INT(11) NOT NULL,
+    anio INT(11) NOT NULL,
+    num_acta INT(11) NOT NULL,
+    ip VARCHAR(45) DEFAULT NULL,
+    creado_en DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY usuario_fecha (usuario_id, creado_en),
+    CONSTRAINT bitacora_visualizaciones_ibfk_1 FOREIGN KEY (usuario_id) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 2: Cuotas por rol o por usuario (NULL = sin límite)
+CREATE TABLE IF NOT EXISTS cuotas (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    rol_id INT(11) DEFAULT NULL,
+    usuario_id INT(11) DEFAULT NULL,
+    max_por_hora INT(11) DEFAULT NULL,
+    max_por_dia INT(11) DEFAULT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY unique_rol (rol_id),
+    UNIQUE KEY unique_usuario (usuario_id),
+    CONSTRAINT cuotas_ibfk_1 FOREIGN KEY (rol_id) REFERENCES roles (id),
+    CONSTRAINT cuotas_ibfk_2 FOREIGN KEY (usuario_id) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 3: Alertas de uso inusual
+CREATE TABLE IF NOT EXISTS alertas (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    usuario_id INT(11) NOT NULL,
+    tipo VARCHAR(30) NOT NULL,
+    detalle VARCHAR(255) NOT NULL,
+    creado_en DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revisada TINYINT(1) NOT NULL DEFAULT 0,
+    revisada_por INT(11) DEFAULT NULL,
+    revisada_en DATETIME DEFAULT NULL,
+    PRIMARY KEY (id),
+    KEY usuario_tipo (usuario_id, tipo, creado_en),
+    KEY revisada (revisada),
+    CONSTRAINT alertas_ibfk_1 FOREIGN KEY (usuario_id) REFERENCES usuarios (id),
+    CONSTRAINT alertas_ibfk_2 FOREIGN KEY (revisada_por) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 4: Cuota por defecto para el rol de usuario
+INSERT INTO cuotas (rol_id, max_por_hora, max_por_dia) VALUES (2, 200, 1000)
+ON DUPLICATE KEY UPDATE rol_id = rol_id;
+
+SELECT '✅ Migración de bitácora, cuotas y alertas completada' AS resultado;
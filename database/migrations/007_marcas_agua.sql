@@ -0,0 +1,20 @@
+-- =====================================================
+-- Migración: Marca de agua visible por rol
+-- =====================================================
+-- Cada tile servido por /api/pdf lleva una marca con el usuario,
+-- la fecha y el código de la visualización en la bitácora. El
+-- texto admite {usuario}, {fecha} y {vista}. Los roles sin fila
+-- usan la configuración por defecto del backend.
+
+USE digitalizacion;
+
+CREATE TABLE IF NOT EXISTS marcas_agua (
+    rol_id INT(11) NOT NULL,
+    texto VARCHAR(200) NOT NULL DEFAULT '{usuario} {fecha} {vista}',
+    opacidad DECIMAL(3,2) NOT NULL DEFAULT 0.25,
+    angulo INT(11) NOT NULL DEFAULT 30,
+    PRIMARY KEY (rol_id),
+    CONSTRAINT marcas_agua_ibfk_1 FOREIGN KEY (rol_id) REFERENCES roles (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+SELECT '✅ Migración de marcas de agua completada' AS resultado;
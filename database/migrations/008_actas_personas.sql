@@ -0,0 +1,45 @@
+-- =====================================================
+-- Migración: Catálogo de actas e índice de personas
+-- =====================================================
+-- actas identifica cada acta digitalizada con los mismos datos
+-- que forman el nombre del PDF. acta_personas guarda el
+-- registrado, sus padres y los cónyuges para poder buscar por
+-- nombre. Se cargan con cmd/importar-personas desde los índices
+-- en hoja de cálculo (CSV).
+
+USE digitalizacion;
+
+-- PASO 1: Catálogo de actas
+CREATE TABLE IF NOT EXISTS actas (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    acto VARCHAR(2) NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    oficialia INT(11) NOT NULL,
+    localidad INT(11) NOT NULL,
+    anio INT(11) NOT NULL,
+    num_acta INT(11) NOT NULL,
+    fecha_evento DATE DEFAULT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY unique_acta (acto, municipio_id, oficialia, anio, num_acta, localidad),
+    KEY municipio_anio (municipio_id, anio),
+    CONSTRAINT actas_ibfk_1 FOREIGN KEY (municipio_id) REFERENCES municipios (idmunicipios)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 2: Personas que aparecen en cada acta
+-- rol: registrado, padre, madre, conyuge1, conyuge2
+-- (utf8mb4 porque los nombres pueden traer caracteres fuera de latin1)
+CREATE TABLE IF NOT EXISTS acta_personas (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    acta_id INT(11) NOT NULL,
+    rol VARCHAR(20) NOT NULL,
+    nombre VARCHAR(100) NOT NULL DEFAULT '',
+    primer_apellido VARCHAR(100) NOT NULL DEFAULT '',
+    segundo_apellido VARCHAR(100) NOT NULL DEFAULT '',
+    PRIMARY KEY (id),
+    KEY acta_id (acta_id),
+    KEY apellidos (primer_apellido, segundo_apellido),
+    KEY nombre (nombre),
+    CONSTRAINT acta_personas_ibfk_1 FOREIGN KEY (acta_id) REFERENCES actas (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+SELECT '✅ Migración de actas y personas completada' AS resultado;
@@ -0,0 +1,17 @@
+-- =====================================================
+-- Migración: Claves fonéticas del índice de personas
+-- =====================================================
+-- Las claves las calcula el backend (paquete fonetica) al
+-- importar. Para llenar las de personas ya importadas:
+--   cd back && go run ./cmd/importar-personas -recalcular-claves
+
+USE digitalizacion;
+
+ALTER TABLE acta_personas
+ADD COLUMN clave_nombre VARCHAR(100) NOT NULL DEFAULT '' AFTER segundo_apellido,
+ADD COLUMN clave_primer_apellido VARCHAR(100) NOT NULL DEFAULT '' AFTER clave_nombre,
+ADD COLUMN clave_segundo_apellido VARCHAR(100) NOT NULL DEFAULT '' AFTER clave_primer_apellido,
+ADD INDEX clave_apellidos (clave_primer_apellido, clave_segundo_apellido),
+ADD INDEX clave_nombre (clave_nombre);
+
+SELECT '✅ Migración de claves fonéticas completada' AS resultado;
@@ -0,0 +1,27 @@
+-- =====================================================
+-- Migración: Catálogo de actos registrales
+-- =====================================================
+-- El acto es el primer dígito del nombre de cada PDF y la
+-- primera carpeta bajo la década. /api/pdf solo acepta los
+-- códigos activos de este catálogo. Desactivar un acto lo
+-- oculta del visor sin perder el historial de la bitácora.
+
+USE digitalizacion;
+
+CREATE TABLE IF NOT EXISTS actos (
+    codigo VARCHAR(2) NOT NULL,
+    nombre VARCHAR(100) NOT NULL,
+    activo TINYINT(1) NOT NULL DEFAULT 1,
+    PRIMARY KEY (codigo)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+SET NAMES utf8;
+
+INSERT IGNORE INTO actos (codigo, nombre) VALUES
+    ('1', 'Nacimiento'),
+    ('2', 'Matrimonio'),
+    ('3', 'Defunción'),
+    ('4', 'Reconocimiento'),
+    ('5', 'Divorcio');
+
+SELECT '✅ Migración de actos completada' AS resultado;
@@ -0,0 +1,27 @@
+-- =====================================================
+-- Migración: Catálogo de oficialías por municipio
+-- =====================================================
+-- Cada municipio tiene una o más oficialías del Registro Civil,
+-- identificadas por su número (la carpeta de dos dígitos bajo el
+-- municipio). anio_inicio y anio_fin delimitan los años en que
+-- la oficialía operó; NULL significa sin límite. Se carga con
+-- go run ./cmd/importar-oficialias. En los municipios que ya tienen
+-- oficialías cargadas, /api/pdf solo acepta las del catálogo; los que
+-- todavía no tienen ninguna no se validan, y sus copias certificadas
+-- esperan al catálogo porque el folio consecutivo se guarda aquí.
+
+USE digitalizacion;
+
+CREATE TABLE IF NOT EXISTS oficialias (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    municipio_id INT(11) NOT NULL,
+    numero INT(11) NOT NULL,
+    nombre VARCHAR(150) NOT NULL DEFAULT '',
+    anio_inicio INT(11) DEFAULT NULL,
+    anio_fin INT(11) DEFAULT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY municipio_numero (municipio_id, numero),
+    CONSTRAINT oficialias_ibfk_1 FOREIGN KEY (municipio_id) REFERENCES municipios (idmunicipios)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+SELECT '✅ Migración de oficialías completada' AS resultado;
@@ -0,0 +1,53 @@
+-- =====================================================
+-- Migración: Administración de municipios y localidades
+-- =====================================================
+-- Los catálogos se editan desde /api/admin/municipios/* y
+-- /api/admin/localidades/*. Nada se borra: desactivar o fusionar
+-- solo marca el registro, porque los PDFs siguen guardados con la
+-- clave original. Los nombres anteriores se conservan en
+-- nombres_historicos para mostrar en cada acta el nombre vigente
+-- en su fecha, y cada cambio queda en catalogo_cambios.
+
+USE digitalizacion;
+
+-- PASO 1: Estado y fusión
+-- fusionado_en apunta al municipio (o a la localidad del mismo
+-- municipio) que sustituye al registro fusionado
+ALTER TABLE municipios
+ADD COLUMN activo TINYINT(1) NOT NULL DEFAULT 1,
+ADD COLUMN fusionado_en INT(11) NULL DEFAULT NULL;
+
+ALTER TABLE localidades
+ADD COLUMN activo TINYINT(1) NOT NULL DEFAULT 1,
+ADD COLUMN fusionado_en INT(11) NULL DEFAULT NULL;
+
+-- PASO 2: Nombres anteriores
+-- Cada fila es un nombre que estuvo vigente hasta vigente_hasta
+-- (exclusivo). localidad_id = 0 para los municipios.
+CREATE TABLE IF NOT EXISTS nombres_historicos (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    tipo ENUM('municipio', 'localidad') NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    localidad_id INT(11) NOT NULL DEFAULT 0,
+    nombre VARCHAR(150) NOT NULL,
+    vigente_hasta DATE NOT NULL,
+    PRIMARY KEY (id),
+    KEY entidad_fecha (tipo, municipio_id, localidad_id, vigente_hasta)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 3: Bitácora de cambios al catálogo
+CREATE TABLE IF NOT EXISTS catalogo_cambios (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    usuario_id INT(11) NOT NULL,
+    tipo ENUM('municipio', 'localidad') NOT NULL,
+    accion ENUM('crear', 'renombrar', 'fusionar', 'desactivar', 'activar') NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    localidad_id INT(11) NOT NULL DEFAULT 0,
+    detalle VARCHAR(500) NOT NULL DEFAULT '',
+    creado_en TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY entidad (tipo, municipio_id, localidad_id),
+    CONSTRAINT catalogo_cambios_ibfk_1 FOREIGN KEY (usuario_id) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+SELECT '✅ Migración de administración de catálogos completada' AS resultado;
@@ -0,0 +1,13 @@
+-- =====================================================
+-- Migración: Registrar localidades borradas por el importador INEGI
+-- =====================================================
+-- cmd/importar-inegi puede borrar localidades que ya no están en
+-- el catálogo oficial y que no tienen actas ni visualizaciones;
+-- el borrado queda en catalogo_cambios con la acción 'borrar'.
+
+USE digitalizacion;
+
+ALTER TABLE catalogo_cambios
+MODIFY COLUMN accion ENUM('crear', 'renombrar', 'fusionar', 'desactivar', 'activar', 'borrar') NOT NULL;
+
+SELECT '✅ Migración de borrado de catálogo completada' AS resultado;
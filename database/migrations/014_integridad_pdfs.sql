@@ -0,0 +1,55 @@
+-- =====================================================
+-- Migración: Verificación de integridad de los PDFs
+-- =====================================================
+-- El verificador en segundo plano recorre PDFBasePath, calcula el
+-- SHA-256 de cada PDF y lo compara con el registrado. sha256 es
+-- el valor de referencia; sha256_ultimo el de la última revisión.
+-- Cada cambio de estado (contenido cambiado, archivo faltante o
+-- PDF ilegible) genera una incidencia que revisa un administrador.
+
+USE digitalizacion;
+
+-- PASO 1: Archivos conocidos
+-- ruta es relativa a PDFBasePath, con '/' como separador. Los
+-- datos del acta se toman del nombre del archivo (NULL si no
+-- sigue la convención).
+CREATE TABLE IF NOT EXISTS archivos_pdf (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    ruta VARCHAR(255) NOT NULL,
+    acto VARCHAR(2) DEFAULT NULL,
+    municipio_id INT(11) DEFAULT NULL,
+    oficialia INT(11) DEFAULT NULL,
+    localidad INT(11) DEFAULT NULL,
+    anio INT(11) DEFAULT NULL,
+    num_acta INT(11) DEFAULT NULL,
+    tamano BIGINT NOT NULL DEFAULT 0,
+    modificado DATETIME DEFAULT NULL,
+    sha256 CHAR(64) NOT NULL,
+    sha256_ultimo CHAR(64) NOT NULL,
+    estado ENUM('ok', 'cambiado', 'faltante', 'corrupto') NOT NULL DEFAULT 'ok',
+    registrado_en TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    verificado_en DATETIME DEFAULT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY ruta (ruta),
+    KEY acta (acto, municipio_id, oficialia, anio, num_acta),
+    KEY estado (estado)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- PASO 2: Incidencias detectadas
+CREATE TABLE IF NOT EXISTS incidencias_integridad (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    archivo_id INT(11) NOT NULL,
+    tipo ENUM('cambiado', 'faltante', 'corrupto') NOT NULL,
+    sha256_anterior CHAR(64) DEFAULT NULL,
+    sha256_nuevo CHAR(64) DEFAULT NULL,
+    detectada_en TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revisada TINYINT(1) NOT NULL DEFAULT 0,
+    revisada_por INT(11) DEFAULT NULL,
+    revisada_en DATETIME DEFAULT NULL,
+    PRIMARY KEY (id),
+    KEY pendientes (revisada, detectada_en),
+    CONSTRAINT incidencias_integridad_ibfk_1 FOREIGN KEY (archivo_id) REFERENCES archivos_pdf (id),
+    CONSTRAINT incidencias_integridad_ibfk_2 FOREIGN KEY (revisada_por) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+SELECT '✅ Migración de integridad de PDFs completada' AS resultado;
@@ -0,0 +1,20 @@
+-- =====================================================
+-- Migración: Varias raíces del archivo de PDFs
+-- =====================================================
+-- Los PDFs pueden estar en varias raíces (PDFBasePath y las de
+-- raicesPDF), cada una con su plantilla de rutas. La ruta de
+-- archivos_pdf pasa a ser relativa a su raíz, identificada por
+-- nombre; los registros existentes pertenecen a la principal.
+
+USE digitalizacion;
+
+-- PASO 1: Raíz de cada archivo
+ALTER TABLE archivos_pdf
+    ADD COLUMN raiz VARCHAR(50) NOT NULL DEFAULT 'principal' AFTER id;
+
+-- PASO 2: La ruta es única dentro de su raíz
+ALTER TABLE archivos_pdf
+    DROP INDEX ruta,
+    ADD UNIQUE KEY raiz_ruta (raiz, ruta);
+
+SELECT '✅ Migración de raíces del archivo completada' AS resultado;
@@ -0,0 +1,104 @@
+-- =====================================================
+-- Migración: Varios estados
+-- =====================================================
+-- El estado pasa a ser parte del catálogo: cada municipio guarda
+-- la clave INEGI de su estado y su clave dentro de él (las que
+-- forman el nombre del PDF). idmunicipios sigue siendo el id
+-- interno único: los municipios existentes quedan en el estado 20
+-- con su id como clave; los de otros estados reciben
+-- estado*1000+clave al importarse.
+--
+-- Cada despliegue declara qué estados atiende con ESTADOS
+-- (por defecto 20).
+
+USE digitalizacion;
+
+-- PASO 1: Catálogo de estados (claves INEGI)
+CREATE TABLE IF NOT EXISTS estados (
+    id INT(11) NOT NULL,
+    nombre VARCHAR(60) NOT NULL,
+    abreviatura VARCHAR(10) NOT NULL,
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+INSERT IGNORE INTO estados (id, nombre, abreviatura) VALUES
+    (1, 'Aguascalientes', 'AGS'),
+    (2, 'Baja California', 'BC'),
+    (3, 'Baja California Sur', 'BCS'),
+    (4, 'Campeche', 'CAMP'),
+    (5, 'Coahuila de Zaragoza', 'COAH'),
+    (6, 'Colima', 'COL'),
+    (7, 'Chiapas', 'CHIS'),
+    (8, 'Chihuahua', 'CHIH'),
+    (9, 'Ciudad de México', 'CDMX'),
+    (10, 'Durango', 'DGO'),
+    (11, 'Guanajuato', 'GTO'),
+    (12, 'Guerrero', 'GRO'),
+    (13, 'Hidalgo', 'HGO'),
+    (14, 'Jalisco', 'JAL'),
+    (15, 'México', 'MEX'),
+    (16, 'Michoacán de Ocampo', 'MICH'),
+    (17, 'Morelos', 'MOR'),
+    (18, 'Nayarit', 'NAY'),
+    (19, 'Nuevo León', 'NL'),
+    (20, 'Oaxaca', 'OAX'),
+    (21, 'Puebla', 'PUE'),
+    (22, 'Querétaro', 'QRO'),
+    (23, 'Quintana Roo', 'QROO'),
+    (24, 'San Luis Potosí', 'SLP'),
+    (25, 'Sinaloa', 'SIN'),
+    (26, 'Sonora', 'SON'),
+    (27, 'Tabasco', 'TAB'),
+    (28, 'Tamaulipas', 'TAMPS'),
+    (29, 'Tlaxcala', 'TLAX'),
+    (30, 'Veracruz de Ignacio de la Llave', 'VER'),
+    (31, 'Yucatán', 'YUC'),
+    (32, 'Zacatecas', 'ZAC');
+
+-- PASO 2: Estado y clave INEGI de cada municipio
+ALTER TABLE municipios
+    ADD COLUMN estado_id INT(11) NOT NULL DEFAULT 20 AFTER idmunicipios,
+    ADD COLUMN clave INT(11) DEFAULT NULL AFTER estado_id;
+
+UPDATE municipios SET clave = idmunicipios WHERE clave IS NULL;
+
+ALTER TABLE municipios
+    MODIFY clave INT(11) NOT NULL,
+    ADD UNIQUE KEY estado_clave (estado_id, clave),
+    ADD CONSTRAINT municipios_ibfk_estado FOREIGN KEY (estado_id) REFERENCES estados (id);
+
+-- PASO 3: Las regiones pertenecen a un estado
+ALTER TABLE regiones
+    ADD COLUMN estado_id INT(11) NOT NULL DEFAULT 20 AFTER id,
+    ADD CONSTRAINT regiones_ibfk_estado FOREIGN KEY (estado_id) REFERENCES estados (id);
+
+-- PASO 4: Asignación de estados completos a usuarios
+CREATE TABLE IF NOT EXISTS usuario_estados (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    usuario_id INT(11) NOT NULL,
+    estado_id INT(11) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY usuario_estado (usuario_id, estado_id),
+    CONSTRAINT usuario_estados_ibfk_1 FOREIGN KEY (usuario_id) REFERENCES usuarios (id),
+    CONSTRAINT usuario_estados_ibfk_2 FOREIGN KEY (estado_id) REFERENCES estados (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 5: La vista de municipios efectivos incluye los estados asignados
+CREATE OR REPLACE VIEW v_usuario_municipios AS
+SELECT um.usuario_id, um.municipio_id, 'municipio' AS origen
+FROM usuario_municipios um
+UNION
+SELECT ud.usuario_id, dm.municipio_id, 'distrito' AS origen
+FROM usuario_distritos ud
+JOIN distrito_municipios dm ON dm.distrito_id = ud.distrito_id
+UNION
+SELECT ur.usuario_id, dm.municipio_id, 'region' AS origen
+FROM usuario_regiones ur
+JOIN distritos d ON d.region_id = ur.region_id
+JOIN distrito_municipios dm ON dm.distrito_id = d.id
+UNION
+SELECT ue.usuario_id, m.idmunicipios, 'estado' AS origen
+FROM usuario_estados ue
+JOIN municipios m ON m.estado_id = ue.estado_id;
+
+SELECT '✅ Migración de estados completada' AS resultado;
@@ -0,0 +1,47 @@
+-- =====================================================
+-- Migración: Permisos y bitácora de descargas del PDF original
+-- =====================================================
+-- Algunos usuarios (notarías, requerimientos judiciales) necesitan
+-- el PDF original y no solo los tiles. La descarga requiere el
+-- permiso 'descarga_original', que se otorga a un rol o a un
+-- usuario. Cada descarga queda en su propia bitácora, separada de
+-- las visualizaciones y sin contar contra las cuotas.
+
+USE digitalizacion;
+
+-- PASO 1: Permisos por rol o por usuario
+CREATE TABLE IF NOT EXISTS permisos (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    permiso VARCHAR(50) NOT NULL,
+    rol_id INT(11) DEFAULT NULL,
+    usuario_id INT(11) DEFAULT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY unique_rol (permiso, rol_id),
+    UNIQUE KEY unique_usuario (permiso, usuario_id),
+    CONSTRAINT permisos_ibfk_1 FOREIGN KEY (rol_id) REFERENCES roles (id),
+    CONSTRAINT permisos_ibfk_2 FOREIGN KEY (usuario_id) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- PASO 2: Bitácora de descargas (una fila por petición, incluidas
+-- las de rangos al reanudar una descarga)
+CREATE TABLE IF NOT EXISTS bitacora_descargas (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    usuario_id INT(11) NOT NULL,
+    acto VARCHAR(2) NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    oficialia INT(11) NOT NULL,
+    localidad INT(11) NOT NULL,
+    anio INT(11) NOT NULL,
+    num_acta INT(11) NOT NULL,
+    raiz VARCHAR(50) NOT NULL,
+    ruta VARCHAR(500) NOT NULL,
+    sellado TINYINT(1) NOT NULL DEFAULT 0,
+    rango VARCHAR(100) DEFAULT NULL,
+    ip VARCHAR(45) DEFAULT NULL,
+    creado_en DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY usuario_fecha (usuario_id, creado_en),
+    CONSTRAINT bitacora_descargas_ibfk_1 FOREIGN KEY (usuario_id) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+SELECT '✅ Migración de permisos y descargas completada' AS resultado;
@@ -0,0 +1,41 @@
+-- =====================================================
+-- Migración: Copias certificadas con folio por oficialía
+-- =====================================================
+-- Cada copia certificada que emite el visor recibe un folio
+-- consecutivo de su oficialía (sin huecos: el consecutivo se toma
+-- en la misma transacción que registra la copia) y el nombre del
+-- oficial que la expide. El código QR de la copia apunta a
+-- /verificar/{folio}, que confirma el folio, el acta y la fecha de
+-- emisión sin mostrar el contenido.
+
+USE digitalizacion;
+
+-- PASO 1: Titular y último folio emitido de cada oficialía
+ALTER TABLE oficialias
+    ADD COLUMN oficial VARCHAR(150) NOT NULL DEFAULT '' AFTER nombre,
+    ADD COLUMN ultimo_folio INT(11) NOT NULL DEFAULT 0;
+
+-- PASO 2: Copias emitidas
+CREATE TABLE IF NOT EXISTS copias_certificadas (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    folio VARCHAR(30) NOT NULL,
+    oficialia_id INT(11) NOT NULL,
+    consecutivo INT(11) NOT NULL,
+    acto VARCHAR(2) NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    oficialia INT(11) NOT NULL,
+    localidad INT(11) NOT NULL,
+    anio INT(11) NOT NULL,
+    num_acta INT(11) NOT NULL,
+    oficial VARCHAR(150) NOT NULL,
+    usuario_id INT(11) NOT NULL,
+    emitida_en DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY folio (folio),
+    UNIQUE KEY oficialia_consecutivo (oficialia_id, consecutivo),
+    KEY acta (acto, municipio_id, anio, num_acta),
+    CONSTRAINT copias_certificadas_ibfk_1 FOREIGN KEY (oficialia_id) REFERENCES oficialias (id),
+    CONSTRAINT copias_certificadas_ibfk_2 FOREIGN KEY (usuario_id) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+SELECT '✅ Migración de copias certificadas completada' AS resultado;
@@ -0,0 +1,21 @@
+-- =====================================================
+-- Migración: Verificación pública y revocación de copias
+-- =====================================================
+-- /verificar/{folio} exige el código HMAC impreso en la copia (y
+-- contenido en su QR) para que no se puedan recorrer los folios, y
+-- devuelve el SHA-256 del PDF emitido para comparar archivos. Una
+-- copia revocada se sigue mostrando, marcada como revocada.
+--
+-- Las copias emitidas antes de esta migración no tienen hash y su QR no
+-- lleva el código; la oficina puede consultarlo en /api/admin/copias.
+
+USE digitalizacion;
+
+ALTER TABLE copias_certificadas
+    ADD COLUMN sha256 CHAR(64) DEFAULT NULL AFTER oficial,
+    ADD COLUMN revocada_en DATETIME DEFAULT NULL,
+    ADD COLUMN revocada_por INT(11) DEFAULT NULL,
+    ADD COLUMN motivo_revocacion VARCHAR(255) DEFAULT NULL,
+    ADD CONSTRAINT copias_certificadas_ibfk_3 FOREIGN KEY (revocada_por) REFERENCES usuarios (id);
+
+SELECT '✅ Migración de verificación de copias completada' AS resultado;
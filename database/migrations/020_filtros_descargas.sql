@@ -0,0 +1,13 @@
+-- =====================================================
+-- Migración: Filtros de mejora en las descargas
+-- =====================================================
+-- /api/pdf/download?filtros= entrega las páginas filtradas (gris,
+-- contraste, umbral, enderezar...) como PDF de imágenes a 300 DPI. La
+-- bitácora guarda la cadena de filtros aplicada; NULL es el original.
+
+USE digitalizacion;
+
+ALTER TABLE bitacora_descargas
+    ADD COLUMN filtros VARCHAR(120) DEFAULT NULL AFTER sellado;
+
+SELECT '✅ Filtros registrados en la bitácora de descargas' AS resultado;
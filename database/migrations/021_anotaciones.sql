@@ -0,0 +1,37 @@
+-- =====================================================
+-- Migración: Anotaciones sobre regiones de las actas
+-- =====================================================
+-- Quien transcribe marca una región de una página (una nota marginal,
+-- un nombre ilegible) y le agrega un comentario. La región se guarda en
+-- puntos PDF desde la esquina superior izquierda de la página. Una
+-- anotación privada solo la ve su autor; una de equipo, todos los
+-- usuarios con el municipio asignado. /api/anotaciones/w3c las exporta
+-- como Web Annotations (JSON-LD) para visores IIIF.
+
+USE digitalizacion;
+
+CREATE TABLE IF NOT EXISTS anotaciones (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    acto VARCHAR(2) NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    oficialia INT(11) NOT NULL,
+    localidad INT(11) NOT NULL,
+    anio INT(11) NOT NULL,
+    num_acta INT(11) NOT NULL,
+    pagina INT(11) NOT NULL,
+    x DECIMAL(8,2) NOT NULL,
+    y DECIMAL(8,2) NOT NULL,
+    ancho DECIMAL(8,2) NOT NULL,
+    alto DECIMAL(8,2) NOT NULL,
+    texto TEXT NOT NULL,
+    visibilidad ENUM('privada', 'equipo') NOT NULL DEFAULT 'privada',
+    usuario_id INT(11) NOT NULL,
+    creada_en DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actualizada_en DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY acta (acto, municipio_id, anio, num_acta),
+    KEY usuario_id (usuario_id),
+    CONSTRAINT anotaciones_ibfk_1 FOREIGN KEY (usuario_id) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+SELECT '✅ Migración de anotaciones completada' AS resultado;
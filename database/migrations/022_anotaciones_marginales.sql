@@ -0,0 +1,47 @@
+-- =====================================================
+-- Migración: Registro de anotaciones marginales
+-- =====================================================
+-- Los divorcios, reconocimientos, rectificaciones y demás actos que
+-- modifican un acta se asientan al margen del acta base. Este
+-- registro los guarda como datos estructurados: tipo, fecha, acta
+-- que los origina y número de resolución. A diferencia de las
+-- anotaciones de los usuarios es información oficial: solo la
+-- capturan quienes tienen el permiso 'anotaciones_marginales'.
+
+USE digitalizacion;
+
+CREATE TABLE IF NOT EXISTS anotaciones_marginales (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    -- Acta base, al margen de la cual se asienta
+    acto VARCHAR(2) NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    oficialia INT(11) NOT NULL,
+    localidad INT(11) NOT NULL,
+    anio INT(11) NOT NULL,
+    num_acta INT(11) NOT NULL,
+    tipo VARCHAR(20) NOT NULL,
+    fecha DATE NOT NULL,
+    -- Acta que origina la anotación (la de divorcio, de reconocimiento...)
+    ref_acto VARCHAR(2) DEFAULT NULL,
+    ref_municipio_id INT(11) DEFAULT NULL,
+    ref_oficialia INT(11) DEFAULT NULL,
+    ref_localidad INT(11) DEFAULT NULL,
+    ref_anio INT(11) DEFAULT NULL,
+    ref_num_acta INT(11) DEFAULT NULL,
+    resolucion VARCHAR(60) DEFAULT NULL,
+    observaciones TEXT DEFAULT NULL,
+    capturada_por INT(11) NOT NULL,
+    capturada_en DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actualizada_por INT(11) DEFAULT NULL,
+    actualizada_en DATETIME DEFAULT NULL,
+    PRIMARY KEY (id),
+    KEY acta (acto, municipio_id, anio, num_acta),
+    KEY referencia (ref_acto, ref_municipio_id, ref_anio, ref_num_acta),
+    KEY tipo_fecha (tipo, fecha),
+    KEY resolucion (resolucion),
+    CONSTRAINT anotaciones_marginales_ibfk_1 FOREIGN KEY (municipio_id) REFERENCES municipios (idmunicipios),
+    CONSTRAINT anotaciones_marginales_ibfk_2 FOREIGN KEY (capturada_por) REFERENCES usuarios (id),
+    CONSTRAINT anotaciones_marginales_ibfk_3 FOREIGN KEY (actualizada_por) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+SELECT '✅ Migración de anotaciones marginales completada' AS resultado;
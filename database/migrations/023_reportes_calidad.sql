@@ -0,0 +1,54 @@
+-- =====================================================
+-- Migración: Reportes de calidad de la digitalización
+-- =====================================================
+-- Quien consulta un acta reporta una página ilegible, recortada o
+-- que no corresponde al acta, con una descripción y opcionalmente la
+-- región afectada. Los supervisores (permiso 'supervisar_calidad')
+-- asignan cada reporte y lo llevan por los estados abierto,
+-- en_redigitalizacion, corregido y no_se_corregira.
+--
+-- El reporte guarda la raíz, la ruta y el SHA-256 del PDF que se
+-- vio. Cuando el verificador de integridad registra otro contenido
+-- para ese archivo (archivos_pdf.sha256_ultimo), la cola sugiere
+-- cerrar el reporte.
+
+USE digitalizacion;
+
+CREATE TABLE IF NOT EXISTS reportes_calidad (
+    id INT(11) NOT NULL AUTO_INCREMENT,
+    acto VARCHAR(2) NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    oficialia INT(11) NOT NULL,
+    localidad INT(11) NOT NULL,
+    anio INT(11) NOT NULL,
+    num_acta INT(11) NOT NULL,
+    pagina INT(11) NOT NULL,
+    categoria VARCHAR(20) NOT NULL,
+    descripcion TEXT NOT NULL,
+    -- Región afectada en puntos PDF (NULL = toda la página)
+    x DECIMAL(8,2) DEFAULT NULL,
+    y DECIMAL(8,2) DEFAULT NULL,
+    ancho DECIMAL(8,2) DEFAULT NULL,
+    alto DECIMAL(8,2) DEFAULT NULL,
+    -- Archivo que se vio al reportar
+    raiz VARCHAR(50) NOT NULL,
+    ruta VARCHAR(255) NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    estado ENUM('abierto', 'en_redigitalizacion', 'corregido', 'no_se_corregira') NOT NULL DEFAULT 'abierto',
+    asignado_a INT(11) DEFAULT NULL,
+    comentario VARCHAR(500) DEFAULT NULL,
+    reportado_por INT(11) NOT NULL,
+    reportado_en DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actualizado_por INT(11) DEFAULT NULL,
+    actualizado_en DATETIME DEFAULT NULL,
+    PRIMARY KEY (id),
+    KEY cola (estado, reportado_en),
+    KEY acta (acto, municipio_id, anio, num_acta),
+    KEY archivo (raiz, ruta),
+    KEY asignado_a (asignado_a),
+    CONSTRAINT reportes_calidad_ibfk_1 FOREIGN KEY (asignado_a) REFERENCES usuarios (id),
+    CONSTRAINT reportes_calidad_ibfk_2 FOREIGN KEY (reportado_por) REFERENCES usuarios (id),
+    CONSTRAINT reportes_calidad_ibfk_3 FOREIGN KEY (actualizado_por) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+SELECT '✅ Migración de reportes de calidad completada' AS resultado;
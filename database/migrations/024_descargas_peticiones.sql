@@ -0,0 +1,18 @@
+-- =====================================================
+-- Migración: Una descarga por archivo aunque llegue en rangos
+-- =====================================================
+-- Los visores de PDF piden el original en muchos rangos (Range). Cada
+-- rango de un mismo usuario y archivo que llega hasta 30 minutos
+-- después de la petición anterior se suma a la misma descarga en
+-- lugar de abrir otra: peticiones cuenta las peticiones recibidas y
+-- ultima_peticion marca la más reciente. Las revalidaciones que se
+-- contestan con 304 no se registran.
+
+USE digitalizacion;
+
+ALTER TABLE bitacora_descargas
+    ADD COLUMN peticiones INT(11) NOT NULL DEFAULT 1 AFTER rango,
+    ADD COLUMN ultima_peticion DATETIME DEFAULT NULL AFTER creado_en,
+    ADD KEY usuario_archivo (usuario_id, raiz, ruta);
+
+SELECT '✅ Rangos agrupados en la bitácora de descargas' AS resultado;
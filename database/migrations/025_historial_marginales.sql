@@ -0,0 +1,55 @@
+-- =====================================================
+-- Migración: Historial de las anotaciones marginales
+-- =====================================================
+-- El registro de anotaciones marginales es información oficial: una
+-- corrección no debe perder lo que decía la anotación ni un borrado la
+-- anotación misma. Antes de editar o eliminar, la versión vigente se
+-- copia a anotaciones_marginales_historial con quién hizo el cambio,
+-- cuándo y el motivo. Eliminar ya no borra la fila: la marca con
+-- eliminada_en, eliminada_por y motivo_eliminacion, y deja de listarse.
+
+USE digitalizacion;
+
+-- PASO 1: Eliminación lógica
+ALTER TABLE anotaciones_marginales
+    ADD COLUMN eliminada_en DATETIME DEFAULT NULL,
+    ADD COLUMN eliminada_por INT(11) DEFAULT NULL,
+    ADD COLUMN motivo_eliminacion VARCHAR(255) DEFAULT NULL,
+    ADD CONSTRAINT anotaciones_marginales_ibfk_4 FOREIGN KEY (eliminada_por) REFERENCES usuarios (id);
+
+-- PASO 2: Versiones anteriores de cada anotación
+CREATE TABLE IF NOT EXISTS anotaciones_marginales_historial (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    marginal_id BIGINT NOT NULL,
+    accion ENUM('editar', 'eliminar') NOT NULL,
+    motivo VARCHAR(255) DEFAULT NULL,
+    usuario_id INT(11) NOT NULL,
+    creado_en DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Datos de la anotación antes del cambio
+    acto VARCHAR(2) NOT NULL,
+    municipio_id INT(11) NOT NULL,
+    oficialia INT(11) NOT NULL,
+    localidad INT(11) NOT NULL,
+    anio INT(11) NOT NULL,
+    num_acta INT(11) NOT NULL,
+    tipo VARCHAR(20) NOT NULL,
+    fecha DATE NOT NULL,
+    ref_acto VARCHAR(2) DEFAULT NULL,
+    ref_municipio_id INT(11) DEFAULT NULL,
+    ref_oficialia INT(11) DEFAULT NULL,
+    ref_localidad INT(11) DEFAULT NULL,
+    ref_anio INT(11) DEFAULT NULL,
+    ref_num_acta INT(11) DEFAULT NULL,
+    resolucion VARCHAR(60) DEFAULT NULL,
+    observaciones TEXT DEFAULT NULL,
+    capturada_por INT(11) NOT NULL,
+    capturada_en DATETIME NOT NULL,
+    actualizada_por INT(11) DEFAULT NULL,
+    actualizada_en DATETIME DEFAULT NULL,
+    PRIMARY KEY (id),
+    KEY marginal (marginal_id, id),
+    CONSTRAINT anotaciones_marginales_historial_ibfk_1 FOREIGN KEY (marginal_id) REFERENCES anotaciones_marginales (id),
+    CONSTRAINT anotaciones_marginales_historial_ibfk_2 FOREIGN KEY (usuario_id) REFERENCES usuarios (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+SELECT '✅ Migración de historial de anotaciones marginales completada' AS resultado;
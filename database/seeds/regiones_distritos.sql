@@ -0,0 +1,54 @@
+-- =====================================================
+-- Catálogo de regiones y distritos de Oaxaca
+-- =====================================================
+-- El mapeo municipio -> distrito se carga con el comando
+-- cmd/importar-regiones a partir del catálogo oficial.
+
+USE digitalizacion;
+SET NAMES utf8;
+
+INSERT INTO regiones (id, nombre) VALUES
+(1, 'Cañada'),
+(2, 'Costa'),
+(3, 'Istmo'),
+(4, 'Mixteca'),
+(5, 'Papaloapan'),
+(6, 'Sierra Norte'),
+(7, 'Sierra Sur'),
+(8, 'Valles Centrales')
+ON DUPLICATE KEY UPDATE nombre = VALUES(nombre);
+
+INSERT INTO distritos (id, region_id, nombre) VALUES
+(1, 1, 'Cuicatlán'),
+(2, 1, 'Teotitlán'),
+(3, 2, 'Jamiltepec'),
+(4, 2, 'Juquila'),
+(5, 2, 'Pochutla'),
+(6, 3, 'Juchitán'),
+(7, 3, 'Tehuantepec'),
+(8, 4, 'Coixtlahuaca'),
+(9, 4, 'Huajuapan'),
+(10, 4, 'Juxtlahuaca'),
+(11, 4, 'Nochixtlán'),
+(12, 4, 'Silacayoapam'),
+(13, 4, 'Teposcolula'),
+(14, 4, 'Tlaxiaco'),
+(15, 5, 'Choapam'),
+(16, 5, 'Tuxtepec'),
+(17, 6, 'Ixtlán'),
+(18, 6, 'Mixe'),
+(19, 6, 'Villa Alta'),
+(20, 7, 'Miahuatlán'),
+(21, 7, 'Putla'),
+(22, 7, 'Sola de Vega'),
+(23, 7, 'Yautepec'),
+(24, 8, 'Centro'),
+(25, 8, 'Ejutla'),
+(26, 8, 'Etla'),
+(27, 8, 'Ocotlán'),
+(28, 8, 'Tlacolula'),
+(29, 8, 'Zaachila'),
+(30, 8, 'Zimatlán')
+ON DUPLICATE KEY UPDATE region_id = VALUES(region_id), nombre = VALUES(nombre);
+
+SELECT '✅ Regiones y distritos insertados correctamente' AS resultado;
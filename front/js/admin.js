@@ -3,7 +3,8 @@
 // =============================================
 const CONFIG = {
     API_BASE: "http://172.19.2.220:8080/api",
-    NOTIFICATION_TIMEOUT: 3000
+    NOTIFICATION_TIMEOUT: 3000,
+    USERS_PAGE_SIZE: 50
 };
 
 const SELECTORS = {
@@ -20,6 +21,7 @@ const SELECTORS = {
         MUNICIPALITIES_CONTAINER: '#municipiosDisponibles',
         NOTIFICATION: '#notification',
         TOTAL_USUARIOS: '#totalUsuarios',
+        PAGINACION_USUARIOS: '#paginacionUsuarios',
         CONTADOR_MUNICIPIOS: '#contadorMunicipios',
         // Elementos para autocompletado
         BUSCAR_USUARIO: '#buscarUsuario',
@@ -37,7 +39,8 @@ const SELECTORS = {
 };
 
 // Variables globales para almacenar datos
-let allUsers = [];
+// Página actual del listado de usuarios; el filtrado y la paginación los hace el servidor
+let userListState = { q: '', offset: 0 };
 let allMunicipalities = [];
 let userMunicipalities = new Map();
 
@@ -76,8 +79,11 @@ const ApiService = {
         return this.request('/admin/roles');
     },
 
-    async getUsers() {
-        return this.request('/admin/usuarios');
+    // Devuelve una página del listado: { usuarios, total, limite, offset }
+    async getUsers({ q = '', limite = CONFIG.USERS_PAGE_SIZE, offset = 0 } = {}) {
+        const params = new URLSearchParams({ limite, offset, orden: 'username' });
+        if (q) params.set('q', q);
+        return this.request(`/admin/usuarios?${params}`);
     },
 
     async getMunicipalities() {
@@ -229,15 +235,13 @@ const AutocompleteManager = {
         });
     },
 
-    searchUsers(searchTerm) {
-        if (!allUsers || allUsers.length === 0) return;
-
-        const filteredUsers = allUsers.filter(usuario => 
-            usuario.username.toLowerCase().includes(searchTerm.toLowerCase()) ||
-            usuario.rol_nombre.toLowerCase().includes(searchTerm.toLowerCase())
-        );
-
-        this.renderAutocompleteResults(filteredUsers);
+    async searchUsers(searchTerm) {
+        try {
+            const data = await ApiService.getUsers({ q: searchTerm, limite: 10 });
+            this.renderAutocompleteResults(data.usuarios || []);
+        } catch (error) {
+            UI.showNotification('Error buscando usuarios: ' + error.message, 'error');
+        }
     },
 
     renderAutocompleteResults(users) {
@@ -364,8 +368,11 @@ const FilterManager = {
     setupUserFilter() {
         const filterInput = document.querySelector(SELECTORS.FILTERS.USUARIOS);
         if (filterInput) {
+            const debouncedFilter = Utils.debounce((searchTerm) => {
+                this.filterUsers(searchTerm);
+            }, 300);
             filterInput.addEventListener('input', (e) => {
-                this.filterUsers(e.target.value);
+                debouncedFilter(e.target.value);
             });
         }
     },
@@ -379,17 +386,11 @@ const FilterManager = {
         }
     },
 
+    // El servidor busca en el username y en el nombre del rol
     filterUsers(searchTerm) {
-        if (!allUsers || allUsers.length === 0) return;
-        
-        const filteredUsers = allUsers.filter(usuario => {
-            const usernameMatch = usuario.username?.toLowerCase().includes(searchTerm.toLowerCase());
-            const roleMatch = usuario.rol_nombre?.toLowerCase().includes(searchTerm.toLowerCase());
-            return usernameMatch || roleMatch;
-        });
-        
-        DataManager.renderUsers(filteredUsers);
-        DataManager.updateUserCounter(filteredUsers.length);
+        userListState.q = searchTerm.trim();
+        userListState.offset = 0;
+        DataManager.loadUsers();
     },
 
     filterMunicipalities(searchTerm) {
@@ -455,14 +456,50 @@ const DataManager = {
 
     async loadUsers() {
         try {
-            allUsers = await ApiService.getUsers();
-            this.renderUsers(allUsers);
-            this.updateUserCounter(allUsers.length);
+            let data = await ApiService.getUsers(userListState);
+            // Si la página quedó fuera del total (usuarios borrados), volver a la última
+            if (data.usuarios.length === 0 && data.total > 0 && userListState.offset > 0) {
+                userListState.offset = Math.floor((data.total - 1) / data.limite) * data.limite;
+                data = await ApiService.getUsers(userListState);
+            }
+            this.renderUsers(data.usuarios);
+            this.updateUserCounter(data.total);
+            this.renderUserPagination(data);
         } catch (error) {
             UI.showNotification('Error cargando usuarios: ' + error.message, 'error');
         }
     },
 
+    renderUserPagination({ usuarios, total, limite, offset }) {
+        const container = document.querySelector(SELECTORS.ELEMENTS.PAGINACION_USUARIOS);
+        if (!container) return;
+
+        container.innerHTML = '';
+        if (total <= limite) return;
+
+        const prev = document.createElement('button');
+        prev.className = 'btn btn-secondary';
+        prev.innerHTML = '<i class="fas fa-chevron-left"></i> Anterior';
+        prev.disabled = offset === 0;
+        prev.addEventListener('click', () => this.goToUserPage(offset - limite));
+
+        const info = document.createElement('span');
+        info.textContent = `${offset + 1}–${offset + usuarios.length} de ${total}`;
+
+        const next = document.createElement('button');
+        next.className = 'btn btn-secondary';
+        next.innerHTML = 'Siguiente <i class="fas fa-chevron-right"></i>';
+        next.disabled = offset + usuarios.length >= total;
+        next.addEventListener('click', () => this.goToUserPage(offset + limite));
+
+        container.append(prev, info, next);
+    },
+
+    goToUserPage(offset) {
+        userListState.offset = Math.max(0, offset);
+        this.loadUsers();
+    },
+
     renderUsers(usuarios) {
         const tbody = document.querySelector(SELECTORS.ELEMENTS.USER_LIST);
         if (!tbody) return;
@@ -28,6 +28,8 @@ const AppState = {
     municipios: [],
     pages: [],
     currentPage: 0,
+    paginaPendiente: null, // elegida en la tira antes de que carguen los tiles
+    miniaturas: [],        // object URLs de la tira de páginas
     zoomLevel: 1,
     municipioSelected: 0,
 
@@ -53,14 +55,15 @@ const DOM = {
     municipioDropdown: document.getElementById('municipioDropdown'),
     localidadSelect: document.getElementById('localidadSelect'),
     yearInput: document.getElementById('yearInput'),
-    actoInput: document.getElementById('actoInput'),
-    oficialiaInput: document.getElementById('oficialiaInput'),
+    actoSelect: document.getElementById('actoSelect'),
+    oficialiaSelect: document.getElementById('oficialiaSelect'),
     numActaInput: document.getElementById('numActaInput'),
     buscarBtn: document.getElementById('buscarBtn'),
     limpiarBtn: document.getElementById('limpiarBtn'),
 
     // Visor
     pdfViewer: document.getElementById('pdf-viewer'),
+    pageStrip: document.getElementById('page-strip'),
     viewerContainer: document.getElementById('viewer-container'),
     prevBtn: document.getElementById("prev-page"),
     nextBtn: document.getElementById("next-page"),
@@ -202,9 +205,14 @@ class MunicipioService {
             return;
         }
 
+        // Con municipios de varios estados se agrega la abreviatura del estado
+        const variosEstados = new Set(AppState.municipios.map(m => m.estado_id)).size > 1;
+
         filteredMunicipios.forEach(municipio => {
             const div = document.createElement("div");
-            div.textContent = municipio.nombre;
+            let etiqueta = variosEstados && municipio.estado ? `${municipio.nombre}, ${municipio.estado}` : municipio.nombre;
+            if (municipio.activo === false) etiqueta += ' (inactivo)';
+            div.textContent = etiqueta;
             div.onclick = () => this.seleccionarMunicipio(municipio);
             DOM.municipioDropdown.appendChild(div);
         });
@@ -240,6 +248,8 @@ class MunicipioService {
                     const option = document.createElement('option');
                     option.value = localidad.idlocalidades;
                     option.textContent = `${localidad.idlocalidades}-${localidad.nombre}`;
+                    // Las inactivas se siguen mostrando porque sus actas conservan la clave
+                    if (!localidad.activo) option.textContent += ' (inactiva)';
                     DOM.localidadSelect.appendChild(option);
                     if (index === 0) DOM.localidadSelect.value = localidad.idlocalidades;
                 });
@@ -261,6 +271,38 @@ class MunicipioService {
         AppState.municipioSelected = municipio.id;
         DOM.municipioDropdown.style.display = "none";
         this.cargarLocalidades(municipio.id);
+        this.cargarOficialias();
+    }
+
+    // Oficialías del municipio seleccionado; si ya hay año, solo las que operaban ese año
+    static async cargarOficialias() {
+        DOM.oficialiaSelect.innerHTML = '<option value="">Seleccione oficialía</option>';
+        if (!AppState.municipioSelected) return;
+
+        let url = `${API_BASE}/oficialias?idmunicipio=${AppState.municipioSelected}`;
+        if (DOM.yearInput.value.length === 4) {
+            url += `&anio=${DOM.yearInput.value}`;
+        }
+
+        try {
+            const response = await authenticatedFetch(url);
+            const oficialias = await response.json();
+
+            oficialias.forEach(oficialia => {
+                const option = document.createElement('option');
+                option.value = oficialia.numero;
+                option.textContent = oficialia.nombre
+                    ? `${oficialia.numero} - ${oficialia.nombre}`
+                    : oficialia.numero;
+                DOM.oficialiaSelect.appendChild(option);
+            });
+            if (oficialias.length === 1) {
+                DOM.oficialiaSelect.value = oficialias[0].numero;
+            }
+        } catch (error) {
+            console.error('Error cargando oficialías:', error);
+            Notification.show('Error cargando oficialías', 'error');
+        }
     }
 
 
@@ -272,6 +314,36 @@ class MunicipioService {
     }
 }
 
+// =============================================
+// SERVICIO DE ACTOS
+// =============================================
+class ActoService {
+    static async cargarActos() {
+        try {
+            const response = await authenticatedFetch(`${API_BASE}/actos`);
+            if (!response.ok) {
+                throw new Error('Respuesta inválida');
+            }
+            const actos = await response.json();
+
+            DOM.actoSelect.innerHTML = '<option value="">Seleccione acto</option>';
+            actos.forEach(acto => {
+                const option = document.createElement('option');
+                option.value = acto.codigo;
+                option.textContent = `${acto.codigo} - ${acto.nombre}`;
+                DOM.actoSelect.appendChild(option);
+            });
+            // Nacimiento es el acto que más se consulta
+            if (actos.some(a => a.codigo === '1')) {
+                DOM.actoSelect.value = '1';
+            }
+        } catch (error) {
+            console.error('Error cargando actos:', error);
+            Notification.show('Error cargando actos', 'error');
+        }
+    }
+}
+
 // =============================================
 // SERVICIO DE PDF
 // =============================================
@@ -296,8 +368,12 @@ class PDFService {
         DOM.loader.style.display = "block";
         DOM.pdfViewer.innerHTML = "";
 
+        const params = `year=${year}&acto=${acto}&municipio=${municipio}&oficialia=${oficialia}&localidad=${localidad}&numActa=${numActa}`;
+        // La tira de páginas se arma mientras se renderizan los tiles
+        MiniaturasService.cargar(params);
+
         try {
-            const url = `${API_BASE}/pdf?year=${year}&acto=${acto}&municipio=${municipio}&oficialia=${oficialia}&localidad=${localidad}&numActa=${numActa}`;
+            const url = `${API_BASE}/pdf?${params}`;
             const response = await authenticatedFetch(url);
             const data = await response.json();
 
@@ -306,6 +382,10 @@ class PDFService {
             AppState.pages = data.pages;
             AppState.currentPage = 0;
             AppState.zoomLevel = 1;
+            if (AppState.paginaPendiente !== null && AppState.paginaPendiente < data.pages.length) {
+                AppState.currentPage = AppState.paginaPendiente;
+            }
+            AppState.paginaPendiente = null;
 
             this.mostrarPagina(AppState.currentPage);
             UI.actualizarContadorPaginas();
@@ -319,9 +399,9 @@ class PDFService {
 
     static validarFormulario() {
         const year = DOM.yearInput.value;
-        const acto = DOM.actoInput.value;
+        const acto = DOM.actoSelect.value;
         const municipio = AppState.municipioSelected;
-        const oficialia = DOM.oficialiaInput.value;
+        const oficialia = DOM.oficialiaSelect.value;
         const localidad = DOM.localidadSelect.value;
         const numActa = DOM.numActaInput.value;
 
@@ -370,6 +450,7 @@ class PDFService {
         html += `</div>`;
         DOM.pdfViewer.innerHTML = html;
 
+        MiniaturasService.marcarActiva(pageIndex);
         UI.actualizarBotonesNavegacion();
         UI.actualizarContadorPaginas();
     }
@@ -409,19 +490,99 @@ class PDFService {
         AppState.municipioSelected = 0;
         DOM.localidadSelect.innerHTML = '<option value="">Seleccione localidad</option>';
         DOM.yearInput.value = '';
-        // DOM.actoInput.value = '';
-        DOM.oficialiaInput.value = '';
+        DOM.oficialiaSelect.innerHTML = '<option value="">Seleccione oficialía</option>';
         DOM.numActaInput.value = '';
         AppState.pages = [];
         AppState.currentPage = 0;
         AppState.zoomLevel = 1;
         DOM.pdfViewer.style.transform = `scale(1)`;
+        MiniaturasService.limpiar();
         this.mostrarEstadoVacio();
         UI.actualizarIndicadorZoom();
         Notification.show('Formulario limpiado correctamente', 'success');
     }
 }
 
+// =============================================
+// SERVICIO DE MINIATURAS
+// =============================================
+class MiniaturasService {
+    // Pide /pdf/info y luego una miniatura por página; las respuestas vienen
+    // de la caché del servidor, así que la tira aparece antes que los tiles
+    static async cargar(params) {
+        this.limpiar();
+        const busqueda = this.busqueda;
+        try {
+            const response = await authenticatedFetch(`${API_BASE}/pdf/info?${params}`);
+            if (!response.ok || busqueda !== this.busqueda) return;
+            const info = await response.json();
+
+            if (AppState.pages.length === 0) {
+                DOM.totalPagesElement.textContent = info.paginas;
+            }
+            if (info.paginas <= 1) return;
+
+            DOM.pageStrip.innerHTML = '';
+            for (let i = 0; i < info.paginas; i++) {
+                const boton = document.createElement('button');
+                boton.className = 'page-thumb';
+                boton.title = `Página ${i + 1}`;
+                boton.innerHTML = `<span>${i + 1}</span>`;
+                boton.addEventListener('click', () => this.irAPagina(i));
+                DOM.pageStrip.appendChild(boton);
+            }
+            DOM.pageStrip.classList.add('visible');
+            this.marcarActiva(AppState.currentPage);
+
+            for (let i = 0; i < info.paginas; i++) {
+                const resp = await authenticatedFetch(`${API_BASE}/pdf/thumbnail?${params}&pagina=${i + 1}`);
+                if (!resp.ok) continue;
+                const blob = await resp.blob();
+                if (busqueda !== this.busqueda) return; // otra búsqueda o se limpió la tira
+                const url = URL.createObjectURL(blob);
+                AppState.miniaturas.push(url);
+                const boton = DOM.pageStrip.children[i];
+                const img = document.createElement('img');
+                img.src = url;
+                img.alt = `Página ${i + 1}`;
+                boton.prepend(img);
+            }
+        } catch (error) {
+            // Sin tira se sigue pudiendo navegar con Anterior/Siguiente
+            console.error('Error cargando miniaturas:', error);
+        }
+    }
+
+    static irAPagina(indice) {
+        if (AppState.pages.length === 0) {
+            // Los tiles aún no llegan: se mostrará esta página al cargar
+            AppState.paginaPendiente = indice;
+            this.marcarActiva(indice);
+            return;
+        }
+        if (indice === AppState.currentPage || !AppState.pages[indice]) return;
+        AppState.currentPage = indice;
+        DOM.loader.style.display = "block";
+        PDFService.mostrarPagina(indice);
+    }
+
+    static marcarActiva(indice) {
+        Array.from(DOM.pageStrip.children).forEach((boton, i) => {
+            boton.classList.toggle('active', i === indice);
+        });
+    }
+
+    static limpiar() {
+        // Invalida las cargas en curso de la búsqueda anterior
+        this.busqueda = (this.busqueda || 0) + 1;
+        AppState.miniaturas.forEach(url => URL.revokeObjectURL(url));
+        AppState.miniaturas = [];
+        AppState.paginaPendiente = null;
+        DOM.pageStrip.innerHTML = '';
+        DOM.pageStrip.classList.remove('visible');
+    }
+}
+
 // =============================================
 // SERVICIO DE PANNING
 // =============================================
@@ -533,6 +694,9 @@ class EventManager {
 
         DOM.buscarBtn.addEventListener('click', () => PDFService.buscarPDF());
 
+        // Al cambiar el año se filtran las oficialías que operaban entonces
+        DOM.yearInput.addEventListener('change', () => MunicipioService.cargarOficialias());
+
         // Teclas ↑ ↓ Enter
         DOM.municipioSearch.addEventListener("keydown", (e) => {
             const items = DOM.municipioDropdown.querySelectorAll("div");
@@ -611,6 +775,7 @@ class App {
 
         UI.mostrarInterfazPrincipal();
         MunicipioService.cargarMunicipios();
+        ActoService.cargarActos();
         EventManager.inicializar();
 
         console.log("Aplicación inicializada correctamente");
@@ -1,22 +1,32 @@
 # file: pdf_microservice.py
-from flask import Flask, request, jsonify
+from flask import Flask, Response, request, jsonify
 import fitz  # pip install PyMuPDF
 import base64
 import os
 
 app = Flask(__name__)
 
-@app.route("/pdf_to_tiles", methods=["GET"])
+@app.route("/pdf_to_tiles", methods=["GET", "POST"])
 def pdf_to_tiles():
-    pdf_path = request.args.get("pdf_path")
     tile_width = 200
     tile_height = 200  
 
-    if not pdf_path or not os.path.exists(pdf_path):
-        return jsonify({"error": "Archivo no encontrado"}), 404
+    # POST: el backend envía el contenido del PDF (leído de disco o de S3)
+    # GET: ruta local, como antes de que existieran los almacenes remotos
+    if request.method == "POST":
+        datos = request.get_data()
+        if not datos:
+            return jsonify({"error": "PDF vacío"}), 400
+    else:
+        pdf_path = request.args.get("pdf_path")
+        if not pdf_path or not os.path.exists(pdf_path):
+            return jsonify({"error": "Archivo no encontrado"}), 404
 
     try:
-        doc = fitz.open(pdf_path)
+        if request.method == "POST":
+            doc = fitz.open(stream=datos, filetype="pdf")
+        else:
+            doc = fitz.open(pdf_path)
         all_tiles = []
 
         for page_index, page in enumerate(doc):
@@ -57,5 +67,163 @@ def pdf_to_tiles():
         return jsonify({"error": str(e)}), 500
 
 
+@app.route("/pdf_info", methods=["POST"])
+def pdf_info():
+    """Tamaño de cada página en puntos y DPI estimado de su imagen escaneada.
+
+    El DPI sale de la imagen que cubre más área de la página: sus píxeles
+    entre el ancho que ocupa en pulgadas. Sin imágenes queda en 0.
+    """
+    datos = request.get_data()
+    if not datos:
+        return jsonify({"error": "PDF vacío"}), 400
+
+    try:
+        doc = fitz.open(stream=datos, filetype="pdf")
+        paginas = []
+        for page_index, page in enumerate(doc):
+            dpi, mayor_area = 0, 0
+            for info in page.get_image_info():
+                bbox = fitz.Rect(info["bbox"])
+                area = bbox.width * bbox.height
+                if bbox.width > 0 and area > mayor_area:
+                    mayor_area = area
+                    dpi = round(info["width"] * 72 / bbox.width)
+            paginas.append({
+                "page_number": page_index + 1,
+                "width": page.rect.width,
+                "height": page.rect.height,
+                "dpi": dpi
+            })
+        doc.close()
+        return jsonify({"pages": paginas})
+
+    except Exception as e:
+        return jsonify({"error": str(e)}), 500
+
+
+@app.route("/pdf_miniaturas", methods=["POST"])
+def pdf_miniaturas():
+    """Cada página como PNG de `ancho` píxeles (query, 120 por omisión)."""
+    datos = request.get_data()
+    if not datos:
+        return jsonify({"error": "PDF vacío"}), 400
+    ancho = request.args.get("ancho", 120, type=int)
+
+    try:
+        doc = fitz.open(stream=datos, filetype="pdf")
+        paginas = []
+        for page_index, page in enumerate(doc):
+            zoom = ancho / page.rect.width
+            pix = page.get_pixmap(matrix=fitz.Matrix(zoom, zoom))
+            paginas.append({
+                "page_number": page_index + 1,
+                "image": base64.b64encode(pix.tobytes("png")).decode("utf-8")
+            })
+        doc.close()
+        return jsonify({"pages": paginas})
+
+    except Exception as e:
+        return jsonify({"error": str(e)}), 500
+
+
+@app.route("/pdf_region", methods=["POST"])
+def pdf_region():
+    """Un rectángulo de una página como PNG, para los tiles Deep Zoom.
+
+    Query: `pagina` (desde 1), `escala` (píxeles por punto) y `x0`, `y0`,
+    `x1`, `y1` en píxeles a esa escala.
+    """
+    datos = request.get_data()
+    if not datos:
+        return jsonify({"error": "PDF vacío"}), 400
+    pagina = request.args.get("pagina", 1, type=int)
+    escala = request.args.get("escala", type=float)
+    x0, y0, x1, y1 = (request.args.get(k, type=int) for k in ("x0", "y0", "x1", "y1"))
+    if not escala or escala <= 0 or None in (x0, y0, x1, y1) or x1 <= x0 or y1 <= y0:
+        return jsonify({"error": "Parámetros de región inválidos"}), 400
+
+    try:
+        doc = fitz.open(stream=datos, filetype="pdf")
+        if pagina < 1 or pagina > doc.page_count:
+            doc.close()
+            return jsonify({"error": "Página inexistente"}), 404
+        page = doc[pagina - 1]
+        clip = fitz.Rect(x0 / escala, y0 / escala, x1 / escala, y1 / escala)
+        pix = page.get_pixmap(matrix=fitz.Matrix(escala, escala), clip=clip)
+        salida = pix.tobytes("png")
+        doc.close()
+        return Response(salida, mimetype="image/png")
+
+    except Exception as e:
+        return jsonify({"error": str(e)}), 500
+
+
+@app.route("/pdf_sellar", methods=["POST"])
+def pdf_sellar():
+    """Agrega el texto de la query `texto` como pie en cada página del PDF del cuerpo."""
+    texto = request.args.get("texto", "")
+    datos = request.get_data()
+    if not datos or not texto:
+        return jsonify({"error": "Faltan el PDF o el texto del sello"}), 400
+
+    try:
+        doc = fitz.open(stream=datos, filetype="pdf")
+        for page in doc:
+            ancho, alto = page.rect.width, page.rect.height
+            # Franja blanca al pie para que el texto se lea sobre cualquier fondo
+            franja = fitz.Rect(0, alto - 18, ancho, alto)
+            page.draw_rect(franja, color=None, fill=(1, 1, 1), fill_opacity=0.85, overlay=True)
+            page.insert_textbox(fitz.Rect(10, alto - 15, ancho - 10, alto - 2), texto,
+                                fontsize=7, fontname="helv", align=fitz.TEXT_ALIGN_CENTER)
+        salida = doc.tobytes(garbage=3, deflate=True)
+        doc.close()
+        return Response(salida, mimetype="application/pdf")
+
+    except Exception as e:
+        return jsonify({"error": str(e)}), 500
+
+
+@app.route("/pdf_certificar", methods=["POST"])
+def pdf_certificar():
+    """Copia certificada: encabezado, pie y código QR en cada página.
+
+    Recibe multipart/form-data con los archivos `pdf` y `qr` (PNG) y los
+    campos `encabezado` y `pie`.
+    """
+    pdf = request.files.get("pdf")
+    qr = request.files.get("qr")
+    encabezado = request.form.get("encabezado", "")
+    pie = request.form.get("pie", "")
+    if pdf is None or qr is None:
+        return jsonify({"error": "Faltan el PDF o el código QR"}), 400
+
+    try:
+        doc = fitz.open(stream=pdf.read(), filetype="pdf")
+        imagen_qr = qr.read()
+        lado_qr = 56
+        for page in doc:
+            ancho, alto = page.rect.width, page.rect.height
+            blanco = dict(color=None, fill=(1, 1, 1), fill_opacity=0.9, overlay=True)
+
+            page.draw_rect(fitz.Rect(0, 0, ancho, 20), **blanco)
+            page.insert_textbox(fitz.Rect(10, 5, ancho - 10, 20), encabezado,
+                                fontsize=8, fontname="hebo", align=fitz.TEXT_ALIGN_CENTER)
+
+            # El pie deja a la derecha el espacio del QR
+            page.draw_rect(fitz.Rect(0, alto - lado_qr - 8, ancho, alto), **blanco)
+            page.insert_textbox(fitz.Rect(10, alto - 34, ancho - lado_qr - 16, alto - 4), pie,
+                                fontsize=7, fontname="helv", align=fitz.TEXT_ALIGN_LEFT)
+            page.insert_image(fitz.Rect(ancho - lado_qr - 6, alto - lado_qr - 4, ancho - 6, alto - 4),
+                              stream=imagen_qr)
+
+        salida = doc.tobytes(garbage=3, deflate=True)
+        doc.close()
+        return Response(salida, mimetype="application/pdf")
+
+    except Exception as e:
+        return jsonify({"error": str(e)}), 500
+
+
 if __name__ == "__main__":
     app.run(host="0.0.0.0", port=5000)